@@ -0,0 +1,47 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+// Package admin provides an HTTP API to inspect and manage Bokchoy's queues
+// and their tasks.
+//
+// Handler is an http.Handler with JSON endpoints.
+// Mount it using http.StripPrefix() if it's not the root:
+//
+//     http.Handle("/admin/", http.StripPrefix("/admin", admin.NewHandler(b)))
+//
+// Endpoints:
+//
+//     GET    /queues                               list of queues with stats
+//     GET    /queues/{queue}                       queue's stats
+//     POST   /queues/{queue}/purge                 empty the queue
+//     GET    /queues/{queue}/tasks                 browse tasks (?status=failed&offset=0&limit=50)
+//     GET    /queues/{queue}/tasks/{id}            inspect the task
+//     DELETE /queues/{queue}/tasks/{id}            delete the task
+//     POST   /queues/{queue}/tasks/{id}/cancel     cancel the task
+//     POST   /queues/{queue}/tasks/{id}/retry      requeue the finished task
+//     GET    /workers                              list of registered workers (bokchoy.Bokchoy.Workers())
+//
+// Only queues declared in Bokchoy (using Bokchoy.Queue() or WithQueues() option)
+// are available.
+//
+// Errors are reported as {"error": {"id": "...", "class": "...", "messages": [...]}}
+// with the corresponding HTTP status code.
+//
+// WARNING!
+// Handler has no authentication. Protect it by your own middleware.
+package admin
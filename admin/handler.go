@@ -0,0 +1,127 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package admin
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+)
+
+type (
+	// Handler is an http.Handler of admin API. Use NewHandler() to create it.
+	// See package's doc for endpoints.
+	Handler struct {
+		b *bokchoy.Bokchoy
+	}
+)
+
+// Make sure Handler implements http.Handler.
+var _ http.Handler = (*Handler)(nil)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	_DEFAULT_LIMIT = 50
+	_MAX_LIMIT     = 1000
+)
+
+// NewHandler returns a new Handler of admin API for the given Bokchoy.
+func NewHandler(b *bokchoy.Bokchoy) *Handler {
+	return &Handler{b: b}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const s = "Bokchoy.Admin: Failed to handle request. "
+
+	// Path: /workers or /queues[/{queue}[/purge|/tasks[/{id}[/cancel|/retry]]]]
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) == 1 && parts[0] == "workers" {
+		h.route(w, r, http.MethodGet, h.listWorkers)
+		return
+	}
+
+	if len(parts) == 0 || parts[0] != "queues" {
+		writeError(w, ekaerr.NotFound.New(s + "Unknown endpoint.").
+			WithString("bokchoy_admin_path", r.URL.Path).
+			Throw())
+		return
+	}
+
+	if len(parts) == 1 {
+		h.route(w, r, http.MethodGet, h.listQueues)
+		return
+	}
+
+	q := h.queue(parts[1])
+	if q == nil {
+		writeError(w, ekaerr.NotFound.New(s + "Queue is not found.").
+			WithString("bokchoy_queue_name", parts[1]).
+			Throw())
+		return
+	}
+
+	var (
+		method  string
+		handler func(w http.ResponseWriter, r *http.Request, q *bokchoy.Queue, taskID string)
+		taskID  string
+	)
+
+	switch len(parts) {
+	case 2:
+		method, handler = http.MethodGet, h.getQueue
+
+	case 3:
+		switch parts[2] {
+		case "purge": method, handler = http.MethodPost, h.purgeQueue
+		case "tasks": method, handler = http.MethodGet, h.listTasks
+		}
+
+	case 4:
+		taskID = parts[3]
+		if parts[2] == "tasks" && r.Method == http.MethodDelete {
+			method, handler = http.MethodDelete, h.deleteTask
+		} else if parts[2] == "tasks" {
+			method, handler = http.MethodGet, h.getTask
+		}
+
+	case 5:
+		taskID = parts[3]
+		switch {
+		case parts[2] == "tasks" && parts[4] == "cancel":
+			method, handler = http.MethodPost, h.cancelTask
+		case parts[2] == "tasks" && parts[4] == "retry":
+			method, handler = http.MethodPost, h.retryTask
+		}
+	}
+
+	if handler == nil {
+		writeError(w, ekaerr.NotFound.New(s + "Unknown endpoint.").
+			WithString("bokchoy_admin_path", r.URL.Path).
+			Throw())
+		return
+	}
+
+	h.route(w, r, method, func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r, q, taskID)
+	})
+}
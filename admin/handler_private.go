@@ -0,0 +1,282 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+)
+
+type (
+	// errorResponse is a JSON representation of *ekaerr.Error,
+	// that is sent to the client.
+	errorResponse struct {
+		Error struct {
+			ID       string   `json:"id"`
+			Class    string   `json:"class"`
+			Messages []string `json:"messages"`
+		} `json:"error"`
+	}
+
+	// tasksResponse is a page of tasks.
+	tasksResponse struct {
+		Total  int        `json:"total"`
+		Offset int        `json:"offset"`
+		Limit  int        `json:"limit"`
+		Tasks  []TaskInfo `json:"tasks"`
+	}
+)
+
+// route calls handler if request's method is the expected one,
+// or responds with 405 Method Not Allowed otherwise.
+func (h *Handler) route(w http.ResponseWriter, r *http.Request, method string, handler http.HandlerFunc) {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, ekaerr.UnsupportedOperation.
+			New("Bokchoy.Admin: Method is not allowed.").
+			WithString("bokchoy_admin_method", r.Method).
+			Throw())
+		return
+	}
+	handler(w, r)
+}
+
+// queue returns a declared Queue by its name or nil if there is no such queue.
+func (h *Handler) queue(name string) *bokchoy.Queue {
+	for _, q := range h.b.Queues() {
+		if q.Name() == name {
+			return q
+		}
+	}
+	return nil
+}
+
+func (h *Handler) listQueues(w http.ResponseWriter, _ *http.Request) {
+
+	queues := h.b.Queues()
+	infos := make([]QueueInfo, len(queues))
+
+	for i := range queues {
+		// Broker error is represented as absent tasks stats.
+		// The whole list must not be failed because of one queue.
+		infos[i], _ = NewQueueInfo(queues[i])
+	}
+
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (h *Handler) listWorkers(w http.ResponseWriter, _ *http.Request) {
+
+	workers, err := h.b.Workers()
+	if err.IsNotNil() {
+		writeError(w, err.AddMessage("Bokchoy.Admin: Failed to get workers.").Throw())
+		return
+	}
+
+	infos := make([]WorkerInfo, len(workers))
+	for i := range workers {
+		infos[i] = NewWorkerInfo(workers[i])
+	}
+
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (h *Handler) getQueue(w http.ResponseWriter, _ *http.Request, q *bokchoy.Queue, _ string) {
+
+	info, err := NewQueueInfo(q)
+	if err.IsNotNil() {
+		writeError(w, err.AddMessage("Bokchoy.Admin: Failed to get queue's stats.").Throw())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (h *Handler) purgeQueue(w http.ResponseWriter, _ *http.Request, q *bokchoy.Queue, _ string) {
+
+	if err := q.Empty(); err.IsNotNil() {
+		writeError(w, err.Throw())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) listTasks(w http.ResponseWriter, r *http.Request, q *bokchoy.Queue, _ string) {
+	const s = "Bokchoy.Admin: Failed to list tasks. "
+
+	var (
+		query     = r.URL.Query()
+		status    = bokchoy.TASK_STATUS_INVALID
+		offset    = 0
+		limit     = _DEFAULT_LIMIT
+		legacyErr error
+	)
+
+	if statusName := query.Get("status"); statusName != "" {
+		var ok bool
+		if status, ok = ParseTaskStatus(statusName); !ok {
+			writeError(w, ekaerr.IllegalArgument.New(s + "Unknown task status.").
+				WithString("bokchoy_admin_status", statusName).
+				Throw())
+			return
+		}
+	}
+
+	if v := query.Get("offset"); v != "" {
+		if offset, legacyErr = strconv.Atoi(v); legacyErr != nil || offset < 0 {
+			writeError(w, ekaerr.IllegalArgument.New(s + "Offset must be a non-negative integer.").
+				WithString("bokchoy_admin_offset", v).
+				Throw())
+			return
+		}
+	}
+
+	if v := query.Get("limit"); v != "" {
+		if limit, legacyErr = strconv.Atoi(v); legacyErr != nil || limit <= 0 || limit > _MAX_LIMIT {
+			writeError(w, ekaerr.IllegalArgument.New(s + "Limit must be a positive integer not greater than 1000.").
+				WithString("bokchoy_admin_limit", v).
+				Throw())
+			return
+		}
+	}
+
+	tasks, err := q.List()
+	if err.IsNotNil() {
+		writeError(w, err.AddMessage(s).Throw())
+		return
+	}
+
+	// The newest tasks are the first ones. PublishedAt has seconds precision,
+	// so tasks published at the same second are ordered by their ULIDs.
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].PublishedAt != tasks[j].PublishedAt {
+			return tasks[i].PublishedAt > tasks[j].PublishedAt
+		}
+		return tasks[i].ID() > tasks[j].ID()
+	})
+
+	resp := tasksResponse{
+		Offset: offset,
+		Limit:  limit,
+		Tasks:  make([]TaskInfo, 0, limit),
+	}
+
+	for i := range tasks {
+		if status != bokchoy.TASK_STATUS_INVALID && tasks[i].Status() != status {
+			continue
+		}
+		if resp.Total >= offset && len(resp.Tasks) < limit {
+			resp.Tasks = append(resp.Tasks, NewTaskInfo(&tasks[i], q.SerializerOfTask(&tasks[i])))
+		}
+		resp.Total++
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) getTask(w http.ResponseWriter, _ *http.Request, q *bokchoy.Queue, taskID string) {
+
+	task, err := q.Get(taskID)
+	if err.IsNil() && task == nil {
+		err = ekaerr.NotFound.New("Bokchoy.Admin: Task is not found.").
+			WithString("bokchoy_queue_name", q.Name()).
+			WithString("bokchoy_task_id", taskID)
+	}
+
+	if err.IsNotNil() {
+		writeError(w, err.Throw())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewTaskInfo(task, q.SerializerOfTask(task)))
+}
+
+func (h *Handler) deleteTask(w http.ResponseWriter, _ *http.Request, q *bokchoy.Queue, taskID string) {
+
+	if err := q.Delete(taskID); err.IsNotNil() {
+		writeError(w, err.Throw())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) cancelTask(w http.ResponseWriter, _ *http.Request, q *bokchoy.Queue, taskID string) {
+
+	task, err := q.Cancel(taskID)
+	if err.IsNotNil() {
+		writeError(w, err.Throw())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewTaskInfo(task, q.SerializerOfTask(task)))
+}
+
+func (h *Handler) retryTask(w http.ResponseWriter, _ *http.Request, q *bokchoy.Queue, taskID string) {
+
+	task, err := q.Requeue(taskID)
+	if err.IsNotNil() {
+		writeError(w, err.Throw())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewTaskInfo(task, q.SerializerOfTask(task)))
+}
+
+// writeJSON writes v as JSON response with the given HTTP status code.
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as JSON response with the HTTP status code,
+// that corresponds to the err's class.
+func writeError(w http.ResponseWriter, err *ekaerr.Error) {
+
+	var resp errorResponse
+	if info := bokchoy.NewTaskErrorInfo(err); info != nil {
+		resp.Error.ID = info.ID
+		resp.Error.Class = info.Class
+		for i := range info.Stack {
+			resp.Error.Messages = append(resp.Error.Messages, info.Stack[i].Messages...)
+		}
+	}
+
+	statusCode := http.StatusInternalServerError
+	switch {
+	case err.Is(ekaerr.NotFound):
+		statusCode = http.StatusNotFound
+	case err.Is(ekaerr.IllegalArgument):
+		statusCode = http.StatusBadRequest
+	case err.Is(ekaerr.RejectedOperation):
+		statusCode = http.StatusConflict
+	case err.Is(ekaerr.UnsupportedOperation):
+		statusCode = http.StatusMethodNotAllowed
+	}
+
+	writeJSON(w, statusCode, resp)
+}
@@ -0,0 +1,116 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package admin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/qioalice/ekago/v3/ekatime"
+
+	"github.com/qioalice/bokchoy"
+	"github.com/qioalice/bokchoy/admin"
+	"github.com/qioalice/bokchoy/internal/brokertest"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testPayload struct {
+	Data string `json:"data"`
+}
+
+func request(t *testing.T, h http.Handler, method, path string, statusCode int, v interface{}) {
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+
+	require.Equal(t, statusCode, rec.Code, rec.Body.String())
+	if v != nil {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), v))
+	}
+}
+
+func TestHandler(t *testing.T) {
+
+	b, err := bokchoy.New(
+		bokchoy.WithBroker(brokertest.NewMemoryBroker()),
+		bokchoy.WithCustomSerializerJSON(testPayload{}),
+		bokchoy.WithDisableOutput(true),
+		bokchoy.WithQueues("tasks.test"),
+	)
+	require.True(t, err.IsNil())
+
+	// The first task is older, so it's the last one in the list.
+	task1 := b.Queue("tasks.test").NewTask(testPayload{Data: "first"})
+	task1.PublishedAt -= ekatime.SECONDS_IN_MINUTE
+	require.True(t, b.Queue("tasks.test").PublishTask(task1).IsNil())
+	task2, err := b.Publish("tasks.test", testPayload{Data: "second"})
+	require.True(t, err.IsNil())
+
+	h := admin.NewHandler(b)
+
+	var queues []admin.QueueInfo
+	request(t, h, "GET", "/queues", http.StatusOK, &queues)
+	require.Len(t, queues, 1)
+	require.Equal(t, "tasks.test", queues[0].Name)
+	require.Equal(t, 2, queues[0].Tasks.Direct)
+
+	request(t, h, "GET", "/queues/unknown", http.StatusNotFound, nil)
+	request(t, h, "GET", "/queues/tasks.test/tasks/unknown", http.StatusNotFound, nil)
+	request(t, h, "POST", "/queues/tasks.test/tasks/unknown/retry", http.StatusNotFound, nil)
+	request(t, h, "POST", "/queues/tasks.test", http.StatusMethodNotAllowed, nil)
+
+	var task admin.TaskInfo
+	request(t, h, "GET", "/queues/tasks.test/tasks/" + task1.ID(), http.StatusOK, &task)
+	require.Equal(t, "waiting", task.Status)
+	require.JSONEq(t, `{"data":"first"}`, string(task.Payload))
+
+	// Waiting task can not be retried.
+	request(t, h, "POST", "/queues/tasks.test/tasks/" + task1.ID() + "/retry", http.StatusConflict, nil)
+
+	request(t, h, "POST", "/queues/tasks.test/tasks/" + task1.ID() + "/cancel", http.StatusOK, &task)
+	require.Equal(t, "cancelled", task.Status)
+
+	var page struct {
+		Total int              `json:"total"`
+		Tasks []admin.TaskInfo `json:"tasks"`
+	}
+	request(t, h, "GET", "/queues/tasks.test/tasks?status=cancelled", http.StatusOK, &page)
+	require.Equal(t, 1, page.Total)
+	require.Equal(t, task1.ID(), page.Tasks[0].ID)
+
+	request(t, h, "GET", "/queues/tasks.test/tasks?limit=1&offset=1", http.StatusOK, &page)
+	require.Equal(t, 2, page.Total)
+	require.Len(t, page.Tasks, 1)
+	require.Equal(t, task1.ID(), page.Tasks[0].ID) // newest first
+
+	request(t, h, "GET", "/queues/tasks.test/tasks?status=unknown", http.StatusBadRequest, nil)
+
+	request(t, h, "POST", "/queues/tasks.test/tasks/" + task1.ID() + "/retry", http.StatusOK, &task)
+	require.Equal(t, "waiting", task.Status)
+
+	request(t, h, "DELETE", "/queues/tasks.test/tasks/" + task2.ID(), http.StatusNoContent, nil)
+	request(t, h, "GET", "/queues/tasks.test/tasks/" + task2.ID(), http.StatusNotFound, nil)
+
+	request(t, h, "POST", "/queues/tasks.test/purge", http.StatusNoContent, nil)
+	request(t, h, "GET", "/queues/tasks.test/tasks", http.StatusOK, &page)
+	require.Equal(t, 0, page.Total)
+}
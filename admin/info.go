@@ -0,0 +1,272 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package admin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+)
+
+type (
+	// QueueInfo is a JSON representation of bokchoy.Queue with its stats.
+	QueueInfo struct {
+		Name      string                 `json:"name"`
+		Tasks     *QueueInfoTasks        `json:"tasks,omitempty"` // nil if Broker failed
+		Consumers QueueInfoConsumers     `json:"consumers"`
+	}
+
+	// QueueInfoTasks is a part of QueueInfo. See bokchoy.BrokerStats.
+	QueueInfoTasks struct {
+		Total   int `json:"total"`
+		Direct  int `json:"direct"`
+		Delayed int `json:"delayed"`
+	}
+
+	// QueueInfoConsumers is a part of QueueInfo. See bokchoy.ConsumersStats.
+	QueueInfoConsumers struct {
+		Total  int `json:"total"`
+		Active int `json:"active"`
+		Frozen int `json:"frozen"`
+	}
+
+	// WorkerInfo is a JSON representation of bokchoy.WorkerInfo.
+	WorkerInfo struct {
+		ID                string            `json:"id"`
+		Hostname          string            `json:"hostname"`
+		PID               int               `json:"pid"`
+		Version           string            `json:"version"`
+		Queues            []WorkerInfoQueue `json:"queues"`
+		StartedAt         time.Time         `json:"started_at"`
+		LastHeartbeat     time.Time         `json:"last_heartbeat"`
+		HeartbeatInterval string            `json:"heartbeat_interval"`
+		Alive             bool              `json:"alive"`
+	}
+
+	// WorkerInfoQueue is a part of WorkerInfo. See bokchoy.WorkerQueueInfo.
+	WorkerInfoQueue struct {
+		Name        string `json:"name"`
+		Concurrency int8   `json:"concurrency"`
+	}
+
+	// TaskInfoProgress is a part of TaskInfo. See bokchoy.Task.Progress().
+	TaskInfoProgress struct {
+		Percent int    `json:"percent"`
+		Message string `json:"message,omitempty"`
+	}
+
+	// TaskInfo is a JSON representation of bokchoy.Task.
+	TaskInfo struct {
+		ID            string                 `json:"id"`
+		QueueName     string                 `json:"queue"`
+		Type          string                 `json:"type,omitempty"`
+		WorkerID      string                 `json:"worker_id,omitempty"`
+		Status        string                 `json:"status"`
+		Progress      *TaskInfoProgress      `json:"progress,omitempty"` // nil if it's never been set
+
+		IdempotencyKey string `json:"idempotency_key,omitempty"` // only if it's not ID
+
+		PublishedAt   time.Time              `json:"published_at"`
+		ETA           *time.Time             `json:"eta,omitempty"`
+		StartedAt     *time.Time             `json:"started_at,omitempty"`
+		ProcessedAt   *time.Time             `json:"processed_at,omitempty"`
+
+		ExecTime      string                 `json:"exec_time,omitempty"`
+		Timeout       string                 `json:"timeout,omitempty"`
+		TTL           string                 `json:"ttl,omitempty"`
+		RetriesLeft   int8                   `json:"retries_left"`
+
+		Headers       map[string]string      `json:"headers,omitempty"`
+
+		Error         *bokchoy.TaskErrorInfo `json:"error,omitempty"`
+		Panic         string                 `json:"panic,omitempty"`
+
+		// Payload is rendered through the queue's serializer
+		// only if it's human readable (see bokchoy.Serializer.IsHumanReadable()).
+		// It's a raw JSON if serialized payload is a valid JSON,
+		// or a JSON string otherwise. PayloadBase64 is used if it's not human readable.
+		Payload       json.RawMessage        `json:"payload,omitempty"`
+		PayloadBase64 string                 `json:"payload_base64,omitempty"`
+	}
+)
+
+var (
+	// taskStatusNames are the names of bokchoy.TaskStatus, that are used by the API.
+	taskStatusNames = map[bokchoy.TaskStatus]string{
+		bokchoy.TASK_STATUS_WAITING:    "waiting",
+		bokchoy.TASK_STATUS_PROCESSING: "processing",
+		bokchoy.TASK_STATUS_RETRYING:   "retrying",
+		bokchoy.TASK_STATUS_SUCCEEDED:  "succeeded",
+		bokchoy.TASK_STATUS_FAILED:     "failed",
+		bokchoy.TASK_STATUS_CANCELLED:  "cancelled",
+		bokchoy.TASK_STATUS_TIMED_OUT:  "timed_out",
+	}
+)
+
+// NewQueueInfo returns a QueueInfo of the given bokchoy.Queue.
+// If Broker failed to count tasks, QueueInfo.Tasks is nil and an error is returned.
+func NewQueueInfo(q *bokchoy.Queue) (QueueInfo, *ekaerr.Error) {
+
+	consumers := q.ConsumersStats()
+	info := QueueInfo{
+		Name: q.Name(),
+		Consumers: QueueInfoConsumers{
+			Total:  consumers.Total,
+			Active: consumers.Active,
+			Frozen: consumers.Frozen,
+		},
+	}
+
+	stats, err := q.Count()
+	if err.IsNotNil() {
+		return info, err.Throw()
+	}
+
+	info.Tasks = &QueueInfoTasks{
+		Total:   stats.Total,
+		Direct:  stats.Direct,
+		Delayed: stats.Delayed,
+	}
+
+	return info, nil
+}
+
+// NewWorkerInfo returns a WorkerInfo of the given bokchoy.WorkerInfo.
+func NewWorkerInfo(worker bokchoy.WorkerInfo) WorkerInfo {
+
+	info := WorkerInfo{
+		ID:                worker.ID,
+		Hostname:          worker.Hostname,
+		PID:               worker.PID,
+		Version:           worker.Version,
+		Queues:            make([]WorkerInfoQueue, len(worker.Queues)),
+		StartedAt:         worker.StartedAt,
+		LastHeartbeat:     worker.LastHeartbeat,
+		HeartbeatInterval: worker.HeartbeatInterval.String(),
+		Alive:             worker.IsAlive,
+	}
+
+	for i := range worker.Queues {
+		info.Queues[i] = WorkerInfoQueue{
+			Name:        worker.Queues[i].Name,
+			Concurrency: worker.Queues[i].Concurrency,
+		}
+	}
+
+	return info
+}
+
+// NewTaskInfo returns a TaskInfo of the given bokchoy.Task.
+// Payload is rendered using the given bokchoy.Serializer (see bokchoy.Queue.SerializerOfTask());
+// it's omitted if serializer is nil or failed.
+func NewTaskInfo(task *bokchoy.Task, serializer bokchoy.Serializer) TaskInfo {
+
+	info := TaskInfo{
+		ID:          task.ID(),
+		QueueName:   task.QueueName(),
+		Type:        task.Type(),
+		WorkerID:    task.WorkerID(),
+		Status:      TaskStatusName(task.Status()),
+		PublishedAt: task.PublishedAt.Std().UTC(),
+		RetriesLeft: task.MaxRetries,
+		Headers:     task.Headers,
+		Error:       task.ErrorInfo(),
+	}
+
+	if key := task.IdempotencyKey(); key != task.ID() {
+		info.IdempotencyKey = key
+	}
+
+	if percent, message := task.Progress(); percent != 0 || message != "" {
+		info.Progress = &TaskInfoProgress{Percent: percent, Message: message}
+	}
+	if task.ETA != 0 {
+		eta := time.Unix(0, task.ETA).UTC()
+		info.ETA = &eta
+	}
+	if startedAt := task.StartedAt(); !startedAt.IsZero() {
+		info.StartedAt = &startedAt
+	}
+	if processedAt := task.ProcessedAt(); !processedAt.IsZero() {
+		info.ProcessedAt = &processedAt
+	}
+
+	if task.ExecTime != 0 {
+		info.ExecTime = task.ExecTime.String()
+	}
+	if task.Timeout != 0 {
+		info.Timeout = task.Timeout.String()
+	}
+	if task.TTL != 0 {
+		info.TTL = task.TTL.String()
+	}
+
+	if task.Panic != nil {
+		info.Panic = fmt.Sprintf("%+v", task.Panic)
+	}
+
+	if serializer == nil || task.Payload == nil {
+		return info
+	}
+
+	encodedPayload, err := serializer.Dumps(task.Payload)
+	switch {
+	case err.IsNotNil() || len(encodedPayload) == 0:
+		// Payload can not be rendered. Nothing to do.
+
+	case !serializer.IsHumanReadable():
+		info.PayloadBase64 = base64.StdEncoding.EncodeToString(encodedPayload)
+
+	case json.Valid(encodedPayload):
+		info.Payload = encodedPayload
+
+	default:
+		info.Payload, _ = json.Marshal(string(encodedPayload))
+	}
+
+	return info
+}
+
+// TaskStatusName returns a name of bokchoy.TaskStatus, that is used by the API:
+// "waiting", "processing", "retrying", "succeeded", "failed", "cancelled",
+// "timed_out" or "invalid".
+func TaskStatusName(status bokchoy.TaskStatus) string {
+	if name, ok := taskStatusNames[status]; ok {
+		return name
+	}
+	return "invalid"
+}
+
+// ParseTaskStatus returns bokchoy.TaskStatus by its name (see TaskStatusName()).
+// Case insensitive. Returns false if there is no such status.
+func ParseTaskStatus(name string) (bokchoy.TaskStatus, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for status, statusName := range taskStatusNames {
+		if statusName == name {
+			return status, true
+		}
+	}
+	return bokchoy.TASK_STATUS_INVALID, false
+}
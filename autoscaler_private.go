@@ -0,0 +1,155 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type (
+	// autoscaler changes the number of Queue's consumers (Queue.SetConcurrency())
+	// between bounds of WithAutoscaling() option, according with Queue's backlog
+	// and the measured exec time of its tasks.
+	//
+	// It's created by Queue.start() if autoscaling is enabled,
+	// its loop is run in a separate goroutine until stop() is called.
+	autoscaler struct {
+		queue     *Queue
+
+		mu        sync.Mutex
+		execTime  time.Duration // sum of exec time of processed tasks since the last scaling
+		processed int           // number of processed tasks since the last scaling
+
+		stopChan  chan struct{}
+		stopOnce  sync.Once
+		doneChan  chan struct{}
+	}
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	// _AUTOSCALER_INTERVAL is how often autoscaler checks Queue's backlog.
+	// It's also the time, autoscaler tries to process the whole backlog for.
+	_AUTOSCALER_INTERVAL = 1 * time.Second
+)
+
+func newAutoscaler(q *Queue) *autoscaler {
+	return &autoscaler{
+		queue:    q,
+		stopChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+}
+
+// observe reports that the processing of some tasks, started at startedAt, is done.
+// It's called by consumers.
+func (a *autoscaler) observe(startedAt time.Time, processed int) {
+	execTime := time.Since(startedAt)
+
+	a.mu.Lock()
+	a.execTime += execTime
+	a.processed += processed
+	a.mu.Unlock()
+}
+
+// run is autoscaler's loop. It's done when stop() is called.
+func (a *autoscaler) run() {
+	defer close(a.doneChan)
+
+	ticker := time.NewTicker(_AUTOSCALER_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stopChan:
+			return
+		case <-ticker.C:
+			a.scale()
+		}
+	}
+}
+
+// stop stops autoscaler's loop and waits until it's done.
+// Must be called only if run() has been called. Does nothing if already stopped.
+func (a *autoscaler) stop() {
+	a.stopOnce.Do(func() { close(a.stopChan) })
+	<-a.doneChan
+}
+
+// scale changes Queue's concurrency according with its backlog
+// and the average exec time of tasks, processed since the last scaling.
+func (a *autoscaler) scale() {
+	const s = "Bokchoy: Failed to scale consumers. Failed to get queue's backlog. "
+
+	stats, err := a.queue.Count()
+	if err.IsNotNil() {
+		a.queue.parent.logger.Copy().
+			WithString("bokchoy_queue_name", a.queue.name).
+			Warne(s, err)
+		return
+	}
+
+	a.mu.Lock()
+	execTime, processed := a.execTime, a.processed
+	a.execTime, a.processed = 0, 0
+	a.mu.Unlock()
+
+	current := a.queue.Concurrency()
+	desired := desiredConcurrency(current, stats.Direct, execTime, processed)
+
+	if desired != current {
+		a.queue.SetConcurrency(desired) // clamps by autoscaling bounds
+	}
+}
+
+// desiredConcurrency returns the number of consumers, that is enough
+// to process backlog tasks for _AUTOSCALER_INTERVAL, considering the average
+// exec time of processed tasks (execTime is their sum).
+//
+// It grows concurrency immediately, but shrinks it one by one to avoid flapping.
+// If there is backlog, but no tasks has been processed (exec time is unknown),
+// concurrency grows one by one.
+func desiredConcurrency(current int8, backlog int, execTime time.Duration, processed int) int8 {
+
+	var desired int64
+	switch {
+
+	case backlog == 0:
+		desired = 0
+
+	case processed == 0:
+		desired = int64(current) + 1
+
+	default:
+		averageExecTime := int64(execTime) / int64(processed)
+		desired = (int64(backlog) * averageExecTime + int64(_AUTOSCALER_INTERVAL) - 1) /
+			int64(_AUTOSCALER_INTERVAL)
+	}
+
+	switch {
+	case desired < int64(current):
+		desired = int64(current) - 1
+	case desired > math.MaxInt8:
+		desired = math.MaxInt8
+	}
+
+	return int8(desired)
+}
@@ -0,0 +1,36 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDesiredConcurrency(t *testing.T) {
+	const ms = time.Millisecond
+
+	require.Equal(t, int8(3), desiredConcurrency(4, 0, 0, 0))            // shrinks one by one
+	require.Equal(t, int8(5), desiredConcurrency(4, 10, 0, 0))           // exec time is unknown
+	require.Equal(t, int8(10), desiredConcurrency(1, 100, 10*100*ms, 10)) // 100 tasks * 100ms
+	require.Equal(t, int8(1), desiredConcurrency(1, 5, 10*ms, 1))
+	require.Equal(t, int8(127), desiredConcurrency(1, 100000, 1000*ms, 1))
+}
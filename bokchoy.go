@@ -35,11 +35,15 @@ type (
 		defaultOptions *options
 		broker         Broker
 		queues         map[string]*Queue
+		events         *EventStream
 
 		handlers    []HandlerFunc
 
 		logger         *ekalog.Logger
 		isStarted      bool
+		heartbeat      *heartbeat   // nil if it's not running, see WithHeartbeatInterval()
+		reaper         *reaper      // nil if heartbeat is not running or orphans are ignored
+		relay          *outboxRelay // nil if it's not running, see WithOutbox()
 
 		queueNamesWithDuplicateHandlers []string
 	}
@@ -58,6 +62,8 @@ func New(options ...Option) (*Bokchoy, *ekaerr.Error) {
 		}
 	}
 
+	optionsObject.resetTaskOptions()
+
 	// Validate options.
 	// Some options are must presented by user.
 	switch {
@@ -107,6 +113,7 @@ func New(options ...Option) (*Bokchoy, *ekaerr.Error) {
 		sema:           &sync.Mutex{},
 		logger:         logger,
 		defaultOptions: optionsObject,
+		events:         newEventStream(logger),
 	}
 
 	for i, n := 0, len(optionsObject.Queues); i < n; i++ {
@@ -149,6 +156,7 @@ func (b *Bokchoy) Queue(name string, options ...Option) *Queue {
 		bokchoyDefaultOptionsCopy := *b.defaultOptions
 		optionsObject = &bokchoyDefaultOptionsCopy
 		optionsObject.apply(options)
+		optionsObject.resetTaskOptions()
 	}
 
 	q, ok := b.queues[name]
@@ -160,12 +168,35 @@ func (b *Bokchoy) Queue(name string, options ...Option) *Queue {
 			wg:       b.wg,
 			handlers: b.handlers,
 		}
+		q.breaker = newCircuitBreaker(q)
 		b.queues[name] = q
 	}
 
 	return q
 }
 
+// Queues returns all declared queues sorted by their names.
+func (b *Bokchoy) Queues() []*Queue {
+
+	if !b.isValid() {
+		return nil
+	}
+
+	b.sema.Lock()
+	defer b.sema.Unlock()
+
+	return b.sortedQueues()
+}
+
+// Events returns an EventStream, that delivers lifecycle events
+// of all Bokchoy's queues and their tasks. See EventStream for more details.
+func (b *Bokchoy) Events() *EventStream {
+	if !b.isValid() {
+		return nil
+	}
+	return b.events
+}
+
 // Run runs the system and block the current goroutine.
 func (b *Bokchoy) Run() *ekaerr.Error {
 	const s = "Bokchoy: Failed to run the whole Bokchoy broker. "
@@ -192,10 +223,29 @@ func (b *Bokchoy) Run() *ekaerr.Error {
 		WithArray("bokchoy_queues_list", queuesList).
 		Debug("Bokchoy: Starting queues and their consumers...")
 
+	// Worker must be registered before its consumers claim tasks,
+	// and it must be alive before it's registered as the claimant of tasks,
+	// otherwise the reaper of another worker may consider it dead.
+	b.heartbeat = newHeartbeat(b)
+	b.reaper = newReaper(b, b.heartbeat)
+	if b.heartbeat != nil {
+		b.heartbeat.beat()
+		go b.heartbeat.run()
+	}
+	if b.reaper != nil {
+		b.reaper.register()
+		go b.reaper.run()
+	}
+
 	for _, queue := range b.queues {
 		queue.start()
 	}
 
+	b.relay = newOutboxRelay(b)
+	if b.relay != nil {
+		go b.relay.run()
+	}
+
 	b.isStarted = true
 	b.sema.Unlock()
 
@@ -232,10 +282,22 @@ func (b *Bokchoy) Stop() {
 		WithArray("bokchoy_queues_list", queuesList).
 		Debug("Bokchoy: Stopping queues and their consumers...")
 
+	if b.relay != nil {
+		b.relay.stop()
+	}
+
 	for _, queue := range b.queues {
 		queue.stop() // can not fail
 	}
 
+	// Worker is alive until its queues are stopped.
+	if b.reaper != nil {
+		b.reaper.stop()
+	}
+	if b.heartbeat != nil {
+		b.heartbeat.stop()
+	}
+
 	b.logger.Copy().
 		WithArray("bokchoy_queues_list", queuesList).
 		Debug("Bokchoy: Queues and their consumers has been stopped.")
@@ -247,6 +309,19 @@ func (b *Bokchoy) Use(queueName string, handlers ...HandlerFunc) *Bokchoy {
 	return b.Queue(queueName).Use(handlers...).parent
 }
 
+// Handle append a new handlers for tasks of the given type to the queue.
+// See Queue.Handle() for more details.
+// Does nothing if Bokchoy already running (Run() has called).
+func (b *Bokchoy) Handle(queueName, taskType string, handlers ...HandlerFunc) *Bokchoy {
+	return b.Queue(queueName).Handle(taskType, handlers...).parent
+}
+
+// Wrap append a new around-style middleware to the queue.
+// Does nothing if Bokchoy already running (Run() has called).
+func (b *Bokchoy) Wrap(queueName string, middlewares ...Middleware) *Bokchoy {
+	return b.Queue(queueName).Wrap(middlewares...).parent
+}
+
 // Empty empties initialized queues.
 // Returns an error of the first queue that can not be emptied.
 // Does nothing (but returns an error) if Bokchoy already running (Run() has called).
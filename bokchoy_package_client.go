@@ -59,6 +59,11 @@ func Use(queueName string, handlers ...HandlerFunc) *Bokchoy {
 	return defaultClient.Use(queueName, handlers...)
 }
 
+// Wrap append a new around-style middleware to the queue of the default Bokchoy.
+func Wrap(queueName string, middlewares ...Middleware) *Bokchoy {
+	return defaultClient.Wrap(queueName, middlewares...)
+}
+
 func Empty() *ekaerr.Error {
 	return defaultClient.Empty().Throw()
 }
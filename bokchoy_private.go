@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"os"
 	"os/user"
+	"sort"
 
 	"github.com/qioalice/ekago/v3/ekasys"
 )
@@ -42,6 +43,21 @@ func (b *Bokchoy) queueNames() []string {
 	return names
 }
 
+// sortedQueues returns the managed queues sorted by their names.
+// Caller must take responsibility about locking to provide thread-safety.
+func (b *Bokchoy) sortedQueues() []*Queue {
+
+	queueNames := b.queueNames()
+	sort.Strings(queueNames)
+
+	queues := make([]*Queue, len(queueNames))
+	for i := range queueNames {
+		queues[i] = b.queues[queueNames[i]]
+	}
+
+	return queues
+}
+
 // displayOutput writes ASCII hello message to the synced STDOUT.
 func (b *Bokchoy) displayOutput() {
 
@@ -72,6 +88,7 @@ func (b *Bokchoy) displayOutput() {
 	buf.Write("	- TTL:             %s\n", b.defaultOptions.TTL)
 	buf.Write("	- Countdown:       %s\n", b.defaultOptions.Countdown)
 	buf.Write("	- Timeout:         %s\n", b.defaultOptions.Timeout)
+	buf.Write("	- Signed tasks:    %t\n", len(b.defaultOptions.SigningKey) > 0)
 
 	queueNames := b.queueNames()
 	if len(queueNames) > 0 {
@@ -58,6 +58,113 @@ type Broker interface {
 	Consume(queueName string, maxETA int64) ([][]byte, *ekaerr.Error)
 }
 
+// BrokerQueuesLister is an optional interface, that a Broker may implement
+// to report the names of all queues it stores.
+type BrokerQueuesLister interface {
+
+	// Queues returns the names of all queues, stored in the broker.
+	Queues() ([]string, *ekaerr.Error)
+}
+
+// BrokerCancellationNotifier is an optional interface, that a Broker may implement
+// to deliver cancellation signals of tasks (see Queue.Cancel())
+// to all Bokchoy's instances, that consume the same queue, using its pub/sub.
+//
+// If Broker doesn't implement it, only tasks that are processed
+// by the same Bokchoy's instance, Queue.Cancel() is called by,
+// may be interrupted while they're under processing.
+type BrokerCancellationNotifier interface {
+
+	// NotifyCancellation publishes a cancellation signal of the task.
+	NotifyCancellation(queueName, taskID string) *ekaerr.Error
+
+	// SubscribeCancellation calls callback for each cancellation signal
+	// of the queue's tasks (including published by the current instance),
+	// until returned func is called.
+	SubscribeCancellation(queueName string, callback func(taskID string)) (func(), *ekaerr.Error)
+}
+
+// BrokerPendingRemover is an optional interface, that a Broker may implement
+// to remove a published task from the queue's pending and delayed structures,
+// so the cancelled task won't be consumed at all (see Queue.Cancel()).
+//
+// If Broker doesn't implement it, the cancelled task is still consumed,
+// but it's dropped by the consumer once its stored copy is checked.
+type BrokerPendingRemover interface {
+
+	// RemovePending atomically removes the task from the queue's pending
+	// and delayed structures, keeping its stored copy.
+	// It's not an error if the task is not pending (e.g. it's already consumed).
+	RemovePending(queueName, taskID string) *ekaerr.Error
+}
+
+// BrokerBatchPublisher is an optional interface, that a Broker may implement
+// to publish many tasks by one round-trip or transaction. See Queue.PublishBatch().
+//
+// If Broker doesn't implement it, tasks are published one-by-one using Publish().
+type BrokerBatchPublisher interface {
+
+	// PublishMany publishes raw data of many tasks.
+	// Returns nil if all of them are published. Otherwise, returns errors
+	// of the same length as items: errs[i] is the error of items[i], nil if it's published.
+	PublishMany(queueName string, items []BrokerPublishItem) []*ekaerr.Error
+}
+
+// BrokerPublishItem is a raw data of the Task, that is published
+// by BrokerBatchPublisher. See Broker.Publish() for the meaning of fields.
+type BrokerPublishItem struct {
+	TaskID          string
+	TaskPayload     []byte
+	TaskEtaUnixNano int64
+}
+
+// BrokerWorkerRegistry is an optional interface, that a Broker may implement
+// to store the information about running Bokchoy instances (workers),
+// that is saved by their heartbeats. See Bokchoy.Workers().
+//
+// If Broker doesn't implement it, workers are not registered at all.
+type BrokerWorkerRegistry interface {
+
+	// SaveWorker saves (overwrites) the encoded worker's info.
+	// It may be removed by the broker after ttl.
+	SaveWorker(workerID string, data []byte, ttl time.Duration) *ekaerr.Error
+
+	// DeleteWorker removes the worker's info.
+	// It's not an error if there's no such worker.
+	DeleteWorker(workerID string) *ekaerr.Error
+
+	// Workers returns the encoded infos of all stored workers.
+	Workers() ([][]byte, *ekaerr.Error)
+}
+
+// BrokerIdempotencyStore is an optional interface, that a Broker may implement
+// to store the idempotency keys of the succeeded tasks. See WithIdempotency().
+//
+// A key is reserved before the task is handled and it's saved once the task
+// is succeeded, so the tasks with the same key are not handled concurrently.
+// If Broker doesn't implement it, tasks are not checked whether
+// they've been succeeded already at all.
+type BrokerIdempotencyStore interface {
+
+	// ReserveIdempotencyKey atomically stores the idempotency key of the queue
+	// as reserved (SETNX-like), unless it's stored already (reserved or saved).
+	// Reports whether the key has been reserved by this call.
+	// The reservation may be removed by the broker after ttl.
+	ReserveIdempotencyKey(queueName, key string, ttl time.Duration) (bool, *ekaerr.Error)
+
+	// SaveIdempotencyKey saves (overwrites) the idempotency key of the queue,
+	// even if it's reserved. It may be removed by the broker after ttl.
+	SaveIdempotencyKey(queueName, key string, ttl time.Duration) *ekaerr.Error
+
+	// HasIdempotencyKey reports whether the idempotency key of the queue is saved.
+	// The reserved key is not saved.
+	HasIdempotencyKey(queueName, key string) (bool, *ekaerr.Error)
+
+	// DeleteIdempotencyKey removes the idempotency key of the queue,
+	// either reserved or saved. It's not an error if there's no such key.
+	DeleteIdempotencyKey(queueName, key string) *ekaerr.Error
+}
+
 // BrokerStats is the statistics returned by a Queue.
 type BrokerStats struct {
 	Total   int
@@ -0,0 +1,108 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+)
+
+type (
+	// BrokerFactory creates a new Broker using the given DSN
+	// (e.g. "redis://localhost:6379/0").
+	BrokerFactory func(dsn string) (Broker, *ekaerr.Error)
+)
+
+var (
+	brokerFactoriesMu sync.RWMutex
+	brokerFactories   = make(map[string]BrokerFactory)
+)
+
+// RegisterBroker registers a BrokerFactory for the given DSN scheme
+// (e.g. "redis"), so the Broker may be created by OpenBroker().
+// Broker implementations should call it from their init() functions.
+//
+// Registering the same scheme twice replaces the previous factory.
+// Does nothing if either scheme is empty or factory is nil.
+func RegisterBroker(scheme string, factory BrokerFactory) {
+
+	if scheme == "" || factory == nil {
+		return
+	}
+
+	brokerFactoriesMu.Lock()
+	defer brokerFactoriesMu.Unlock()
+
+	brokerFactories[scheme] = factory
+}
+
+// RegisteredBrokers returns the sorted DSN schemes of registered Broker s.
+// See RegisterBroker().
+func RegisteredBrokers() []string {
+
+	brokerFactoriesMu.RLock()
+	defer brokerFactoriesMu.RUnlock()
+
+	schemes := make([]string, 0, len(brokerFactories))
+	for scheme := range brokerFactories {
+		schemes = append(schemes, scheme)
+	}
+
+	sort.Strings(schemes)
+	return schemes
+}
+
+// OpenBroker returns a new Broker, created by the BrokerFactory,
+// that has been registered for the DSN's scheme. See RegisterBroker().
+func OpenBroker(dsn string) (Broker, *ekaerr.Error) {
+	const s = "Bokchoy: Failed to open Broker. "
+
+	u, legacyErr := url.Parse(dsn)
+	if legacyErr != nil || u.Scheme == "" {
+		return nil, ekaerr.IllegalArgument.
+			New(s + "DSN must be an URL with a scheme, like \"redis://localhost:6379\".").
+			WithString("bokchoy_broker_dsn", dsn).
+			Throw()
+	}
+
+	brokerFactoriesMu.RLock()
+	factory := brokerFactories[u.Scheme]
+	brokerFactoriesMu.RUnlock()
+
+	if factory == nil {
+		return nil, ekaerr.NotFound.
+			New(s + "There is no registered Broker for DSN's scheme. " +
+				"Did you import the Broker's package?").
+			WithString("bokchoy_broker_scheme", u.Scheme).
+			WithArray("bokchoy_broker_registered", RegisteredBrokers()).
+			Throw()
+	}
+
+	broker, err := factory(dsn)
+	if err.IsNotNil() {
+		return nil, err.AddMessage(s).
+			WithString("bokchoy_broker_scheme", u.Scheme).
+			Throw()
+	}
+
+	return broker, nil
+}
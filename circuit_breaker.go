@@ -0,0 +1,62 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+type (
+	// CircuitBreakerState is a state of Queue's circuit breaker.
+	// See WithCircuitBreaker() for more details.
+	CircuitBreakerState int8
+
+	// CircuitBreakerStats is the statistics of Queue's circuit breaker.
+	CircuitBreakerStats struct {
+		State         CircuitBreakerState
+		Trips         int // how many times it has been opened being closed
+		BrokerErrors  int // Broker's errors in a row
+		HandlerErrors int // failed, timed out tasks in a row
+	}
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	CIRCUIT_BREAKER_STATE_INVALID   CircuitBreakerState = 0
+	CIRCUIT_BREAKER_STATE_CLOSED    CircuitBreakerState = 1
+	CIRCUIT_BREAKER_STATE_OPEN      CircuitBreakerState = 2
+	CIRCUIT_BREAKER_STATE_HALF_OPEN CircuitBreakerState = 3
+)
+
+func (cbs CircuitBreakerState) String() string {
+	switch cbs {
+	case CIRCUIT_BREAKER_STATE_INVALID:   return "Invalid"
+	case CIRCUIT_BREAKER_STATE_CLOSED:    return "Closed"
+	case CIRCUIT_BREAKER_STATE_OPEN:      return "Open"
+	case CIRCUIT_BREAKER_STATE_HALF_OPEN: return "HalfOpen"
+	default:                              return "Incorrect"
+	}
+}
+
+// CircuitBreakerStats returns the statistics of the queue's circuit breaker.
+// See WithCircuitBreaker() for more details.
+func (q *Queue) CircuitBreakerStats() CircuitBreakerStats {
+
+	if !q.isValid() {
+		return CircuitBreakerStats{}
+	}
+
+	return q.breaker.stats()
+}
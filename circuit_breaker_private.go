@@ -0,0 +1,247 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+)
+
+type (
+	// circuitBreaker decreases load to the either Broker, or any your service
+	// when an errors occurred one-by-one in a row. See WithCircuitBreaker().
+	//
+	// Don't worry, it's OK if sometimes your Task s are failed,
+	// or Broker returns an errors (while retrieving/saving tasks).
+	// But it's not, if there's ONLY errors, nothing more.
+	// Each task, each Broker's call, everything is failed.
+	//
+	// Broker's errors and handlers' errors (failed, timed out tasks)
+	// are counted separately, each of them has its own threshold.
+	// When any threshold is reached, circuit breaker is opened:
+	// all slave consumer s are frozen and the master one is paused for cool-down.
+	// Then it becomes half-open and only master consumer keeps consuming.
+	// It's closed back (and slaves are unfrozen) by the first success
+	// of the same kind it has been opened by, or it's opened again by any error.
+	circuitBreaker struct {
+		queue            *Queue
+
+		brokerThreshold  int // 0 means Broker's errors are not counted
+		handlerThreshold int // 0 means handlers' errors are not counted
+		coolDown         time.Duration
+
+		mu               sync.Mutex
+		state            CircuitBreakerState
+		openedBy         int8 // _CIRCUIT_BREAKER_ERRORS_...
+		openedAt         time.Time
+		brokerErrors     int
+		handlerErrors    int
+		trips            int
+	}
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	_CIRCUIT_BREAKER_ERRORS_BROKER  int8 = 1
+	_CIRCUIT_BREAKER_ERRORS_HANDLER int8 = 2
+)
+
+func circuitBreakerErrorsKindName(kind int8) string {
+	if kind == _CIRCUIT_BREAKER_ERRORS_HANDLER {
+		return "handlers"
+	}
+	return "broker"
+}
+
+func newCircuitBreaker(q *Queue) *circuitBreaker {
+	return &circuitBreaker{
+		queue:            q,
+		brokerThreshold:  q.options.CircuitBreakerBrokerErrors,
+		handlerThreshold: q.options.CircuitBreakerHandlerErrors,
+		coolDown:         q.options.CircuitBreakerCoolDown,
+		state:            CIRCUIT_BREAKER_STATE_CLOSED,
+	}
+}
+
+func (cb *circuitBreaker) stats() CircuitBreakerStats {
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return CircuitBreakerStats{
+		State:         cb.state,
+		Trips:         cb.trips,
+		BrokerErrors:  cb.brokerErrors,
+		HandlerErrors: cb.handlerErrors,
+	}
+}
+
+func (cb *circuitBreaker) isClosed() bool {
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.state == CIRCUIT_BREAKER_STATE_CLOSED
+}
+
+// probe returns how long master consumer must wait before the next consuming.
+// If circuit breaker is open and its cool-down is passed, it becomes half-open.
+func (cb *circuitBreaker) probe() time.Duration {
+
+	cb.mu.Lock()
+
+	if cb.state != CIRCUIT_BREAKER_STATE_OPEN {
+		cb.mu.Unlock()
+		return 0
+	}
+
+	if wait := cb.coolDown - time.Since(cb.openedAt); wait > 0 {
+		cb.mu.Unlock()
+		return wait
+	}
+
+	cb.state = CIRCUIT_BREAKER_STATE_HALF_OPEN
+	cb.mu.Unlock()
+
+	cb.queue.parent.logger.Copy().
+		WithString("bokchoy_queue_name", cb.queue.name).
+		Warn("Bokchoy: Circuit breaker is half-open. Master consumer is probing.")
+
+	cb.queue.parent.events.emit(EVENT_TYPE_CIRCUIT_BREAKER_HALF_OPENED, cb.queue.name, nil)
+	return 0
+}
+
+// reportBroker counts the result of Broker's call, made by consumer to consume tasks.
+func (cb *circuitBreaker) reportBroker(err *ekaerr.Error) {
+	const s = "Bokchoy: An error occurred while trying to consume tasks of queue. "
+
+	if err.IsNotNil() {
+		cb.queue.parent.logger.Copy().
+			WithString("bokchoy_queue_name", cb.queue.name).
+			Errore(s, err)
+	}
+
+	cb.report(_CIRCUIT_BREAKER_ERRORS_BROKER, err.IsNotNil())
+}
+
+// reportSaving counts the result of saving processed Task (or returning it
+// back to the pool to be retried later) as a result of Broker's call,
+// the same way as reportBroker() does.
+func (cb *circuitBreaker) reportSaving(err *ekaerr.Error) {
+	const s = "Bokchoy: An error occurred while trying to save processed task of queue. "
+
+	if err.IsNotNil() {
+		cb.queue.parent.logger.Copy().
+			WithString("bokchoy_queue_name", cb.queue.name).
+			Errore(s, err)
+	}
+
+	cb.report(_CIRCUIT_BREAKER_ERRORS_BROKER, err.IsNotNil())
+}
+
+// reportHandler counts the result of Task's processing.
+// Cancelled tasks are not counted at all.
+func (cb *circuitBreaker) reportHandler(t *Task) {
+	switch t.status {
+	case TASK_STATUS_SUCCEEDED:
+		cb.report(_CIRCUIT_BREAKER_ERRORS_HANDLER, false)
+	case TASK_STATUS_FAILED, TASK_STATUS_RETRYING, TASK_STATUS_TIMED_OUT:
+		cb.report(_CIRCUIT_BREAKER_ERRORS_HANDLER, true)
+	}
+}
+
+// report implements all transitions between circuit breaker's states
+// caused by errors or successes of the given kind.
+// Unfreezes slave consumer s when it's closed.
+func (cb *circuitBreaker) report(kind int8, failed bool) {
+
+	counter, threshold := &cb.brokerErrors, cb.brokerThreshold
+	if kind == _CIRCUIT_BREAKER_ERRORS_HANDLER {
+		counter, threshold = &cb.handlerErrors, cb.handlerThreshold
+	}
+
+	if threshold == 0 {
+		return
+	}
+
+	eventType := EVENT_TYPE_INVALID
+
+	cb.mu.Lock()
+
+	switch {
+	case !failed:
+		*counter = 0
+		if cb.state == CIRCUIT_BREAKER_STATE_HALF_OPEN && cb.openedBy == kind {
+			cb.state = CIRCUIT_BREAKER_STATE_CLOSED
+			cb.brokerErrors, cb.handlerErrors = 0, 0
+			eventType = EVENT_TYPE_CIRCUIT_BREAKER_CLOSED
+		}
+
+	case cb.state == CIRCUIT_BREAKER_STATE_HALF_OPEN:
+		*counter++
+		cb.state, cb.openedBy, cb.openedAt = CIRCUIT_BREAKER_STATE_OPEN, kind, time.Now()
+		eventType = EVENT_TYPE_CIRCUIT_BREAKER_OPENED
+
+	default:
+		*counter++
+		if cb.state == CIRCUIT_BREAKER_STATE_CLOSED && *counter >= threshold {
+			cb.state, cb.openedBy, cb.openedAt = CIRCUIT_BREAKER_STATE_OPEN, kind, time.Now()
+			cb.trips++
+			eventType = EVENT_TYPE_CIRCUIT_BREAKER_OPENED
+		}
+	}
+
+	errorsInARow := *counter
+	cb.mu.Unlock()
+
+	switch eventType {
+	case EVENT_TYPE_CIRCUIT_BREAKER_OPENED:
+		cb.queue.parent.logger.Copy().
+			WithString("bokchoy_queue_name", cb.queue.name).
+			WithString("bokchoy_circuit_breaker_opened_by", circuitBreakerErrorsKindName(kind)).
+			WithInt("bokchoy_circuit_breaker_errors_in_a_row", errorsInARow).
+			WithDuration("bokchoy_circuit_breaker_cool_down", cb.coolDown).
+			Error("Bokchoy: Circuit breaker is opened. " +
+				"All consumers but one will be frozen until errors are get out.")
+
+	case EVENT_TYPE_CIRCUIT_BREAKER_CLOSED:
+		cb.queue.parent.logger.Copy().
+			WithString("bokchoy_queue_name", cb.queue.name).
+			Warn("Bokchoy: Circuit breaker is closed. All consumers are unfrozen.")
+
+		cb.queue.unfreezeConsumers()
+
+	default:
+		return
+	}
+
+	cb.queue.parent.events.emit(eventType, cb.queue.name, nil)
+}
+
+// reset closes circuit breaker and resets its counters, but not the number of trips.
+func (cb *circuitBreaker) reset() {
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.state = CIRCUIT_BREAKER_STATE_CLOSED
+	cb.brokerErrors, cb.handlerErrors = 0, 0
+}
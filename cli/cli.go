@@ -0,0 +1,250 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+// Package cli implements the command-line tool for inspecting and manipulating
+// Bokchoy's queues and tasks. See cmd/bokchoy for the binary.
+//
+// The tool connects to the Broker using a DSN (--broker flag or BOKCHOY_BROKER env),
+// that is opened by bokchoy.OpenBroker(). Thus the Broker's package must be
+// imported to register itself. If you use your own Broker, build your own binary:
+//
+//     package main
+//
+//     import (
+//         "os"
+//
+//         "github.com/qioalice/bokchoy/cli"
+//
+//         _ "your/broker/package" // calls bokchoy.RegisterBroker() in init()
+//     )
+//
+//     func main() {
+//         os.Exit(cli.Main(os.Args[1:], os.Stdout, os.Stderr))
+//     }
+//
+// Task's payloads are decoded and encoded as JSON.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+	"github.com/qioalice/ekago/v3/ekalog"
+
+	"github.com/qioalice/bokchoy"
+)
+
+type (
+	// config is the parsed command line.
+	config struct {
+		broker     string
+		signingKey string
+		queues     string
+		output     string
+		verbose    bool
+
+		// list
+		status     string
+		offset     int
+		limit      int
+
+		// publish
+		payload    string
+		countdown  time.Duration
+		headers    headersFlag
+		taskType   string
+
+		command    string
+		args       []string
+	}
+
+	// headersFlag is a repeatable flag.Value of "key=value" headers.
+	headersFlag map[string]string
+
+	// command is a CLI's command.
+	command struct {
+		usage   string
+		help    string
+		nArgs   int
+		run     func(c *cli, args []string) *ekaerr.Error
+	}
+
+	// cli is the state of running command.
+	cli struct {
+		cfg    *config
+		b      *bokchoy.Bokchoy
+		broker bokchoy.Broker
+		stdout io.Writer
+	}
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	_EXIT_CODE_OK    = 0
+	_EXIT_CODE_ERROR = 1
+	_EXIT_CODE_USAGE = 2
+
+	_OUTPUT_TEXT = "text"
+	_OUTPUT_JSON = "json"
+)
+
+// Main runs the command line tool with the given arguments (w/o program name),
+// writing output to stdout and errors to stderr. Returns the exit code.
+func Main(args []string, stdout, stderr io.Writer) int {
+
+	cfg, legacyErr := parseArgs(args, stderr)
+	if legacyErr != nil {
+		if legacyErr != flag.ErrHelp {
+			_, _ = fmt.Fprintln(stderr, "Error:", legacyErr)
+		}
+		printUsage(stderr)
+		return _EXIT_CODE_USAGE
+	}
+
+	cmd, ok := commands[cfg.command]
+	switch {
+	case !ok:
+		_, _ = fmt.Fprintf(stderr, "Error: unknown command %q\n", cfg.command)
+		printUsage(stderr)
+		return _EXIT_CODE_USAGE
+
+	case len(cfg.args) != cmd.nArgs:
+		_, _ = fmt.Fprintf(stderr, "Usage: bokchoy %s\n", cmd.usage)
+		return _EXIT_CODE_USAGE
+
+	case cfg.output != _OUTPUT_TEXT && cfg.output != _OUTPUT_JSON:
+		_, _ = fmt.Fprintf(stderr, "Error: unknown output format %q\n", cfg.output)
+		return _EXIT_CODE_USAGE
+	}
+
+	c, err := newCLI(cfg, stdout)
+	if err.IsNil() {
+		err = cmd.run(c, cfg.args)
+	}
+
+	if err.IsNotNil() {
+		printError(stderr, err)
+		return _EXIT_CODE_ERROR
+	}
+
+	return _EXIT_CODE_OK
+}
+
+// parseArgs parses flags, that may be placed anywhere (before, after
+// or between positional arguments), and positional arguments.
+func parseArgs(args []string, stderr io.Writer) (*config, error) {
+
+	cfg := &config{headers: make(headersFlag)}
+
+	fs := flag.NewFlagSet("bokchoy", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {}
+
+	fs.StringVar(&cfg.broker, "broker", os.Getenv("BOKCHOY_BROKER"), "")
+	fs.StringVar(&cfg.signingKey, "signing-key", os.Getenv("BOKCHOY_SIGNING_KEY"), "")
+	fs.StringVar(&cfg.queues, "queues", os.Getenv("BOKCHOY_QUEUES"), "")
+	fs.StringVar(&cfg.output, "output", _OUTPUT_TEXT, "")
+	fs.BoolVar(&cfg.verbose, "verbose", false, "")
+	fs.StringVar(&cfg.status, "status", "", "")
+	fs.IntVar(&cfg.offset, "offset", 0, "")
+	fs.IntVar(&cfg.limit, "limit", 50, "")
+	fs.StringVar(&cfg.payload, "json", "", "")
+	fs.DurationVar(&cfg.countdown, "countdown", 0, "")
+	fs.Var(cfg.headers, "header", "")
+	fs.StringVar(&cfg.taskType, "type", "", "")
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if len(positional) == 0 {
+		return nil, flag.ErrHelp
+	}
+
+	cfg.command, cfg.args = positional[0], positional[1:]
+	return cfg, nil
+}
+
+// newCLI opens the Broker and creates Bokchoy, that is used to manage queues.
+func newCLI(cfg *config, stdout io.Writer) (*cli, *ekaerr.Error) {
+	const s = "Bokchoy.CLI: Failed to initialize. "
+
+	if cfg.broker == "" {
+		return nil, ekaerr.IllegalArgument.
+			New(s + "Broker's DSN must be presented using --broker flag or BOKCHOY_BROKER env.").
+			Throw()
+	}
+
+	// Otherwise, it's reported as an unknown DSN's scheme, that is misleading.
+	if len(bokchoy.RegisteredBrokers()) == 0 {
+		return nil, ekaerr.UnsupportedOperation.
+			New(s + "No Broker is compiled in this binary. " +
+				"Build your own one, importing your Broker's package (see cli package).").
+			Throw()
+	}
+
+	broker, err := bokchoy.OpenBroker(cfg.broker)
+	if err.IsNotNil() {
+		return nil, err.AddMessage(s).Throw()
+	}
+
+	logger := ekalog.If(cfg.verbose)
+
+	b, err := bokchoy.New(
+		bokchoy.WithBroker(broker),
+		bokchoy.WithSerializer(bokchoy.DefaultSerializerJSON()),
+		bokchoy.WithLogger(logger),
+		bokchoy.WithDisableOutput(true),
+		bokchoy.WithSigningKey([]byte(cfg.signingKey)),
+	)
+	if err.IsNotNil() {
+		return nil, err.AddMessage(s).Throw()
+	}
+
+	return &cli{cfg: cfg, b: b, broker: broker, stdout: stdout}, nil
+}
+
+func (h headersFlag) String() string {
+	pairs := make([]string, 0, len(h))
+	for k, v := range h {
+		pairs = append(pairs, k + "=" + v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (h headersFlag) Set(value string) error {
+	idx := strings.IndexByte(value, '=')
+	if idx <= 0 {
+		return fmt.Errorf("header must be in key=value format, got %q", value)
+	}
+	h[value[:idx]] = value[idx+1:]
+	return nil
+}
@@ -0,0 +1,179 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+	"github.com/qioalice/bokchoy/admin"
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	_TIME_FORMAT = "2006-01-02 15:04:05"
+)
+
+func printUsage(w io.Writer) {
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	_, _ = fmt.Fprint(w, "Usage: bokchoy <command> [arguments] [flags]\n\nCommands:\n")
+	for _, name := range names {
+		_, _ = fmt.Fprintf(w, "  %-70s %s\n", commands[name].usage, commands[name].help)
+	}
+
+	_, _ = fmt.Fprint(w, `
+Flags:
+  --broker=<dsn>         Broker's DSN (env: BOKCHOY_BROKER).
+  --signing-key=<key>    Key of tasks' signatures, if they are signed (env: BOKCHOY_SIGNING_KEY).
+  --output=text|json     Output format. Default: text.
+  --verbose              Write Bokchoy's logs.
+
+Registered brokers: `)
+
+	if brokers := bokchoy.RegisteredBrokers(); len(brokers) > 0 {
+		_, _ = fmt.Fprintln(w, strings.Join(brokers, ", "))
+	} else {
+		_, _ = fmt.Fprintln(w, "none. Build your own binary, see cli package.")
+	}
+}
+
+// printError writes err's class and messages to w.
+func printError(w io.Writer, err *ekaerr.Error) {
+
+	info := bokchoy.NewTaskErrorInfo(err)
+	if info == nil {
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "Error: %s\n", info.Class)
+	for i := range info.Stack {
+		for _, message := range info.Stack[i].Messages {
+			_, _ = fmt.Fprintf(w, "  %s\n", message)
+		}
+		for _, field := range info.Stack[i].Fields {
+			_, _ = fmt.Fprintf(w, "    %s: %s\n", field.Key, field.Value)
+		}
+	}
+}
+
+func (c *cli) printJSON(v interface{}) *ekaerr.Error {
+
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+
+	if legacyErr := enc.Encode(v); legacyErr != nil {
+		return ekaerr.ExternalError.
+			Wrap(legacyErr, "Bokchoy.CLI: Failed to write JSON output.").
+			Throw()
+	}
+
+	return nil
+}
+
+// printTask writes the task's details in human readable or JSON format.
+func (c *cli) printTask(info admin.TaskInfo) *ekaerr.Error {
+
+	if c.cfg.output == _OUTPUT_JSON {
+		return c.printJSON(info)
+	}
+
+	w := c.stdout
+	_, _ = fmt.Fprintf(w, "ID:           %s\n", info.ID)
+	_, _ = fmt.Fprintf(w, "Queue:        %s\n", info.QueueName)
+	if info.Type != "" {
+		_, _ = fmt.Fprintf(w, "Type:         %s\n", info.Type)
+	}
+	if info.IdempotencyKey != "" {
+		_, _ = fmt.Fprintf(w, "Idempotency:  %s\n", info.IdempotencyKey)
+	}
+	_, _ = fmt.Fprintf(w, "Status:       %s\n", info.Status)
+	if info.Progress != nil {
+		_, _ = fmt.Fprintf(w, "Progress:     %d%% %s\n", info.Progress.Percent, info.Progress.Message)
+	}
+	if info.WorkerID != "" {
+		_, _ = fmt.Fprintf(w, "Worker:       %s\n", info.WorkerID)
+	}
+	_, _ = fmt.Fprintf(w, "Published:    %s\n", info.PublishedAt.Format(_TIME_FORMAT))
+
+	if info.ETA != nil {
+		_, _ = fmt.Fprintf(w, "ETA:          %s\n", info.ETA.Format(_TIME_FORMAT))
+	}
+	if info.StartedAt != nil {
+		_, _ = fmt.Fprintf(w, "Started:      %s\n", info.StartedAt.Format(_TIME_FORMAT))
+	}
+	if info.ProcessedAt != nil {
+		_, _ = fmt.Fprintf(w, "Processed:    %s\n", info.ProcessedAt.Format(_TIME_FORMAT))
+	}
+	if info.ExecTime != "" {
+		_, _ = fmt.Fprintf(w, "Exec time:    %s\n", info.ExecTime)
+	}
+
+	_, _ = fmt.Fprintf(w, "Retries left: %d\n", info.RetriesLeft)
+
+	if len(info.Headers) > 0 {
+		keys := make([]string, 0, len(info.Headers))
+		for k := range info.Headers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		_, _ = fmt.Fprintln(w, "Headers:")
+		for _, k := range keys {
+			_, _ = fmt.Fprintf(w, "  %s: %s\n", k, info.Headers[k])
+		}
+	}
+
+	switch {
+	case len(info.Payload) > 0:
+		_, _ = fmt.Fprintf(w, "Payload:      %s\n", info.Payload)
+	case info.PayloadBase64 != "":
+		_, _ = fmt.Fprintf(w, "Payload:      (base64) %s\n", info.PayloadBase64)
+	}
+
+	if info.Panic != "" {
+		_, _ = fmt.Fprintf(w, "Panic:        %s\n", info.Panic)
+	}
+
+	if info.Error != nil {
+		_, _ = fmt.Fprintf(w, "Error:        %s (%s)\n", info.Error.Class, info.Error.ID)
+		for _, frame := range info.Error.Stack {
+			_, _ = fmt.Fprintf(w, "  %s\n    %s:%d\n", frame.Function, frame.File, frame.Line)
+			for _, message := range frame.Messages {
+				_, _ = fmt.Fprintf(w, "    > %s\n", message)
+			}
+			for _, field := range frame.Fields {
+				_, _ = fmt.Fprintf(w, "    - %s: %s\n", field.Key, field.Value)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+	"github.com/qioalice/bokchoy/admin"
+	"github.com/qioalice/bokchoy/cli"
+	"github.com/qioalice/bokchoy/internal/brokertest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCLI(t *testing.T) {
+
+	broker := brokertest.NewMemoryBroker()
+	bokchoy.RegisterBroker("memory", func(_ string) (bokchoy.Broker, *ekaerr.Error) {
+		return broker, nil
+	})
+
+	run := func(exitCode int, args ...string) string {
+		var stdout, stderr bytes.Buffer
+		args = append(args, "--broker=memory://")
+		require.Equal(t, exitCode, cli.Main(args, &stdout, &stderr), stderr.String())
+		return stdout.String()
+	}
+
+	var task admin.TaskInfo
+	out := run(0, "publish", "tasks.test", `--json={"data":"hello"}`, "--header=tenant_id=42", "--output=json")
+	require.NoError(t, json.Unmarshal([]byte(out), &task))
+	require.Equal(t, "waiting", task.Status)
+	require.Equal(t, "42", task.Headers["tenant_id"])
+
+	out = run(0, "get", "tasks.test", task.ID)
+	require.Contains(t, out, `Payload:      {"data":"hello"}`)
+
+	out = run(0, "stats", "tasks.test")
+	require.Contains(t, out, "Direct:  1")
+
+	out = run(0, "--queues=tasks.test", "queues")
+	require.Contains(t, out, "tasks.test")
+
+	run(0, "cancel", "tasks.test", task.ID)
+
+	out = run(0, "list", "tasks.test", "--status=cancelled")
+	require.Contains(t, out, task.ID)
+	require.Equal(t, 2, strings.Count(out, "\n")) // header + task
+
+	out = run(0, "list", "tasks.test", "--status=failed")
+	require.NotContains(t, out, task.ID)
+
+	out = run(0, "requeue", "tasks.test", task.ID)
+	require.Contains(t, out, "Status:       waiting")
+
+	run(0, "purge", "tasks.test")
+	run(1, "get", "tasks.test", task.ID)
+
+	run(2, "unknown")
+	run(2, "get", "tasks.test")
+	run(1, "list", "tasks.test", "--status=unknown")
+}
@@ -0,0 +1,300 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+	"github.com/qioalice/bokchoy/admin"
+)
+
+var (
+	// commands are all CLI's commands by their names.
+	commands = map[string]command{
+		"queues": {
+			usage: "queues [--queues=q1,q2]",
+			help:  "List queues with their stats.",
+			nArgs: 0,
+			run:   (*cli).queues,
+		},
+		"stats": {
+			usage: "stats <queue>",
+			help:  "Show queue's stats.",
+			nArgs: 1,
+			run:   (*cli).stats,
+		},
+		"list": {
+			usage: "list <queue> [--status=failed] [--offset=0] [--limit=50]",
+			help:  "List queue's tasks, the newest first.",
+			nArgs: 1,
+			run:   (*cli).list,
+		},
+		"get": {
+			usage: "get <queue> <id>",
+			help:  "Show the task.",
+			nArgs: 2,
+			run:   (*cli).get,
+		},
+		"cancel": {
+			usage: "cancel <queue> <id>",
+			help:  "Cancel the task.",
+			nArgs: 2,
+			run:   (*cli).cancel,
+		},
+		"requeue": {
+			usage: "requeue <queue> <id>",
+			help:  "Publish the finished (e.g. failed) task again.",
+			nArgs: 2,
+			run:   (*cli).requeue,
+		},
+		"purge": {
+			usage: "purge <queue>",
+			help:  "Delete all queue's tasks.",
+			nArgs: 1,
+			run:   (*cli).purge,
+		},
+		"publish": {
+			usage: "publish <queue> --json=<payload> [--type=<type>] [--header=key=value] [--countdown=1m]",
+			help:  "Publish a new task with JSON payload.",
+			nArgs: 1,
+			run:   (*cli).publish,
+		},
+	}
+)
+
+func (c *cli) queues(_ []string) *ekaerr.Error {
+	const s = "Bokchoy.CLI: Failed to list queues. "
+
+	var queueNames []string
+	if c.cfg.queues != "" {
+		queueNames = strings.Split(c.cfg.queues, ",")
+
+	} else if lister, ok := c.broker.(bokchoy.BrokerQueuesLister); ok {
+		var err *ekaerr.Error
+		if queueNames, err = lister.Queues(); err.IsNotNil() {
+			return err.AddMessage(s).Throw()
+		}
+
+	} else {
+		return ekaerr.UnsupportedOperation.
+			New(s + "Broker can not list its queues. " +
+				"Use --queues flag or BOKCHOY_QUEUES env to specify them.").
+			WithString("bokchoy_broker", c.broker.String()).
+			Throw()
+	}
+
+	sort.Strings(queueNames)
+	infos := make([]admin.QueueInfo, 0, len(queueNames))
+
+	for _, queueName := range queueNames {
+		if queueName = strings.TrimSpace(queueName); queueName == "" {
+			continue
+		}
+		info, err := admin.NewQueueInfo(c.b.Queue(queueName))
+		if err.IsNotNil() {
+			return err.AddMessage(s).Throw()
+		}
+		infos = append(infos, info)
+	}
+
+	if c.cfg.output == _OUTPUT_JSON {
+		return c.printJSON(infos)
+	}
+
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "QUEUE\tDIRECT\tDELAYED\tTOTAL")
+	for _, info := range infos {
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n",
+			info.Name, info.Tasks.Direct, info.Tasks.Delayed, info.Tasks.Total)
+	}
+	_ = tw.Flush()
+
+	return nil
+}
+
+func (c *cli) stats(args []string) *ekaerr.Error {
+
+	info, err := admin.NewQueueInfo(c.b.Queue(args[0]))
+	if err.IsNotNil() {
+		return err.AddMessage("Bokchoy.CLI: Failed to get queue's stats.").Throw()
+	}
+
+	if c.cfg.output == _OUTPUT_JSON {
+		return c.printJSON(info)
+	}
+
+	_, _ = fmt.Fprintf(c.stdout, "Queue:   %s\nDirect:  %d\nDelayed: %d\nTotal:   %d\n",
+		info.Name, info.Tasks.Direct, info.Tasks.Delayed, info.Tasks.Total)
+
+	return nil
+}
+
+func (c *cli) list(args []string) *ekaerr.Error {
+	const s = "Bokchoy.CLI: Failed to list tasks. "
+
+	status := bokchoy.TASK_STATUS_INVALID
+	if c.cfg.status != "" {
+		var ok bool
+		if status, ok = admin.ParseTaskStatus(c.cfg.status); !ok {
+			return ekaerr.IllegalArgument.
+				New(s + "Unknown task status.").
+				WithString("bokchoy_cli_status", c.cfg.status).
+				Throw()
+		}
+	}
+
+	q := c.b.Queue(args[0])
+
+	tasks, err := q.List()
+	if err.IsNotNil() {
+		return err.AddMessage(s).Throw()
+	}
+
+	// The newest tasks are the first ones. PublishedAt has seconds precision,
+	// so tasks published at the same second are ordered by their ULIDs.
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].PublishedAt != tasks[j].PublishedAt {
+			return tasks[i].PublishedAt > tasks[j].PublishedAt
+		}
+		return tasks[i].ID() > tasks[j].ID()
+	})
+
+	infos := make([]admin.TaskInfo, 0, c.cfg.limit)
+	for i, skipped := 0, 0; i < len(tasks) && len(infos) < c.cfg.limit; i++ {
+		switch {
+		case status != bokchoy.TASK_STATUS_INVALID && tasks[i].Status() != status:
+		case skipped < c.cfg.offset:
+			skipped++
+		default:
+			infos = append(infos, admin.NewTaskInfo(&tasks[i], q.SerializerOfTask(&tasks[i])))
+		}
+	}
+
+	if c.cfg.output == _OUTPUT_JSON {
+		return c.printJSON(infos)
+	}
+
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tSTATUS\tPUBLISHED\tRETRIES LEFT\tERROR")
+	for _, info := range infos {
+		errorClass := "-"
+		if info.Error != nil {
+			errorClass = info.Error.Class
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n",
+			info.ID, info.Status, info.PublishedAt.Format(_TIME_FORMAT), info.RetriesLeft, errorClass)
+	}
+	_ = tw.Flush()
+
+	return nil
+}
+
+func (c *cli) get(args []string) *ekaerr.Error {
+
+	q := c.b.Queue(args[0])
+
+	task, err := q.Get(args[1])
+	if err.IsNil() && task == nil {
+		err = ekaerr.NotFound.New("Task is not found.").
+			WithString("bokchoy_queue_name", args[0]).
+			WithString("bokchoy_task_id", args[1])
+	}
+
+	if err.IsNotNil() {
+		return err.AddMessage("Bokchoy.CLI: Failed to get the task.").Throw()
+	}
+
+	return c.printTask(admin.NewTaskInfo(task, q.SerializerOfTask(task)))
+}
+
+func (c *cli) cancel(args []string) *ekaerr.Error {
+
+	q := c.b.Queue(args[0])
+
+	task, err := q.Cancel(args[1])
+	if err.IsNotNil() {
+		return err.AddMessage("Bokchoy.CLI: Failed to cancel the task.").Throw()
+	}
+
+	return c.printTask(admin.NewTaskInfo(task, q.SerializerOfTask(task)))
+}
+
+func (c *cli) requeue(args []string) *ekaerr.Error {
+
+	q := c.b.Queue(args[0])
+
+	task, err := q.Requeue(args[1])
+	if err.IsNotNil() {
+		return err.AddMessage("Bokchoy.CLI: Failed to requeue the task.").Throw()
+	}
+
+	return c.printTask(admin.NewTaskInfo(task, q.SerializerOfTask(task)))
+}
+
+func (c *cli) purge(args []string) *ekaerr.Error {
+
+	if err := c.b.Queue(args[0]).Empty(); err.IsNotNil() {
+		return err.AddMessage("Bokchoy.CLI: Failed to purge the queue.").Throw()
+	}
+
+	if c.cfg.output == _OUTPUT_JSON {
+		return c.printJSON(map[string]string{"queue": args[0]})
+	}
+
+	_, _ = fmt.Fprintf(c.stdout, "Queue %s has been purged.\n", args[0])
+	return nil
+}
+
+func (c *cli) publish(args []string) *ekaerr.Error {
+	const s = "Bokchoy.CLI: Failed to publish the task. "
+
+	if c.cfg.payload == "" {
+		return ekaerr.IllegalArgument.
+			New(s + "Payload must be presented using --json flag.").
+			Throw()
+	}
+
+	q := c.b.Queue(args[0])
+
+	var payload interface{}
+	if err := q.SerializerOf(c.cfg.taskType).Loads([]byte(c.cfg.payload), &payload); err.IsNotNil() {
+		return err.AddMessage(s + "Payload is not a valid JSON.").Throw()
+	}
+
+	options := []bokchoy.Option{
+		bokchoy.WithCountdown(c.cfg.countdown),
+		bokchoy.WithTaskType(c.cfg.taskType),
+	}
+	for k, v := range c.cfg.headers {
+		options = append(options, bokchoy.WithHeader(k, v))
+	}
+
+	task, err := q.Publish(payload, options...)
+	if err.IsNotNil() {
+		return err.AddMessage(s).Throw()
+	}
+
+	return c.printTask(admin.NewTaskInfo(task, q.SerializerOfTask(task)))
+}
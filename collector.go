@@ -0,0 +1,45 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+type (
+	// Collector is an observer of the Task's lifecycle.
+	// It's the extension point for metrics, statistics, etc.
+	// Use WithCollector() option to register it.
+	//
+	// All methods are called synchronously from the goroutine of either
+	// publisher or consumer, thus they must be fast and must not block.
+	// Task must not be modified and must not be saved outside of the call.
+	Collector interface {
+
+		// TaskPublished is called each time Task has been published to the Broker,
+		// including the case when it's returned back to be retried later
+		// (Task.Status() is TASK_STATUS_RETRYING then).
+		TaskPublished(task *Task)
+
+		// TaskProcessed is called each time the Task's processing attempt is over.
+		// Task.Status() is the result of processing and it may be:
+		// TASK_STATUS_SUCCEEDED, TASK_STATUS_FAILED, TASK_STATUS_RETRYING,
+		// TASK_STATUS_TIMED_OUT or TASK_STATUS_CANCELLED.
+		TaskProcessed(task *Task)
+
+		// TaskCanceled is called when the Task has been cancelled using Queue.Cancel().
+		TaskCanceled(task *Task)
+	}
+)
@@ -37,6 +37,19 @@ const (
 	_DEFAULT_MAX_RETRIES = 3
 	_DEFAULT_TTL         = 180 * time.Second
 
+	_DEFAULT_HEARTBEAT_INTERVAL = 10 * time.Second
+
+	_DEFAULT_CIRCUIT_BREAKER_BROKER_ERRORS  = 32
+	_DEFAULT_CIRCUIT_BREAKER_HANDLER_ERRORS = 0
+	_DEFAULT_CIRCUIT_BREAKER_COOL_DOWN      = 5 * time.Second
+
+	_QUARANTINE_QUEUE_SUFFIX = ".quarantine"
+	_CLAIMS_QUEUE_SUFFIX     = ".claims"
+
+	_DEFAULT_OUTBOX_TABLE = "bokchoy_outbox"
+
+	_IDEMPOTENCY_POSTPONE_INTERVAL = time.Second
+
 	VERSION = "v1.4.3, 13 May 2021, 22:51 GMT+3"
 )
 
@@ -0,0 +1,191 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+)
+
+type (
+	// consumerBatch accumulates tasks, consumed by consumer,
+	// until they are enough to be processed by Queue's batch handler
+	// (see Queue.HandleBatch()).
+	//
+	// It's created by consumer's loop if Queue has batch handler,
+	// and it's never shared between consumers.
+	consumerBatch struct {
+		consumer  *consumer
+		tasks     []Task
+		startedAt time.Time // when the first of tasks has been received
+	}
+)
+
+// add appends consumed tasks to the batch and processes as many batches
+// as it's possible, according with Queue's batch size and wait time.
+func (b *consumerBatch) add(tasks []Task) {
+
+	if len(b.tasks) == 0 {
+		b.startedAt = time.Now()
+	}
+
+	b.tasks = append(b.tasks, tasks...)
+
+	maxSize := b.consumer.queue.batchMaxSize
+	for len(b.tasks) >= maxSize {
+		b.consumer.processBatch(b.tasks[:maxSize])
+		b.tasks = b.tasks[maxSize:]
+	}
+
+	if len(b.tasks) > 0 && time.Since(b.startedAt) >= b.consumer.queue.batchMaxWait {
+		b.flush()
+	}
+}
+
+// flush processes accumulated tasks regardless of their number.
+func (b *consumerBatch) flush() {
+	if len(b.tasks) > 0 {
+		b.consumer.processBatch(b.tasks)
+	}
+	b.tasks = nil
+}
+
+// release returns accumulated tasks back to the pool without processing them.
+// They're neither tracked nor claimed by the current worker after that.
+//
+// It's used when consumer's loop is done by freezing: the batch can't wait
+// in memory until the consumer is unfrozen, because it might never be.
+func (b *consumerBatch) release() {
+	const s = "Bokchoy: Failed to return batched task to the pool. "
+
+	q := b.consumer.queue
+
+	for i := range b.tasks {
+		t := &b.tasks[i]
+
+		q.untrackProcessing(t)
+		q.unclaim(t)
+
+		// Cancellation is already saved and reported by Queue.Cancel().
+		if q.isCancelled(t) {
+			continue
+		}
+
+		if err := q.PublishTask(t); err.IsNotNil() {
+			q.parent.logger.Copy().
+				WithString("bokchoy_queue_name", q.name).
+				WithString("bokchoy_task_id", t.id).
+				Errore(s, err)
+		}
+	}
+
+	b.tasks = nil
+}
+
+// processBatch is the same as processTask() but for many tasks,
+// that are handled by Queue's batch handler at once.
+// Reports results to the Queue's circuit breaker.
+//
+// Task.Timeout is not applied, so it locks itself until all tasks are processed.
+func (c *consumer) processBatch(tasks []Task) {
+
+	var (
+		spans    = make([]Span, len(tasks))
+		started  = make([]bool, len(tasks))
+		fired    = make([]*Task, 0, len(tasks)) // onStart callbacks haven't changed status
+		handled  = make([]*Task, 0, len(tasks)) // the same, but not cancelled
+	)
+
+	if c.queue.autoscaler != nil {
+		defer c.queue.autoscaler.observe(time.Now(), len(tasks))
+	}
+
+	for i := range tasks {
+		if spans[i], started[i] = c.startProcessing(&tasks[i]); !started[i] {
+			continue
+		}
+
+		if c.fireStart(&tasks[i]) {
+			fired = append(fired, &tasks[i])
+			if !tasks[i].isCancellationRequested() {
+				handled = append(handled, &tasks[i])
+			}
+		}
+	}
+
+	if len(handled) > 0 {
+		c.fireSafeBatchCall(handled)
+	}
+
+	for i, n := 0, len(fired); i < n; i++ {
+		c.fireFinish(fired[i])
+	}
+
+	for i := range tasks {
+		var err *ekaerr.Error
+		if started[i] {
+			err = c.finishProcessing(spans[i], &tasks[i])
+		}
+		c.queue.untrackProcessing(&tasks[i])
+		c.queue.breaker.reportHandler(&tasks[i])
+		c.queue.breaker.reportSaving(err)
+	}
+}
+
+// fireSafeBatchCall calls Queue's batch handler for passed tasks protecting
+// that call from the panic inside, the same way as Task.fireSafeCall() does.
+//
+// If batch handler returns an error (or panics), it's saved to Error
+// of each task, that has no its own one. Each task gets its own copy
+// of the error, they will be reported independently.
+func (c *consumer) fireSafeBatchCall(tasks []*Task) {
+
+	var (
+		err       *ekaerr.Error
+		recovered interface{}
+	)
+
+	func() {
+		defer func() {
+			if recovered = recover(); recovered != nil {
+				err = ekaerr.IllegalState.
+					New(fmt.Sprintf("Batch handler panicked: %+v", recovered)).
+					Throw()
+			}
+		}()
+		err = c.queue.batchHandler(tasks)
+	}()
+
+	if err.IsNil() {
+		return
+	}
+
+	env := newTaskEnvelopeError(err)
+
+	for i, n := 0, len(tasks); i < n; i++ {
+		if recovered != nil {
+			tasks[i].Panic = recovered
+			tasks[i].Error = env.toError()
+		} else if tasks[i].Error.IsNil() {
+			tasks[i].Error = env.toError()
+		}
+	}
+}
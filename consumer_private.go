@@ -29,6 +29,8 @@ type (
 	// consumer is a some Queue's Task s executioner.
 	// One Queue will have as many consumers,
 	// as requested by WithConcurrency() option. Default is: _DEFAULT_CONCURRENCY.
+	// The number of consumers may be changed at runtime (Queue.SetConcurrency()),
+	// but master consumer is never retired.
 	//
 	// Main consumer's loop is started by requestStart() method,
 	// stops by requestStop()'s one,
@@ -40,13 +42,9 @@ type (
 	//
 	// Master/Slave division of consumers is exist
 	// to decrease load to the either Broker, or any your service
-	// when an errors occurred one-by-one in a row, counting not only Broker errors,
-	// but Task's callbacks/handlers too.
-	//
-	// Don't worry, it's OK if sometimes your Task s are failed,
-	// or Broker returns an errors (while retrieving/saving tasks).
-	// But it's not, if there's ONLY errors, nothing more.
-	// Each task, each Broker's call, everything is failed.
+	// when an errors occurred one-by-one in a row.
+	// Slaves are frozen while Queue's circuit breaker is not closed,
+	// master is paused for its cool-down and then probes. See circuitBreaker.
 	//
 	// It guarantees, that NOT MORE THAN ONE loop may be run for one consumer.
 	consumer struct {
@@ -73,8 +71,9 @@ type (
 
 //goland:noinspection GoSnakeCaseUsage
 const (
-	// TODO: Add an Option to manage this value instead of constant usage.
-	_CONSUMER_MAX_ERRORS_IN_A_ROW = 32
+	// _CONSUMER_MAX_PAUSE is the max time the paused master consumer
+	// does not check whether it's stopped.
+	_CONSUMER_MAX_PAUSE = 100 * time.Millisecond
 
 	_CONSUMER_STATUS_ACTIVE  int32 = 1
 	_CONSUMER_STATUS_STOPPED int32 = 2
@@ -84,8 +83,17 @@ const (
 // requestStart starts consumer's loop (if it's not started yet)
 // calling consumeLoop() in a new, separated goroutine.
 func (c *consumer) requestStart() {
-	c.queue.wg.Add(1)
 	if atomic.SwapInt32(&c.status, _CONSUMER_STATUS_ACTIVE) != _CONSUMER_STATUS_ACTIVE {
+		c.queue.wg.Add(1)
+		go c.consumeLoop()
+	}
+}
+
+// requestUnfreeze starts frozen consumer's loop again.
+// Does nothing if consumer is not frozen (e.g. it's stopped).
+func (c *consumer) requestUnfreeze() {
+	if atomic.CompareAndSwapInt32(&c.status, _CONSUMER_STATUS_FROZEN, _CONSUMER_STATUS_ACTIVE) {
+		c.queue.wg.Add(1)
 		go c.consumeLoop()
 	}
 }
@@ -98,16 +106,51 @@ func (c *consumer) requestStop() {
 
 // consumeLoop() is consumer's loop.
 // It tries to retrieve next N tasks (depends of Broker.Consume())
-// and process all of them one-by-one using processTask() method.
+// and process all of them one-by-one using processTask() method,
+// or in batches using processBatch() one if Queue has batch handler.
+// Reports results of both of them to the Queue's circuit breaker.
 func (c *consumer) consumeLoop() {
 	defer c.queue.wg.Done()
 
-	status := atomic.LoadInt32(&c.status)
-	for status == _CONSUMER_STATUS_ACTIVE ||
-		status == _CONSUMER_STATUS_FROZEN && c.idx == 0 {
+	breaker := c.queue.breaker
+
+	// Tasks are accumulated to be handled at once, if there is batch handler.
+	// Accumulated tasks must be processed if the loop is done by stopping,
+	// but returned back to the pool if it's done by freezing
+	// (circuit breaker is open, it's not the time to handle them).
+	var batch *consumerBatch
+	if c.queue.batchHandler != nil {
+		batch = &consumerBatch{consumer: c}
+	}
+
+	for atomic.LoadInt32(&c.status) == _CONSUMER_STATUS_ACTIVE {
+
+		if c.idx != 0 && !breaker.isClosed() && c.freeze() {
+			if batch != nil {
+				batch.release()
+			}
+			return
+		}
+
+		if c.idx == 0 {
+			if pause := breaker.probe(); pause > 0 {
+				if pause > _CONSUMER_MAX_PAUSE {
+					pause = _CONSUMER_MAX_PAUSE
+				}
+				time.Sleep(pause)
+				continue
+			}
+		}
 
 		tasks, err := c.queue.Consume()
-		c.countErrorIfAny(err)
+		breaker.reportBroker(err)
+
+		// Consumed tasks may be cancelled by Queue.Cancel()
+		// and recovered if the worker dies (see claim()) since now.
+		for i := range tasks {
+			c.queue.trackProcessing(&tasks[i])
+			c.queue.claim(&tasks[i])
+		}
 
 		if len(tasks) > 0 {
 
@@ -115,19 +158,45 @@ func (c *consumer) consumeLoop() {
 				WithString("bokchoy_queue_name", c.queue.name).
 				WithInt("bokchoy_tasks_received", len(tasks)).
 				WithInt8("bokchoy_queue_consumers_idx", c.idx).
-				WithInt("bokchoy_queue_consumers_number", len(c.queue.consumers)).
+				WithInt("bokchoy_queue_consumers_number", len(c.queue.consumersSnapshot())).
 				Debug("Bokchoy: Received tasks to consume.")
+		}
 
-			for i, n := 0, len(tasks); i < n; i++ {
-				err = c.processTask(&tasks[i])
-				c.countErrorIfAny(err)
-			}
+		if batch != nil {
+			batch.add(tasks)
+			continue
+		}
+
+		for i, n := 0, len(tasks); i < n; i++ {
+			err = c.processTask(&tasks[i])
+			breaker.reportHandler(&tasks[i])
+			breaker.reportSaving(err)
 		}
+	}
 
-		status = atomic.LoadInt32(&c.status)
+	if batch != nil {
+		batch.flush()
 	}
 }
 
+// freeze freezes slave consumer, reporting whether its loop must be done.
+//
+// Circuit breaker might be closed right before freezing,
+// and frozen consumer won't be unfrozen then. So, it's checked once again.
+// CAS is our protector: if consumer is unfrozen by someone else,
+// it's a new loop already, and if it's stopped, this one must be done too.
+func (c *consumer) freeze() bool {
+
+	if !atomic.CompareAndSwapInt32(&c.status,
+		_CONSUMER_STATUS_ACTIVE, _CONSUMER_STATUS_FROZEN) {
+		return true
+	}
+
+	return !c.queue.breaker.isClosed() ||
+		!atomic.CompareAndSwapInt32(&c.status,
+			_CONSUMER_STATUS_FROZEN, _CONSUMER_STATUS_ACTIVE)
+}
+
 // processTask is the Task's processing entry point.
 // After this, Task may be only:
 //  - Returned back to the pool if it must be retried later,
@@ -141,10 +210,16 @@ func (c *consumer) consumeLoop() {
 func (c *consumer) processTask(t *Task) *ekaerr.Error {
 	const s = "Bokchoy: Failed to process task under consuming. "
 
-	c.queue.parent.logger.Copy().
-		WithString("bokchoy_queue_name", c.queue.name).
-		WithString("bokchoy_task_id", t.id).
-		Debug("Bokchoy: Task processing is started.")
+	defer c.queue.untrackProcessing(t)
+
+	span, ok := c.startProcessing(t)
+	if !ok {
+		return nil
+	}
+
+	if c.queue.autoscaler != nil {
+		defer c.queue.autoscaler.observe(time.Now(), 1)
+	}
 
 	if t.Timeout != 0 {
 		var (
@@ -159,6 +234,13 @@ func (c *consumer) processTask(t *Task) *ekaerr.Error {
 		select {
 		case _, _ = <- doneChan: // will be closed in c.fire()
 		case _, _ = <- timeoutTimer.C:
+			// Handlers are still running, they must stop touching the Task.
+			if t.progressSaver != nil {
+				t.progressSaver.stop()
+			}
+			if t.cancellation != nil {
+				t.cancellation.cancel()
+			}
 			t.markAsTimedOut()
 
 			c.queue.parent.logger.Copy().
@@ -173,6 +255,61 @@ func (c *consumer) processTask(t *Task) *ekaerr.Error {
 		c.fire(nil, t)
 	}
 
+	return c.finishProcessing(span, t)
+}
+
+// startProcessing prepares tracked and claimed Task
+// (see Queue.trackProcessing(), Queue.claim()) for calling its handlers and callbacks.
+// Returns the processing span, that must be passed to finishProcessing().
+//
+// Reports false if the Task must be skipped (it's been cancelled while waiting,
+// or it's been succeeded already, see WithIdempotency()). It's unclaimed then.
+func (c *consumer) startProcessing(t *Task) (span Span, ok bool) {
+
+	// Task might be cancelled while it's been waiting.
+	// Cancellation is already saved and reported by Queue.Cancel().
+	if c.queue.isCancelled(t) {
+		c.queue.parent.logger.Copy().
+			WithString("bokchoy_queue_name", c.queue.name).
+			WithString("bokchoy_task_id", t.id).
+			Debug("Bokchoy: Task is cancelled. Skipped.")
+		c.queue.unclaim(t)
+		return nil, false
+	}
+
+	// Task might be re-delivered after it's been succeeded,
+	// or while it's been processed by another consumer.
+	if !c.queue.reserveIdempotencyKey(t) {
+		c.queue.unclaim(t)
+		return nil, false
+	}
+
+	c.queue.parent.logger.Copy().
+		WithString("bokchoy_queue_name", c.queue.name).
+		WithString("bokchoy_task_id", t.id).
+		Debug("Bokchoy: Task processing is started.")
+
+	c.queue.trackProgress(t)
+
+	// Context of the processing span must be set before handlers are called.
+	span = c.queue.traceProcessing(t)
+
+	return span, true
+}
+
+// finishProcessing reports processed Task and either returns it back
+// to the pool if it must be retried later, or saves it.
+func (c *consumer) finishProcessing(span Span, t *Task) *ekaerr.Error {
+	const s = "Bokchoy: Failed to process task under consuming. "
+
+	// Requested cancellation is already reported by Queue.Cancel().
+	if !t.isCancellationRequested() {
+		if c.queue.options.Collector != nil {
+			c.queue.options.Collector.TaskProcessed(t)
+		}
+		c.queue.parent.events.emitTaskProcessed(c.queue.name, t)
+	}
+
 	var err *ekaerr.Error
 
 	if t.status == TASK_STATUS_RETRYING {
@@ -183,6 +320,9 @@ func (c *consumer) processTask(t *Task) *ekaerr.Error {
 		t.ETA = t.nextETA()
 		t.MaxRetries--
 
+		// Task is returned back to the pool, its waiting starts over (see EnqueuedAt()).
+		t.enqueuedAt = time.Now().UnixNano()
+
 		err = c.queue.PublishTask(t).
 			AddMessage(s + "Failed to return failed task to the pool for being retried later.")
 
@@ -191,92 +331,18 @@ func (c *consumer) processTask(t *Task) *ekaerr.Error {
 			AddMessage(s + "Failed to save processed task.")
 	}
 
-	return err.Throw()
-}
-
-// countErrorIfAny implements 95% of starting/stopping/freezing master/slave
-// consumer s when error is occurred.
-//
-// It freezes all slave consumer s if Queue.errCounter is reached N
-// (Default is: _CONSUMER_MAX_ERRORS_IN_A_ROW).
-// It also unfreezes all slave consumer s if master consumer meet non-error call;
-// and counts all errors using Queue.errCounter.
-//
-// Writes logs, handles rare cases like stopping requested while freezing/unfreezing,
-// etc.
-func (c *consumer) countErrorIfAny(err *ekaerr.Error) {
-	const s = "Bokchoy: An error (%d) occurred while trying to consume tasks of queue. "
-
-	if err.IsNotNil() {
-
-		queueConsumeErrorCounter := atomic.LoadInt32(&c.queue.errCounter)
-		if queueConsumeErrorCounter >= _CONSUMER_MAX_ERRORS_IN_A_ROW {
-			// CAS because we don't want to change stopped -> frozen.
-			// It should never happen at all, but who knows the future, right?
-			atomic.CompareAndSwapInt32(&c.status,
-				_CONSUMER_STATUS_ACTIVE, _CONSUMER_STATUS_FROZEN)
-			return
-		}
-
-		queueConsumeErrorCounter = atomic.AddInt32(&c.queue.errCounter, 1)
-		if queueConsumeErrorCounter >= _CONSUMER_MAX_ERRORS_IN_A_ROW {
-			queueConsumeErrorCounter = _CONSUMER_MAX_ERRORS_IN_A_ROW
-
-			const s1 = s + "Error limit is reached. All consumers but one will be freeze until error is get out."
-			c.queue.parent.logger.Errore(s1, err, queueConsumeErrorCounter)
-
-			atomic.StoreInt32(&c.status, _CONSUMER_STATUS_FROZEN)
-			return
-
-		} else {
-			const s1 = s + "Another %d errors and all but one consumers will be temporary stopped."
-			c.queue.parent.logger.Errore(s1, err, queueConsumeErrorCounter, _CONSUMER_MAX_ERRORS_IN_A_ROW - queueConsumeErrorCounter)
-		}
-
+	// Task must be remembered as succeeded only if it's saved as succeeded.
+	// Otherwise, it (or another one with the same key) may be handled again.
+	if err.IsNil() && t.status == TASK_STATUS_SUCCEEDED {
+		c.queue.rememberSucceeded(t)
 	} else {
+		c.queue.releaseIdempotencyKey(t)
+	}
 
-		// Reset Queue's error counter.
-		atomic.StoreInt32(&c.queue.errCounter, 0)
-
-		// Whether it's master consumer and it must unfreeze others?
-		unfreeze :=
-			atomic.LoadInt32(&c.queue.errCounter) == _CONSUMER_STATUS_FROZEN &&
-			c.idx == 0
-
-		// Shutdown of consumers may requested at this moment.
-		// So, CAS is our protector.
-		// If it's not frozen atm, it's possible stopped.
-		// So, don't do something in that case.
-
-		if unfreeze {
-			masterConsumerActivated := atomic.CompareAndSwapInt32(&c.status,
-				_CONSUMER_STATUS_FROZEN, _CONSUMER_STATUS_ACTIVE)
-			unfreeze = unfreeze && masterConsumerActivated
-		}
-
-		// We need to unfreeze others consumers,
-		// if there are more than 1 consumers.
-
-		for i, n := 1, len(c.queue.consumers); unfreeze && i < n; i++ {
+	c.queue.unclaim(t)
 
-			slaveConsumerActivated := atomic.CompareAndSwapInt32(&c.queue.consumers[i].status,
-				_CONSUMER_STATUS_FROZEN, _CONSUMER_STATUS_ACTIVE)
-			if slaveConsumerActivated {
-				c.queue.consumers[i].requestStart()
-			}
-			unfreeze = unfreeze && slaveConsumerActivated
-
-			if !slaveConsumerActivated {
-				// Very rare case.
-				// The stopping has been requested while we unfreezing consumers.
-				// Apply the current consumer's status to the prev ones
-				applicableStatus := atomic.LoadInt32(&c.queue.consumers[i].status)
-				for j := 1; j < i; j++ {
-					atomic.StoreInt32(&c.queue.consumers[i].status, applicableStatus)
-				}
-			}
-		}
-	}
+	c.queue.traceProcessed(span, t, err)
+	return err.Throw()
 }
 
 // fire calls passed Task's handlers and callbacks, changing its status,
@@ -284,17 +350,36 @@ func (c *consumer) countErrorIfAny(err *ekaerr.Error) {
 // Closes passed channel if it's not nil when fire is done.
 func (c *consumer) fire(done chan<- struct{}, task *Task) {
 
-	// Task.queueName is not saved into encoded RAW data of task.
-	// So, Task.QueueName() must work, use consumer's queue name then.
-	task.queueName = c.queue.name
-
 	defer func(done chan<- struct{}) {
 		if done != nil {
 			close(done)
 		}
 	}(done)
 
+	if !c.fireStart(task) {
+		return
+	}
+
+	// Handlers chain consists of all handlers, wrapped by all middlewares.
+	// See Queue.buildChain().
+	if !task.isCancellationRequested() {
+		task.fireSafeCall(c.queue.chain)
+	}
+
+	c.fireFinish(task)
+}
+
+// fireStart marks Task as processing and calls its onStart callbacks.
+// Reports whether Task's handlers must be called then.
+func (c *consumer) fireStart(task *Task) bool {
+
+	// Task.queueName is not saved into encoded RAW data of task.
+	// So, Task.QueueName() must work, use consumer's queue name then.
+	task.queueName = c.queue.name
+	task.logger = c.queue.parent.logger
+
 	task.markAsProcessing()
+	c.queue.parent.events.emit(EVENT_TYPE_TASK_STARTED, c.queue.name, task)
 
 	// First of all call onStart callbacks.
 	c.fireEvents(task)
@@ -303,19 +388,19 @@ func (c *consumer) fire(done chan<- struct{}, task *Task) {
 	// may be changed up to _TASK_MAX_STATUS_CHANGED_CALLBACKS_FIRING times.
 	//
 	// But if it's still TASK_STATUS_PROCESSING, we need to call handlers.
-	if task.status != TASK_STATUS_PROCESSING {
-		return
-	}
+	return task.status == TASK_STATUS_PROCESSING
+}
 
-	oldStatus := task.status
-	for i, n := 0, len(c.queue.handlers); i < n; i++ {
-		task.fireSafeCall(c.queue.handlers[i])
-		if !task.fireMayContinue() || oldStatus != task.status {
-			break
-		}
-	}
+// fireFinish changes Task's status according with the result of its handlers
+// and calls the corresponding callbacks.
+func (c *consumer) fireFinish(task *Task) {
 
-	if task.status == TASK_STATUS_PROCESSING {
+	// The cancellation (see Queue.Cancel()) overrides any result of handlers,
+	// so the cancelled Task is neither retried nor considered succeeded.
+	switch {
+	case task.isCancellationRequested():
+		task.MarkAsCanceled()
+	case task.fireMayContinue() && task.status == TASK_STATUS_PROCESSING:
 		task.MarkAsSucceeded()
 	}
 
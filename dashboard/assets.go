@@ -0,0 +1,325 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package dashboard
+
+// Static assets of the dashboard. They are kept as Go constants
+// to be embedded into the binary without any generation step.
+//
+// NOTE: JavaScript code must not use template literals (backticks).
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	_ASSET_INDEX_HTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<title>Bokchoy</title>
+	<link rel="stylesheet" href="app.css">
+</head>
+<body>
+	<header>
+		<h1>Bokchoy</h1>
+		<span id="status"></span>
+	</header>
+	<main>
+		<section>
+			<h2>Queues</h2>
+			<table id="queues">
+				<thead>
+					<tr>
+						<th>Queue</th>
+						<th>Direct</th>
+						<th>Delayed</th>
+						<th>Consumers</th>
+						<th>Active</th>
+						<th>Frozen</th>
+						<th>Published/s</th>
+						<th>Succeeded/s</th>
+						<th>Failed/s</th>
+						<th>Last 60s</th>
+					</tr>
+				</thead>
+				<tbody></tbody>
+			</table>
+		</section>
+		<section>
+			<h2>Tasks <select id="tasks-queue"></select> <select id="tasks-status">
+				<option value="">all</option>
+				<option>waiting</option>
+				<option>processing</option>
+				<option>retrying</option>
+				<option>succeeded</option>
+				<option>failed</option>
+				<option>cancelled</option>
+				<option>timed_out</option>
+			</select></h2>
+			<table id="tasks">
+				<thead>
+					<tr>
+						<th>ID</th>
+						<th>Status</th>
+						<th>Published</th>
+						<th>ETA</th>
+						<th>Retries left</th>
+						<th></th>
+					</tr>
+				</thead>
+				<tbody></tbody>
+			</table>
+		</section>
+		<section>
+			<h2>Recent failures</h2>
+			<div id="failures"></div>
+		</section>
+	</main>
+	<script src="app.js"></script>
+</body>
+</html>
+`
+
+	_ASSET_APP_CSS = `body {
+	margin: 0;
+	font: 14px/1.4 -apple-system, "Segoe UI", Roboto, sans-serif;
+	color: #222;
+	background: #f6f7f9;
+}
+header {
+	display: flex;
+	align-items: baseline;
+	gap: 16px;
+	padding: 8px 24px;
+	color: #fff;
+	background: #2e7d32;
+}
+header h1 { margin: 0; font-size: 20px; }
+main { padding: 0 24px 24px; }
+h2 { font-size: 16px; margin: 24px 0 8px; }
+table { width: 100%; border-collapse: collapse; background: #fff; }
+th, td { padding: 6px 8px; text-align: left; border-bottom: 1px solid #e3e5e8; }
+th { font-weight: 600; background: #eef0f3; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+.frozen { color: #c62828; font-weight: 600; }
+.status-failed, .status-timed_out { color: #c62828; }
+.status-succeeded { color: #2e7d32; }
+.status-processing, .status-retrying { color: #ef6c00; }
+.status-cancelled { color: #757575; }
+button { margin-right: 4px; cursor: pointer; }
+svg.spark { display: block; }
+svg.spark .succeeded { stroke: #2e7d32; fill: none; }
+svg.spark .failed { stroke: #c62828; fill: none; }
+.failure { margin-bottom: 8px; padding: 8px; background: #fff; border-left: 4px solid #c62828; }
+.failure summary { cursor: pointer; }
+.failure pre { margin: 8px 0 0; overflow-x: auto; font-size: 12px; }
+.muted { color: #757575; }
+`
+
+	_ASSET_APP_JS = `(function () {
+	"use strict";
+
+	var REFRESH_INTERVAL = 2000;
+	var selectedQueue = "";
+
+	function $(selector) { return document.querySelector(selector); }
+
+	function escape(s) {
+		return String(s === undefined || s === null ? "" : s)
+			.replace(/&/g, "&amp;").replace(/</g, "&lt;").replace(/>/g, "&gt;")
+			.replace(/"/g, "&quot;");
+	}
+
+	function api(method, path) {
+		return fetch("api" + path, { method: method }).then(function (resp) {
+			if (resp.status === 204) { return null; }
+			return resp.json().then(function (body) {
+				if (!resp.ok) {
+					var messages = (body.error && body.error.messages) || [];
+					throw new Error(messages.join(" ") || resp.statusText);
+				}
+				return body;
+			});
+		});
+	}
+
+	function lastSecond(counters) {
+		// The current second is not finished yet, so the previous one is shown.
+		return counters ? counters[counters.length - 2] : 0;
+	}
+
+	function sparkline(t) {
+		if (!t) { return ""; }
+		var max = 1, i;
+		for (i = 0; i < t.succeeded.length; i++) {
+			max = Math.max(max, t.succeeded[i], t.failed[i]);
+		}
+		function points(counters) {
+			return counters.map(function (v, i) {
+				return (i * 2) + "," + (20 - v / max * 20).toFixed(1);
+			}).join(" ");
+		}
+		return "<svg class=\"spark\" width=\"120\" height=\"22\">" +
+			"<polyline class=\"succeeded\" points=\"" + points(t.succeeded) + "\"/>" +
+			"<polyline class=\"failed\" points=\"" + points(t.failed) + "\"/></svg>";
+	}
+
+	function renderQueues(queues, throughput) {
+		var rows = queues.map(function (q) {
+			var t = throughput[q.name];
+			var tasks = q.tasks || {};
+			return "<tr>" +
+				"<td>" + escape(q.name) + "</td>" +
+				"<td class=\"num\">" + escape(tasks.direct) + "</td>" +
+				"<td class=\"num\">" + escape(tasks.delayed) + "</td>" +
+				"<td class=\"num\">" + q.consumers.total + "</td>" +
+				"<td class=\"num\">" + q.consumers.active + "</td>" +
+				"<td class=\"num" + (q.consumers.frozen ? " frozen" : "") + "\">" +
+					q.consumers.frozen + "</td>" +
+				"<td class=\"num\">" + lastSecond(t && t.published) + "</td>" +
+				"<td class=\"num\">" + lastSecond(t && t.succeeded) + "</td>" +
+				"<td class=\"num\">" + lastSecond(t && t.failed) + "</td>" +
+				"<td>" + sparkline(t) + "</td>" +
+				"</tr>";
+		});
+		$("#queues tbody").innerHTML = rows.join("");
+
+		var select = $("#tasks-queue");
+		var options = queues.map(function (q) {
+			return "<option" + (q.name === selectedQueue ? " selected" : "") + ">" +
+				escape(q.name) + "</option>";
+		}).join("");
+		if (select.innerHTML !== options) { select.innerHTML = options; }
+		if (!selectedQueue && queues.length) { selectedQueue = queues[0].name; }
+	}
+
+	function actions(task) {
+		var buttons = "";
+		if (task.status === "failed" || task.status === "timed_out" ||
+			task.status === "cancelled" || task.status === "succeeded") {
+			buttons += "<button data-action=\"retry\" data-queue=\"" + escape(task.queue) +
+				"\" data-id=\"" + escape(task.id) + "\">Retry</button>";
+		}
+		if (task.status === "waiting" || task.status === "retrying") {
+			buttons += "<button data-action=\"cancel\" data-queue=\"" + escape(task.queue) +
+				"\" data-id=\"" + escape(task.id) + "\">Cancel</button>";
+		}
+		return buttons;
+	}
+
+	function progress(task) {
+		if (!task.progress) { return ""; }
+		return " " + task.progress.percent + "%" +
+			(task.progress.message ? " <small>" + escape(task.progress.message) + "</small>" : "");
+	}
+
+	function renderTasks(resp) {
+		var rows = resp.tasks.map(function (task) {
+			return "<tr>" +
+				"<td>" + escape(task.id) + "</td>" +
+				"<td class=\"status-" + escape(task.status) + "\">" + escape(task.status) +
+					progress(task) + "</td>" +
+				"<td>" + escape(task.published_at) + "</td>" +
+				"<td>" + escape(task.eta) + "</td>" +
+				"<td class=\"num\">" + task.retries_left + "</td>" +
+				"<td>" + actions(task) + "</td>" +
+				"</tr>";
+		});
+		if (resp.total > resp.tasks.length) {
+			rows.push("<tr><td colspan=\"6\" class=\"muted\">" +
+				(resp.total - resp.tasks.length) + " more</td></tr>");
+		}
+		$("#tasks tbody").innerHTML = rows.join("");
+	}
+
+	function renderStack(error) {
+		if (!error) { return ""; }
+		var lines = [escape(error.class) + " (" + escape(error.id) + ")"];
+		(error.stack || []).forEach(function (frame) {
+			lines.push("  " + escape(frame.function) + "  " +
+				escape(frame.file) + ":" + frame.line);
+			(frame.messages || []).forEach(function (message) {
+				lines.push("      " + escape(message));
+			});
+			(frame.fields || []).forEach(function (field) {
+				lines.push("      " + escape(field.key) + " = " + escape(field.value));
+			});
+		});
+		return "<pre>" + lines.join("\n") + "</pre>";
+	}
+
+	function renderFailures(failures) {
+		var items = failures.map(function (f) {
+			var task = f.task;
+			return "<details class=\"failure\"><summary>" +
+				escape(f.time) + " &mdash; <b>" + escape(task.queue) + "</b> " +
+				escape(task.id) + " <span class=\"status-" + escape(task.status) + "\">" +
+				escape(task.status) + "</span> " + actions(task) + "</summary>" +
+				renderStack(task.error) +
+				(task.panic ? "<pre>" + escape(task.panic) + "</pre>" : "") +
+				"</details>";
+		});
+		var container = $("#failures");
+		var html = items.length ? items.join("") : "<p class=\"muted\">No failures yet.</p>";
+		// Do not collapse opened stacktraces if nothing has changed.
+		if (container.getAttribute("data-html") !== html) {
+			container.setAttribute("data-html", html);
+			container.innerHTML = html;
+		}
+	}
+
+	function refresh() {
+		Promise.all([
+			api("GET", "/queues"),
+			fetch("stats").then(function (resp) { return resp.json(); })
+		]).then(function (results) {
+			renderQueues(results[0], results[1].throughput);
+			renderFailures(results[1].failures);
+			if (!selectedQueue) { return null; }
+			var status = $("#tasks-status").value;
+			return api("GET", "/queues/" + encodeURIComponent(selectedQueue) +
+				"/tasks?limit=50" + (status ? "&status=" + status : "")).then(renderTasks);
+		}).then(function () {
+			$("#status").textContent = "updated " + new Date().toLocaleTimeString();
+		}).catch(function (err) {
+			$("#status").textContent = "error: " + err.message;
+		});
+	}
+
+	document.addEventListener("click", function (event) {
+		var button = event.target;
+		var action = button.getAttribute && button.getAttribute("data-action");
+		if (!action) { return; }
+		event.preventDefault();
+		var path = "/queues/" + encodeURIComponent(button.getAttribute("data-queue")) +
+			"/tasks/" + encodeURIComponent(button.getAttribute("data-id")) + "/" + action;
+		api("POST", path).then(refresh).catch(function (err) {
+			alert("Failed to " + action + " task: " + err.message);
+		});
+	});
+
+	$("#tasks-queue").addEventListener("change", function (event) {
+		selectedQueue = event.target.value;
+		refresh();
+	});
+	$("#tasks-status").addEventListener("change", refresh);
+
+	refresh();
+	setInterval(refresh, REFRESH_INTERVAL);
+})();
+`
+)
@@ -0,0 +1,37 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+// Package dashboard provides a web UI to monitor and manage Bokchoy's queues,
+// similar to Celery Flower or Sidekiq Web.
+//
+// It shows live per-queue throughput, consumers' states, waiting tasks,
+// recent failures with their errors' stacktraces,
+// and allows to retry or cancel tasks.
+//
+//     d := dashboard.New(b)
+//     defer d.Close()
+//
+//     http.Handle("/bokchoy/", http.StripPrefix("/bokchoy", d))
+//
+// Static assets are embedded into the binary. The data is provided by
+// admin.Handler (mounted at "/api/") and by the lifecycle events of Bokchoy
+// (see bokchoy.Bokchoy.Events()), that are collected since New() is called.
+//
+// WARNING!
+// Dashboard has no authentication. Protect it by your own middleware.
+package dashboard
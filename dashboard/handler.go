@@ -0,0 +1,98 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package dashboard
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qioalice/bokchoy"
+	"github.com/qioalice/bokchoy/admin"
+)
+
+type (
+	// Dashboard is an http.Handler of web UI. Use New() to create it.
+	// See package's doc for more details.
+	Dashboard struct {
+		api         http.Handler
+		unsubscribe func()
+
+		mu          sync.Mutex
+		throughput  map[string]*throughput // by queue name
+		failures    []failure              // ring buffer, see addFailure()
+		failuresIdx int
+	}
+)
+
+// Make sure Dashboard implements http.Handler.
+var _ http.Handler = (*Dashboard)(nil)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	// _THROUGHPUT_WINDOW is the number of seconds throughput is kept for.
+	_THROUGHPUT_WINDOW = 60
+
+	// _MAX_FAILURES is the number of recent failures that are kept.
+	_MAX_FAILURES = 50
+)
+
+// New returns a new Dashboard for the given Bokchoy,
+// subscribing to its events. Use Close() to unsubscribe.
+func New(b *bokchoy.Bokchoy) *Dashboard {
+
+	d := &Dashboard{
+		api:        http.StripPrefix("/api", admin.NewHandler(b)),
+		throughput: make(map[string]*throughput),
+	}
+
+	d.unsubscribe = b.Events().Subscribe(d.onEvent)
+	return d
+}
+
+// Close unsubscribes Dashboard from Bokchoy's events.
+// Dashboard still may be served, but its throughput and failures won't be updated.
+func (d *Dashboard) Close() {
+	d.unsubscribe()
+}
+
+// ServeHTTP implements http.Handler.
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	switch path := r.URL.Path; {
+	case strings.HasPrefix(path, "/api/"):
+		d.api.ServeHTTP(w, r)
+
+	case path == "/stats":
+		d.serveStats(w, time.Now())
+
+	case path == "/" || path == "" || path == "/index.html":
+		serveAsset(w, "text/html; charset=utf-8", _ASSET_INDEX_HTML)
+
+	case path == "/app.js":
+		serveAsset(w, "application/javascript; charset=utf-8", _ASSET_APP_JS)
+
+	case path == "/app.css":
+		serveAsset(w, "text/css; charset=utf-8", _ASSET_APP_CSS)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,177 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/qioalice/bokchoy"
+	"github.com/qioalice/bokchoy/admin"
+)
+
+type (
+	// throughput is the per-second counters of one queue
+	// for the last _THROUGHPUT_WINDOW seconds (ring buffer by unix second).
+	throughput struct {
+		seconds [_THROUGHPUT_WINDOW]throughputSecond
+	}
+
+	throughputSecond struct {
+		unix      int64
+		published int
+		succeeded int
+		failed    int
+	}
+
+	// failure is a recently failed (or timed out) task.
+	failure struct {
+		Time time.Time      `json:"time"`
+		Task admin.TaskInfo `json:"task"`
+	}
+
+	// statsResponse is a response of "/stats" endpoint.
+	// Throughput's arrays are per-second counters, the oldest first.
+	statsResponse struct {
+		Throughput map[string]throughputResponse `json:"throughput"`
+		Failures   []failure                     `json:"failures"`
+	}
+
+	throughputResponse struct {
+		Published []int `json:"published"`
+		Succeeded []int `json:"succeeded"`
+		Failed    []int `json:"failed"`
+	}
+)
+
+// onEvent is Bokchoy's events listener.
+//
+// WARNING!
+// It's called from consumers' goroutines. It must not lock Bokchoy
+// (e.g. by Bokchoy.Queues() call), because Bokchoy.Stop() waits for consumers
+// under the lock. Deadlock otherwise.
+func (d *Dashboard) onEvent(event bokchoy.Event) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var counter func(s *throughputSecond)
+	switch event.Type {
+
+	case bokchoy.EVENT_TYPE_TASK_PUBLISHED:
+		counter = func(s *throughputSecond) { s.published++ }
+
+	case bokchoy.EVENT_TYPE_TASK_SUCCEEDED:
+		counter = func(s *throughputSecond) { s.succeeded++ }
+
+	case bokchoy.EVENT_TYPE_TASK_FAILED, bokchoy.EVENT_TYPE_TASK_TIMED_OUT:
+		counter = func(s *throughputSecond) { s.failed++ }
+		d.addFailure(failure{
+			Time: event.Time,
+			// Payload is not rendered here, it may be requested using API.
+			Task: admin.NewTaskInfo(event.Task, nil),
+		})
+
+	default:
+		return
+	}
+
+	t := d.throughput[event.QueueName]
+	if t == nil {
+		t = new(throughput)
+		d.throughput[event.QueueName] = t
+	}
+
+	counter(t.second(event.Time.Unix()))
+}
+
+// addFailure adds a new failure, overwriting the oldest one
+// if there are _MAX_FAILURES already.
+// Caller must take responsibility about locking to provide thread-safety.
+func (d *Dashboard) addFailure(f failure) {
+	if len(d.failures) < _MAX_FAILURES {
+		d.failures = append(d.failures, f)
+		return
+	}
+	d.failures[d.failuresIdx] = f
+	d.failuresIdx = (d.failuresIdx + 1) % _MAX_FAILURES
+}
+
+// serveStats writes throughput of all queues and recent failures (the newest first).
+func (d *Dashboard) serveStats(w http.ResponseWriter, now time.Time) {
+
+	d.mu.Lock()
+
+	resp := statsResponse{
+		Throughput: make(map[string]throughputResponse, len(d.throughput)),
+		Failures:   append(d.failures[:0:0], d.failures...),
+	}
+
+	for queueName, t := range d.throughput {
+		resp.Throughput[queueName] = t.window(now.Unix())
+	}
+
+	d.mu.Unlock()
+
+	sort.Slice(resp.Failures, func(i, j int) bool {
+		return resp.Failures[i].Time.After(resp.Failures[j].Time)
+	})
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// second returns the counters of the given unix second,
+// resetting them if they are outdated.
+func (t *throughput) second(unix int64) *throughputSecond {
+	s := &t.seconds[unix % _THROUGHPUT_WINDOW]
+	if s.unix != unix {
+		*s = throughputSecond{unix: unix}
+	}
+	return s
+}
+
+// window returns counters of the last _THROUGHPUT_WINDOW seconds
+// before now (inclusive), the oldest first.
+func (t *throughput) window(now int64) throughputResponse {
+
+	resp := throughputResponse{
+		Published: make([]int, _THROUGHPUT_WINDOW),
+		Succeeded: make([]int, _THROUGHPUT_WINDOW),
+		Failed:    make([]int, _THROUGHPUT_WINDOW),
+	}
+
+	for i := 0; i < _THROUGHPUT_WINDOW; i++ {
+		unix := now - _THROUGHPUT_WINDOW + 1 + int64(i)
+		if s := &t.seconds[unix % _THROUGHPUT_WINDOW]; s.unix == unix {
+			resp.Published[i] = s.published
+			resp.Succeeded[i] = s.succeeded
+			resp.Failed[i] = s.failed
+		}
+	}
+
+	return resp
+}
+
+func serveAsset(w http.ResponseWriter, contentType, content string) {
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write([]byte(content))
+}
@@ -0,0 +1,118 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package dashboard_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+	"github.com/qioalice/bokchoy/dashboard"
+	"github.com/qioalice/bokchoy/internal/brokertest"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testPayload struct {
+	Data string `json:"data"`
+}
+
+type testStats struct {
+	Throughput map[string]struct {
+		Published []int `json:"published"`
+		Failed    []int `json:"failed"`
+	} `json:"throughput"`
+	Failures []struct {
+		Task struct {
+			ID    string                 `json:"id"`
+			Error *bokchoy.TaskErrorInfo `json:"error"`
+		} `json:"task"`
+	} `json:"failures"`
+}
+
+func get(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	return rec
+}
+
+func sum(counters []int) (n int) {
+	for _, v := range counters {
+		n += v
+	}
+	return n
+}
+
+func TestDashboard(t *testing.T) {
+
+	b, err := bokchoy.New(
+		bokchoy.WithBroker(brokertest.NewMemoryBroker()),
+		bokchoy.WithCustomSerializerJSON(testPayload{}),
+		bokchoy.WithDisableOutput(true),
+		bokchoy.WithMaxRetries(0),
+		bokchoy.WithQueues("tasks.test"),
+	)
+	require.True(t, err.IsNil())
+
+	b.Queue("tasks.test").Use(func(_ *bokchoy.Task) *ekaerr.Error {
+		return ekaerr.IllegalState.New("Something went wrong.").Throw()
+	})
+
+	d := dashboard.New(b)
+	defer d.Close()
+
+	go func() { _ = b.Run() }()
+	defer b.Stop()
+
+	task, err := b.Publish("tasks.test", testPayload{Data: "hello world"})
+	require.True(t, err.IsNil())
+
+	var stats testStats
+	for deadline := time.Now().Add(5 * time.Second); len(stats.Failures) == 0; {
+		require.True(t, time.Now().Before(deadline), "Task has not been failed in time.")
+		time.Sleep(10 * time.Millisecond)
+
+		rec := get(t, d, "/stats")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	}
+
+	require.Equal(t, task.ID(), stats.Failures[0].Task.ID)
+	require.NotNil(t, stats.Failures[0].Task.Error)
+	require.Equal(t, 1, sum(stats.Throughput["tasks.test"].Published))
+	require.Equal(t, 1, sum(stats.Throughput["tasks.test"].Failed))
+
+	rec := get(t, d, "/")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "app.js")
+
+	rec = get(t, d, "/app.js")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Header().Get("Content-Type"), "javascript")
+
+	rec = get(t, d, "/api/queues/tasks.test")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	require.Equal(t, http.StatusNotFound, get(t, d, "/unknown").Code)
+}
@@ -0,0 +1,29 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+// Package bokchoy is a simple Go library for queueing tasks
+// and processing them in the background with workers.
+//
+// Upgrading
+//
+// Tasks are stored by the Broker in a versioned format. Tasks, that are encoded
+// by newer version of Bokchoy, may be rejected by older consumers
+// as encoded by unsupported version. Thus, when a running deployment is upgraded,
+// upgrade consumers (workers) first, and only then producers.
+// Newer consumers decode tasks, that are encoded by older producers, as is.
+package bokchoy
@@ -0,0 +1,160 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekalog"
+)
+
+type (
+	// EventType is a type of Event.
+	EventType int8
+
+	// Event is a lifecycle event of Task or Queue's consumers,
+	// delivered to the EventStream's listeners.
+	Event struct {
+		Type      EventType
+		Time      time.Time
+		QueueName string
+
+		// Task is a snapshot of the Task at the moment event has been occurred.
+		// Nil for circuit breaker's events.
+		//
+		// WARNING!
+		// Task.Payload is shared with the original Task. Do not modify it.
+		Task      *Task
+	}
+
+	// EventListener is a callback, that is called for each Event.
+	// See EventStream.Subscribe() for more details.
+	EventListener func(event Event)
+
+	// EventStream delivers Event s to its listeners.
+	// Use Bokchoy.Events() to get it.
+	//
+	// Unlike Queue's callbacks (OnStart(), OnSuccess(), etc),
+	// listeners may be subscribed at any time, even after Run(),
+	// and even if Bokchoy is used only to publish tasks (w/o Run() at all).
+	// Of course, in that case only EVENT_TYPE_TASK_PUBLISHED
+	// (and EVENT_TYPE_TASK_CANCELLED) events are occurred.
+	EventStream struct {
+		mu        sync.RWMutex
+		listeners map[uint64]EventListener
+		lastID    uint64
+		logger    *ekalog.Logger
+	}
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	EVENT_TYPE_INVALID                     EventType = 0
+	EVENT_TYPE_TASK_PUBLISHED              EventType = 1
+	EVENT_TYPE_TASK_STARTED                EventType = 2
+	EVENT_TYPE_TASK_RETRIED                EventType = 3
+	EVENT_TYPE_TASK_SUCCEEDED              EventType = 4
+	EVENT_TYPE_TASK_FAILED                 EventType = 5
+	EVENT_TYPE_TASK_TIMED_OUT              EventType = 6
+	EVENT_TYPE_TASK_CANCELLED              EventType = 7
+	EVENT_TYPE_CIRCUIT_BREAKER_OPENED      EventType = 8
+	EVENT_TYPE_CIRCUIT_BREAKER_CLOSED      EventType = 9
+	EVENT_TYPE_CIRCUIT_BREAKER_HALF_OPENED EventType = 10
+	EVENT_TYPE_TASK_PROGRESS               EventType = 11
+)
+
+func (et EventType) String() string {
+	switch et {
+	case EVENT_TYPE_INVALID:                     return "Invalid"
+	case EVENT_TYPE_TASK_PUBLISHED:              return "TaskPublished"
+	case EVENT_TYPE_TASK_STARTED:                return "TaskStarted"
+	case EVENT_TYPE_TASK_RETRIED:                return "TaskRetried"
+	case EVENT_TYPE_TASK_SUCCEEDED:              return "TaskSucceeded"
+	case EVENT_TYPE_TASK_FAILED:                 return "TaskFailed"
+	case EVENT_TYPE_TASK_TIMED_OUT:              return "TaskTimedOut"
+	case EVENT_TYPE_TASK_CANCELLED:              return "TaskCancelled"
+	case EVENT_TYPE_CIRCUIT_BREAKER_OPENED:      return "CircuitBreakerOpened"
+	case EVENT_TYPE_CIRCUIT_BREAKER_CLOSED:      return "CircuitBreakerClosed"
+	case EVENT_TYPE_CIRCUIT_BREAKER_HALF_OPENED: return "CircuitBreakerHalfOpened"
+	case EVENT_TYPE_TASK_PROGRESS:               return "TaskProgress"
+	default:                                     return "Incorrect"
+	}
+}
+
+// Subscribe registers a new listener, that will be called for each Event,
+// and returns a func, that unsubscribes it. Does nothing if listener is nil.
+//
+// Listeners are called synchronously from the goroutines of publishers
+// and consumers, thus they must be fast and must not block.
+// A panic inside listener is recovered and logged.
+//
+// WARNING!
+// Do not subscribe or unsubscribe from the listener itself. Deadlock otherwise.
+func (es *EventStream) Subscribe(listener EventListener) (unsubscribe func()) {
+
+	if es == nil || listener == nil {
+		return func() {}
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	es.lastID++
+	id := es.lastID
+	es.listeners[id] = listener
+
+	return func() {
+		es.mu.Lock()
+		defer es.mu.Unlock()
+
+		delete(es.listeners, id)
+	}
+}
+
+// Channel subscribes a new listener, that sends Event s to the returned channel
+// with the given buffer size, and returns a func, that unsubscribes it
+// and closes the channel.
+//
+// Events are dropped if the channel's buffer is full
+// (listeners must not block, see Subscribe()).
+func (es *EventStream) Channel(size int) (<-chan Event, func()) {
+
+	if size < 0 {
+		size = 0
+	}
+
+	ch := make(chan Event, size)
+	unsubscribe := es.Subscribe(func(event Event) {
+		select {
+		case ch <- event:
+		default:
+		}
+	})
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			// Listener is not under execution after unsubscribe() is returned,
+			// because it's called under the read lock.
+			unsubscribe()
+			close(ch)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+	"github.com/qioalice/ekago/v3/ekalog"
+)
+
+func newEventStream(logger *ekalog.Logger) *EventStream {
+	return &EventStream{
+		listeners: make(map[uint64]EventListener),
+		logger:    logger,
+	}
+}
+
+// emit delivers a new Event of the given type to all listeners.
+// Task's snapshot is made only if there is at least one listener.
+// task may be nil (consumers' events).
+func (es *EventStream) emit(eventType EventType, queueName string, task *Task) {
+
+	es.mu.RLock()
+	defer es.mu.RUnlock()
+
+	if len(es.listeners) == 0 {
+		return
+	}
+
+	event := Event{
+		Type:      eventType,
+		Time:      time.Now(),
+		QueueName: queueName,
+	}
+
+	if task != nil {
+		event.Task = task.snapshot()
+	}
+
+	for _, listener := range es.listeners {
+		es.callSafe(listener, event)
+	}
+}
+
+// callSafe calls listener(event) protecting that call from the panic inside.
+func (es *EventStream) callSafe(listener EventListener, event Event) {
+	const s = "Bokchoy: Event listener panicked. "
+
+	defer func() {
+		if panicObj := recover(); panicObj != nil {
+			err := ekaerr.IllegalState.
+				New(fmt.Sprintf("Listener panicked: %+v", panicObj)).
+				WithStringer("bokchoy_event_type", event.Type)
+			es.logger.Copy().
+				WithString("bokchoy_queue_name", event.QueueName).
+				Errore(s, err)
+		}
+	}()
+
+	listener(event)
+}
+
+// emitTaskProcessed emits an Event, that corresponds to the Task's status
+// after its processing attempt is over.
+func (es *EventStream) emitTaskProcessed(queueName string, task *Task) {
+
+	var eventType EventType
+	switch task.status {
+	case TASK_STATUS_RETRYING:  eventType = EVENT_TYPE_TASK_RETRIED
+	case TASK_STATUS_SUCCEEDED: eventType = EVENT_TYPE_TASK_SUCCEEDED
+	case TASK_STATUS_FAILED:    eventType = EVENT_TYPE_TASK_FAILED
+	case TASK_STATUS_TIMED_OUT: eventType = EVENT_TYPE_TASK_TIMED_OUT
+	case TASK_STATUS_CANCELLED: eventType = EVENT_TYPE_TASK_CANCELLED
+	default:                    return
+	}
+
+	es.emit(eventType, queueName, task)
+}
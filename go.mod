@@ -1,16 +1,25 @@
 module github.com/qioalice/bokchoy
 
-go 1.13
+go 1.18
 
 require (
 	github.com/davecgh/go-spew v1.1.1
-	github.com/json-iterator/go v1.1.9
-	github.com/kr/pretty v0.1.0 // indirect
-	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
-	github.com/modern-go/reflect2 v1.0.1
-	github.com/philhofer/fwd v1.0.0 // indirect
+	github.com/json-iterator/go v1.1.12
+	github.com/modern-go/reflect2 v1.0.2
 	github.com/qioalice/ekago/v3 v3.0.4
 	github.com/stretchr/testify v1.6.1
 	github.com/tinylib/msgp v1.1.2
+)
+
+require (
+	github.com/ef-ds/deque v1.0.4 // indirect
+	github.com/ef-ds/stack v1.0.1 // indirect
+	github.com/kr/pretty v0.1.0 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
+	github.com/oklog/ulid/v2 v2.0.2 // indirect
+	github.com/philhofer/fwd v1.0.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/theodesp/go-heaps v0.0.0-20190520121037-88e35354fe0a // indirect
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
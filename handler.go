@@ -22,7 +22,25 @@ import (
 	"github.com/qioalice/ekago/v3/ekaerr"
 )
 
-// HandlerFunc is a handler to handle incoming tasks.
 type (
+	// HandlerFunc is a handler to handle incoming tasks.
 	HandlerFunc func(task *Task) *ekaerr.Error
+
+	// Middleware is an around-style handler, that wraps the next HandlerFunc
+	// (the rest of the chain) and returns a new one.
+	// Thus it can run some code both before and after the next handlers,
+	// e.g. for timing, transactions or tracing spans.
+	//
+	// The innermost HandlerFunc of the chain calls all the handlers,
+	// registered using Queue.Use(), one-by-one.
+	// See Queue.Wrap() for more details.
+	Middleware func(next HandlerFunc) HandlerFunc
+
+	// BatchHandlerFunc is a handler to handle incoming tasks in batches.
+	// See Queue.HandleBatch() for more details.
+	//
+	// If it returns an error, all tasks of the batch are considered failed.
+	// Otherwise, the tasks which Task.Error is set by the handler
+	// are considered failed and the rest are succeeded.
+	BatchHandlerFunc func(tasks []*Task) *ekaerr.Error
 )
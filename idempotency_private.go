@@ -0,0 +1,158 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"time"
+)
+
+// idempotencyStore returns the Broker's store of idempotency keys,
+// or nil if idempotency is disabled for the Queue (see WithIdempotency())
+// or Broker doesn't support it.
+func (q *Queue) idempotencyStore() BrokerIdempotencyStore {
+
+	if q.options.IdempotencyTTL <= 0 {
+		return nil
+	}
+
+	store, _ := q.parent.broker.(BrokerIdempotencyStore)
+	return store
+}
+
+// reserveIdempotencyKey reserves the idempotency key of the consumed Task
+// before its handlers are called (see WithIdempotency()),
+// reporting whether they must be called. They must not if:
+//  - The Task is succeeded already. It's marked as succeeded and saved then.
+//  - The Task with the same key is under processing right now.
+//    It's returned back to the pool then to be checked once again later,
+//    neither its status, nor its retries are changed.
+//
+// The reservation is expired after Task.Timeout (or after the TTL
+// of idempotency keys if there is no timeout), if the worker dies.
+//
+// The key is considered reserved if it can't be reserved because of Broker's error,
+// because it's better to handle the Task twice than never.
+func (q *Queue) reserveIdempotencyKey(t *Task) bool {
+	const s = "Bokchoy: Failed to reserve idempotency key of task. "
+
+	store := q.idempotencyStore()
+	if store == nil {
+		return true
+	}
+
+	logger := q.parent.logger.Copy().
+		WithString("bokchoy_queue_name", q.name).
+		WithString("bokchoy_task_id", t.id).
+		WithString("bokchoy_task_idempotency_key", t.IdempotencyKey())
+
+	ttl := t.Timeout
+	if ttl <= 0 {
+		ttl = q.options.IdempotencyTTL
+	}
+
+	isReserved, err := store.ReserveIdempotencyKey(q.name, t.IdempotencyKey(), ttl)
+	if err.IsNotNil() {
+		logger.Warne(s + "It will be processed anyway.", err)
+		return true
+	}
+
+	if isReserved {
+		t.isKeyReserved = true
+		return true
+	}
+
+	isSucceeded, err := store.HasIdempotencyKey(q.name, t.IdempotencyKey())
+	if err.IsNotNil() {
+		logger.Warne(s + "Failed to check whether task is succeeded already. " +
+			"It will be processed anyway.", err)
+		return true
+	}
+
+	if !isSucceeded {
+		t.ETA = time.Now().Add(_IDEMPOTENCY_POSTPONE_INTERVAL).UnixNano()
+		if err = q.PublishTask(t); err.IsNotNil() {
+			logger.Warne(s + "Task with the same key is under processing, " +
+				"but task can't be postponed. It will be processed anyway.", err)
+			return true
+		}
+
+		logger.Debug("Bokchoy: Task with the same idempotency key is under processing. Postponed.")
+		return false
+	}
+
+	logger.Debug("Bokchoy: Task is succeeded already. Skipped.")
+
+	t.markAsProcessing()
+	t.MarkAsSucceeded()
+
+	if err = q.save(t); err.IsNotNil() {
+		logger.Warne(s + "Failed to save skipped task.", err)
+	}
+
+	return false
+}
+
+// rememberSucceeded stores the idempotency key of the Task,
+// whose handlers are succeeded right now (see WithIdempotency()),
+// replacing its reservation (see reserveIdempotencyKey()).
+//
+// The error is not returned, but logged, because handlers are succeeded anyway.
+// The Task may be handled twice then.
+func (q *Queue) rememberSucceeded(t *Task) {
+	const s = "Bokchoy: Failed to save idempotency key of succeeded task. "
+
+	store := q.idempotencyStore()
+	if store == nil {
+		return
+	}
+
+	t.isKeyReserved = false
+
+	err := store.SaveIdempotencyKey(q.name, t.IdempotencyKey(), q.options.IdempotencyTTL)
+	if err.IsNotNil() {
+		q.parent.logger.Copy().
+			WithString("bokchoy_queue_name", q.name).
+			WithString("bokchoy_task_id", t.id).
+			WithString("bokchoy_task_idempotency_key", t.IdempotencyKey()).
+			Warne(s, err)
+	}
+}
+
+// releaseIdempotencyKey removes the reservation of the idempotency key
+// of the Task, that is not succeeded (or not saved), so it (or the Task
+// with the same key) may be handled again. See reserveIdempotencyKey().
+func (q *Queue) releaseIdempotencyKey(t *Task) {
+	const s = "Bokchoy: Failed to release idempotency key of task. " +
+		"Tasks with the same key will be postponed until it's expired. "
+
+	store := q.idempotencyStore()
+	if store == nil || !t.isKeyReserved {
+		return
+	}
+
+	t.isKeyReserved = false
+
+	if err := store.DeleteIdempotencyKey(q.name, t.IdempotencyKey()); err.IsNotNil() {
+		q.parent.logger.Copy().
+			WithString("bokchoy_queue_name", q.name).
+			WithString("bokchoy_task_id", t.id).
+			WithString("bokchoy_task_idempotency_key", t.IdempotencyKey()).
+			Warne(s, err)
+	}
+}
@@ -0,0 +1,273 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+// Package brokertest provides helpers for testing Bokchoy and its subpackages.
+package brokertest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+)
+
+type (
+	// MemoryBroker is an in-memory bokchoy.Broker for tests.
+	// TTL of stored tasks, workers and idempotency keys is ignored.
+	MemoryBroker struct {
+		mu      sync.Mutex
+		stored  map[string]map[string][]byte
+		pending map[string][]memoryItem
+		workers map[string][]byte
+		keys    map[string]map[string]bool // idempotency keys by queue's name, true if saved
+	}
+
+	memoryItem struct {
+		taskID string
+		eta    int64
+	}
+)
+
+// NewMemoryBroker returns a new empty MemoryBroker.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		stored:  make(map[string]map[string][]byte),
+		pending: make(map[string][]memoryItem),
+		workers: make(map[string][]byte),
+		keys:    make(map[string]map[string]bool),
+	}
+}
+
+func (b *MemoryBroker) String() string {
+	return "memory"
+}
+
+func (b *MemoryBroker) Get(queueName, taskID string) ([]byte, *ekaerr.Error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stored[queueName][taskID], nil
+}
+
+func (b *MemoryBroker) Delete(queueName, taskID string) *ekaerr.Error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.stored[queueName], taskID)
+	b.removePending(queueName, taskID)
+	return nil
+}
+
+func (b *MemoryBroker) List(queueName string) ([][]byte, *ekaerr.Error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	taskIDs := make([]string, 0, len(b.stored[queueName]))
+	for taskID := range b.stored[queueName] {
+		taskIDs = append(taskIDs, taskID)
+	}
+	sort.Strings(taskIDs)
+
+	encodedTasks := make([][]byte, len(taskIDs))
+	for i := range taskIDs {
+		encodedTasks[i] = b.stored[queueName][taskIDs[i]]
+	}
+	return encodedTasks, nil
+}
+
+func (b *MemoryBroker) Empty(queueName string) *ekaerr.Error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.stored, queueName)
+	delete(b.pending, queueName)
+	return nil
+}
+
+func (b *MemoryBroker) ClearAll() *ekaerr.Error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stored = make(map[string]map[string][]byte)
+	b.pending = make(map[string][]memoryItem)
+	return nil
+}
+
+func (b *MemoryBroker) Count(queueName string) (bokchoy.BrokerStats, *ekaerr.Error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var (
+		stats bokchoy.BrokerStats
+		now   = time.Now().UnixNano()
+	)
+
+	for _, item := range b.pending[queueName] {
+		if item.eta <= now {
+			stats.Direct++
+		} else {
+			stats.Delayed++
+		}
+	}
+
+	stats.Total = stats.Direct + stats.Delayed
+	return stats, nil
+}
+
+func (b *MemoryBroker) Set(queueName, taskID string, data []byte, _ time.Duration) *ekaerr.Error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.set(queueName, taskID, data)
+	return nil
+}
+
+func (b *MemoryBroker) Publish(queueName, taskID string, data []byte, eta int64) *ekaerr.Error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.set(queueName, taskID, data)
+	b.pending[queueName] = append(b.pending[queueName], memoryItem{taskID, eta})
+	return nil
+}
+
+func (b *MemoryBroker) PublishMany(queueName string, items []bokchoy.BrokerPublishItem) []*ekaerr.Error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, item := range items {
+		b.set(queueName, item.TaskID, item.TaskPayload)
+		b.pending[queueName] = append(b.pending[queueName], memoryItem{item.TaskID, item.TaskEtaUnixNano})
+	}
+	return nil
+}
+
+func (b *MemoryBroker) RemovePending(queueName, taskID string) *ekaerr.Error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removePending(queueName, taskID)
+	return nil
+}
+
+func (b *MemoryBroker) SaveWorker(workerID string, data []byte, _ time.Duration) *ekaerr.Error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.workers[workerID] = data
+	return nil
+}
+
+func (b *MemoryBroker) DeleteWorker(workerID string) *ekaerr.Error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.workers, workerID)
+	return nil
+}
+
+func (b *MemoryBroker) Workers() ([][]byte, *ekaerr.Error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	workers := make([][]byte, 0, len(b.workers))
+	for _, data := range b.workers {
+		workers = append(workers, data)
+	}
+
+	return workers, nil
+}
+
+func (b *MemoryBroker) ReserveIdempotencyKey(queueName, key string, _ time.Duration) (bool, *ekaerr.Error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.keys[queueName][key]; ok {
+		return false, nil
+	}
+	if b.keys[queueName] == nil {
+		b.keys[queueName] = make(map[string]bool)
+	}
+	b.keys[queueName][key] = false
+	return true, nil
+}
+
+func (b *MemoryBroker) SaveIdempotencyKey(queueName, key string, _ time.Duration) *ekaerr.Error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.keys[queueName] == nil {
+		b.keys[queueName] = make(map[string]bool)
+	}
+	b.keys[queueName][key] = true
+	return nil
+}
+
+func (b *MemoryBroker) HasIdempotencyKey(queueName, key string) (bool, *ekaerr.Error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.keys[queueName][key], nil
+}
+
+func (b *MemoryBroker) DeleteIdempotencyKey(queueName, key string) *ekaerr.Error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.keys[queueName], key)
+	return nil
+}
+
+func (b *MemoryBroker) Consume(queueName string, maxETA int64) ([][]byte, *ekaerr.Error) {
+	if maxETA == 0 {
+		maxETA = time.Now().UnixNano()
+	}
+
+	b.mu.Lock()
+
+	var (
+		encodedTasks [][]byte
+		leftItems    []memoryItem
+	)
+
+	for _, item := range b.pending[queueName] {
+		if item.eta <= maxETA {
+			encodedTasks = append(encodedTasks, b.stored[queueName][item.taskID])
+		} else {
+			leftItems = append(leftItems, item)
+		}
+	}
+
+	b.pending[queueName] = leftItems
+	b.mu.Unlock()
+
+	// Real brokers block for a while if there is nothing to consume.
+	if len(encodedTasks) == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	return encodedTasks, nil
+}
+
+func (b *MemoryBroker) set(queueName, taskID string, data []byte) {
+	if b.stored[queueName] == nil {
+		b.stored[queueName] = make(map[string][]byte)
+	}
+	b.stored[queueName][taskID] = data
+}
+
+func (b *MemoryBroker) removePending(queueName, taskID string) {
+	items := b.pending[queueName]
+	for i := range items {
+		if items[i].taskID == taskID {
+			b.pending[queueName] = append(items[:i:i], items[i+1:]...)
+			return
+		}
+	}
+}
+
+var _ bokchoy.Broker = (*MemoryBroker)(nil)
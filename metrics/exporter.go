@@ -0,0 +1,164 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/qioalice/bokchoy"
+)
+
+type (
+	// Exporter collects Bokchoy's metrics and exposes them
+	// in the Prometheus text exposition format.
+	// Use NewExporter() to create it.
+	Exporter struct {
+		mu       sync.Mutex
+		queues   map[string]*queueMetrics
+		watched  []*bokchoy.Bokchoy
+	}
+)
+
+// Make sure Exporter implements bokchoy.Collector and http.Handler.
+var (
+	_ bokchoy.Collector = (*Exporter)(nil)
+	_ http.Handler      = (*Exporter)(nil)
+)
+
+// NewExporter returns a new Exporter with no collected metrics.
+func NewExporter() *Exporter {
+	return &Exporter{
+		queues: make(map[string]*queueMetrics),
+	}
+}
+
+// Watch registers Bokchoy, whose queues' gauges (number of tasks, consumers)
+// will be requested at the each scrape. Does nothing if b is nil.
+//
+// Queues are distinguished by their names only. So, if many watched Bokchoy
+// have the queue with the same name (e.g. they share the Broker),
+// its gauges are requested from the first watched one.
+func (e *Exporter) Watch(b *bokchoy.Bokchoy) *Exporter {
+
+	if b == nil {
+		return e
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.watched = append(e.watched, b)
+	return e
+}
+
+// TaskPublished implements bokchoy.Collector.
+// Returning a Task back to be retried is not counted as publishing.
+func (e *Exporter) TaskPublished(task *bokchoy.Task) {
+
+	if task.Status() == bokchoy.TASK_STATUS_RETRYING {
+		return // already counted as retried by TaskProcessed()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.queue(task.QueueName()).published++
+}
+
+// TaskProcessed implements bokchoy.Collector.
+func (e *Exporter) TaskProcessed(task *bokchoy.Task) {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	qm := e.queue(task.QueueName())
+
+	switch task.Status() {
+	case bokchoy.TASK_STATUS_SUCCEEDED:
+		qm.succeeded++
+		qm.execTime.observe(task.ExecTime.Seconds())
+	case bokchoy.TASK_STATUS_FAILED:
+		qm.failed++
+		qm.execTime.observe(task.ExecTime.Seconds())
+	case bokchoy.TASK_STATUS_RETRYING:
+		qm.retried++
+	case bokchoy.TASK_STATUS_TIMED_OUT:
+		qm.timedOut++
+	case bokchoy.TASK_STATUS_CANCELLED:
+		qm.cancelled++
+	}
+
+	// Task is waiting since it's enqueued (published or returned back to be retried),
+	// but not before its ETA (a delayed one, or the one that is being retried).
+	if startedAt := task.StartedAt(); !startedAt.IsZero() {
+		waitingSince := task.EnqueuedAt()
+		if waitingSince.IsZero() {
+			waitingSince = task.PublishedAt.Std() // published by the older version
+		}
+		if eta := time.Unix(0, task.ETA); eta.After(waitingSince) {
+			waitingSince = eta
+		}
+		waitTime := startedAt.Sub(waitingSince).Seconds()
+		if waitTime < 0 {
+			waitTime = 0 // clocks of publisher and consumer may differ
+		}
+		qm.waitTime.observe(waitTime)
+	}
+}
+
+// TaskCanceled implements bokchoy.Collector.
+func (e *Exporter) TaskCanceled(task *bokchoy.Task) {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.queue(task.QueueName()).cancelled++
+}
+
+// ServeHTTP implements http.Handler,
+// writing all metrics in the Prometheus text exposition format.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+
+	// Broker is requested for gauges without holding the lock,
+	// because it may take a while.
+	gauges := e.gauges()
+
+	var buf bytes.Buffer
+
+	e.mu.Lock()
+	for i := range gauges {
+		_ = e.queue(gauges[i].queueName) // zero counters for watched queues
+	}
+	queueNames := make([]string, 0, len(e.queues))
+	for queueName := range e.queues {
+		queueNames = append(queueNames, queueName)
+	}
+	sort.Strings(queueNames)
+	e.writeCounters(&buf, queueNames)
+	e.mu.Unlock()
+
+	writeGauges(&buf, gauges)
+
+	w.Header().Set("Content-Type", _CONTENT_TYPE)
+	_, _ = w.Write(buf.Bytes())
+}
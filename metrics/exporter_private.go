@@ -0,0 +1,313 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/qioalice/bokchoy"
+)
+
+type (
+	// queueMetrics is the collected metrics of one queue.
+	queueMetrics struct {
+		published  uint64
+		succeeded  uint64
+		failed     uint64
+		retried    uint64
+		timedOut   uint64
+		cancelled  uint64
+
+		execTime   histogram
+		waitTime   histogram
+	}
+
+	// histogram is a Prometheus-like cumulative histogram.
+	// counts[i] is the number of observations that are <= buckets[i],
+	// the number of all observations (+Inf bucket) is stored in count.
+	histogram struct {
+		buckets  []float64
+		counts   []uint64
+		count    uint64
+		sum      float64
+	}
+
+	// queueGauges is the gauges of one queue, requested at the scrape.
+	queueGauges struct {
+		queueName   string
+		brokerStats bokchoy.BrokerStats
+		brokerOK    bool
+		consumers   bokchoy.ConsumersStats
+		breaker     bokchoy.CircuitBreakerStats
+	}
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	_CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+var (
+	// execTimeBuckets are the default Prometheus buckets.
+	execTimeBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+	// waitTimeBuckets are wider than execTimeBuckets,
+	// because tasks usually wait longer than they're executed.
+	waitTimeBuckets = []float64{1, 5, 10, 30, 60, 300, 600, 1800, 3600}
+
+	// labelValueReplacer escapes label value according with the text format.
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+func newHistogram(buckets []float64) histogram {
+	return histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	for i := range h.buckets {
+		if v <= h.buckets[i] {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// queue returns the metrics of the queue with the given name,
+// creating them if they are not exist yet.
+// Caller must take responsibility about locking to provide thread-safety.
+func (e *Exporter) queue(queueName string) *queueMetrics {
+	qm := e.queues[queueName]
+	if qm == nil {
+		qm = &queueMetrics{
+			execTime: newHistogram(execTimeBuckets),
+			waitTime: newHistogram(waitTimeBuckets),
+		}
+		e.queues[queueName] = qm
+	}
+	return qm
+}
+
+// gauges requests gauges of all queues of all watched Bokchoy instances.
+// If many of them have the queue with the same name, only the first one
+// is requested, otherwise the same series would be written many times.
+func (e *Exporter) gauges() []queueGauges {
+
+	e.mu.Lock()
+	watched := append(e.watched[:0:0], e.watched...)
+	e.mu.Unlock()
+
+	var (
+		gauges []queueGauges
+		seen   = make(map[string]struct{})
+	)
+
+	for _, b := range watched {
+		for _, q := range b.Queues() {
+			if _, isSeen := seen[q.Name()]; isSeen {
+				continue
+			}
+			seen[q.Name()] = struct{}{}
+
+			brokerStats, err := q.Count()
+			gauges = append(gauges, queueGauges{
+				queueName:   q.Name(),
+				brokerStats: brokerStats,
+				brokerOK:    err.IsNil(),
+				consumers:   q.ConsumersStats(),
+				breaker:     q.CircuitBreakerStats(),
+			})
+		}
+	}
+
+	return gauges
+}
+
+// writeCounters writes counters and histograms of the given queues.
+// Caller must take responsibility about locking to provide thread-safety.
+func (e *Exporter) writeCounters(buf *bytes.Buffer, queueNames []string) {
+
+	counters := []struct {
+		name, help string
+		value      func(qm *queueMetrics) uint64
+	}{
+		{"bokchoy_tasks_published_total", "Number of published tasks.",
+			func(qm *queueMetrics) uint64 { return qm.published }},
+		{"bokchoy_tasks_succeeded_total", "Number of succeeded tasks.",
+			func(qm *queueMetrics) uint64 { return qm.succeeded }},
+		{"bokchoy_tasks_failed_total", "Number of failed tasks.",
+			func(qm *queueMetrics) uint64 { return qm.failed }},
+		{"bokchoy_tasks_retried_total", "Number of tasks returned to be retried.",
+			func(qm *queueMetrics) uint64 { return qm.retried }},
+		{"bokchoy_tasks_timed_out_total", "Number of timed out tasks.",
+			func(qm *queueMetrics) uint64 { return qm.timedOut }},
+		{"bokchoy_tasks_cancelled_total", "Number of cancelled tasks.",
+			func(qm *queueMetrics) uint64 { return qm.cancelled }},
+	}
+
+	for _, counter := range counters {
+		writeHeader(buf, counter.name, counter.help, "counter")
+		for _, queueName := range queueNames {
+			writeSample(buf, counter.name, queueLabels(queueName),
+				float64(counter.value(e.queues[queueName])))
+		}
+	}
+
+	histograms := []struct {
+		name, help string
+		value      func(qm *queueMetrics) *histogram
+	}{
+		{"bokchoy_task_exec_time_seconds", "Time of task execution.",
+			func(qm *queueMetrics) *histogram { return &qm.execTime }},
+		{"bokchoy_task_wait_time_seconds", "Time between task enqueuing (or its ETA) and start of its processing.",
+			func(qm *queueMetrics) *histogram { return &qm.waitTime }},
+	}
+
+	for _, hist := range histograms {
+		writeHeader(buf, hist.name, hist.help, "histogram")
+		for _, queueName := range queueNames {
+			h := hist.value(e.queues[queueName])
+			labels := queueLabels(queueName)
+			for i := range h.buckets {
+				writeSample(buf, hist.name+"_bucket",
+					append(labels, "le", formatFloat(h.buckets[i])), float64(h.counts[i]))
+			}
+			writeSample(buf, hist.name+"_bucket",
+				append(labels, "le", "+Inf"), float64(h.count))
+			writeSample(buf, hist.name+"_sum", labels, h.sum)
+			writeSample(buf, hist.name+"_count", labels, float64(h.count))
+		}
+	}
+}
+
+// writeGauges writes gauges of the given queues, that have been requested
+// by Exporter.gauges().
+func writeGauges(buf *bytes.Buffer, gauges []queueGauges) {
+
+	const (
+		tasks        = "bokchoy_queue_tasks"
+		consumers    = "bokchoy_queue_consumers"
+		breakerState = "bokchoy_queue_circuit_breaker_state"
+		breakerTrips = "bokchoy_queue_circuit_breaker_trips_total"
+	)
+
+	writeHeader(buf, tasks, "Number of waiting tasks in the queue.", "gauge")
+	for i := range gauges {
+		if !gauges[i].brokerOK {
+			continue
+		}
+		labels := queueLabels(gauges[i].queueName)
+		writeSample(buf, tasks, append(labels, "state", "direct"),
+			float64(gauges[i].brokerStats.Direct))
+		writeSample(buf, tasks, append(labels, "state", "delayed"),
+			float64(gauges[i].brokerStats.Delayed))
+	}
+
+	writeHeader(buf, consumers, "Number of queue's consumers.", "gauge")
+	for i := range gauges {
+		labels := queueLabels(gauges[i].queueName)
+		writeSample(buf, consumers, append(labels, "state", "active"),
+			float64(gauges[i].consumers.Active))
+		writeSample(buf, consumers, append(labels, "state", "frozen"),
+			float64(gauges[i].consumers.Frozen))
+	}
+
+	breakerStates := []struct {
+		label string
+		state bokchoy.CircuitBreakerState
+	}{
+		{"closed", bokchoy.CIRCUIT_BREAKER_STATE_CLOSED},
+		{"open", bokchoy.CIRCUIT_BREAKER_STATE_OPEN},
+		{"half_open", bokchoy.CIRCUIT_BREAKER_STATE_HALF_OPEN},
+	}
+
+	writeHeader(buf, breakerState, "State of queue's circuit breaker (1 is the current one).", "gauge")
+	for i := range gauges {
+		labels := queueLabels(gauges[i].queueName)
+		for _, bs := range breakerStates {
+			value := 0.
+			if gauges[i].breaker.State == bs.state {
+				value = 1
+			}
+			writeSample(buf, breakerState, append(labels, "state", bs.label), value)
+		}
+	}
+
+	writeHeader(buf, breakerTrips, "Number of times queue's circuit breaker has been opened.", "counter")
+	for i := range gauges {
+		writeSample(buf, breakerTrips, queueLabels(gauges[i].queueName),
+			float64(gauges[i].breaker.Trips))
+	}
+}
+
+// queueLabels returns the labels pairs with the queue name,
+// with the capacity for one more pair, so append() won't share memory.
+func queueLabels(queueName string) []string {
+	labels := make([]string, 2, 4)
+	labels[0], labels[1] = "queue", queueName
+	return labels
+}
+
+func writeHeader(buf *bytes.Buffer, name, help, typ string) {
+	_, _ = fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// writeSample writes one sample. labels are key-value pairs.
+func writeSample(buf *bytes.Buffer, name string, labels []string, value float64) {
+
+	buf.WriteString(name)
+
+	if len(labels) > 0 {
+		buf.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(labels[i])
+			buf.WriteString(`="`)
+			buf.WriteString(labelValueReplacer.Replace(labels[i+1]))
+			buf.WriteByte('"')
+		}
+		buf.WriteByte('}')
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(formatFloat(value))
+	buf.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, +1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,81 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package metrics_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/qioalice/bokchoy"
+	"github.com/qioalice/bokchoy/internal/brokertest"
+	"github.com/qioalice/bokchoy/metrics"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter(t *testing.T) {
+
+	exporter := metrics.NewExporter()
+
+	b, err := bokchoy.New(
+		bokchoy.WithBroker(brokertest.NewMemoryBroker()),
+		bokchoy.WithSerializer(bokchoy.DefaultSerializerDummy()),
+		bokchoy.WithDisableOutput(true),
+		bokchoy.WithCollector(exporter),
+	)
+	require.True(t, err.IsNil())
+	exporter.Watch(b).Watch(b) // must not be duplicated
+
+	_ = b.Queue("tasks.idle")
+
+	task, err := b.Publish("tasks.test", nil)
+	require.True(t, err.IsNil())
+
+	task.MarkAsSucceeded()
+	exporter.TaskProcessed(task)
+
+	task, err = b.Publish("tasks.test", nil)
+	require.True(t, err.IsNil())
+
+	task.MarkAsCanceled()
+	exporter.TaskCanceled(task)
+
+	rec := httptest.NewRecorder()
+	exporter.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	require.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain"))
+
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE bokchoy_tasks_published_total counter",
+		`bokchoy_tasks_published_total{queue="tasks.test"} 2`,
+		`bokchoy_tasks_published_total{queue="tasks.idle"} 0`,
+		`bokchoy_tasks_succeeded_total{queue="tasks.test"} 1`,
+		`bokchoy_tasks_cancelled_total{queue="tasks.test"} 1`,
+		`bokchoy_task_exec_time_seconds_bucket{queue="tasks.test",le="+Inf"} 1`,
+		`bokchoy_task_exec_time_seconds_count{queue="tasks.test"} 1`,
+		`bokchoy_queue_tasks{queue="tasks.test",state="direct"} 2`,
+		`bokchoy_queue_consumers{queue="tasks.test",state="frozen"} 0`,
+		`bokchoy_queue_circuit_breaker_state{queue="tasks.test",state="closed"} 1`,
+		`bokchoy_queue_circuit_breaker_trips_total{queue="tasks.test"} 0`,
+	} {
+		require.Equal(t, 1, strings.Count(body, line+"\n"), line)
+	}
+}
@@ -0,0 +1,49 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+// Package metrics provides a Prometheus exporter of Bokchoy's metrics.
+//
+// Exporter is a bokchoy.Collector, that counts tasks by their lifecycle events,
+// and an http.Handler, that exposes collected metrics
+// in the Prometheus text exposition format:
+//
+//     exporter := metrics.NewExporter()
+//     b, err := bokchoy.New(bokchoy.WithCollector(exporter), ...)
+//     exporter.Watch(b)
+//
+//     http.Handle("/metrics", exporter)
+//
+// All metrics are labelled by queue name. Exposed metrics:
+//
+//  - bokchoy_tasks_published_total (counter),
+//  - bokchoy_tasks_succeeded_total (counter),
+//  - bokchoy_tasks_failed_total (counter),
+//  - bokchoy_tasks_retried_total (counter),
+//  - bokchoy_tasks_timed_out_total (counter),
+//  - bokchoy_tasks_cancelled_total (counter),
+//  - bokchoy_task_exec_time_seconds (histogram, bokchoy.Task.ExecTime),
+//  - bokchoy_task_wait_time_seconds (histogram, from enqueuing to start of processing),
+//  - bokchoy_queue_tasks (gauge, labelled by state: direct, delayed),
+//  - bokchoy_queue_consumers (gauge, labelled by state: active, frozen),
+//  - bokchoy_queue_circuit_breaker_state (gauge, labelled by state: closed, open, half_open),
+//  - bokchoy_queue_circuit_breaker_trips_total (counter).
+//
+// Gauges are requested at the each scrape from the watched Bokchoy instances
+// (see Exporter.Watch()), counters and histograms are collected
+// by the Exporter itself, so it must be registered using bokchoy.WithCollector().
+package metrics
@@ -0,0 +1,63 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package middleware
+
+import (
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+)
+
+// Logger is a middleware that logs the start and the end of the next handler's
+// execution with the elapsed processing time, using the Task's logger.
+// See bokchoy.Task.Logger().
+//
+// An error returned by next handler is not logged (only its ID and class),
+// because it will be stored into bokchoy.Task.Error.
+func Logger(next bokchoy.HandlerFunc) bokchoy.HandlerFunc {
+	return func(task *bokchoy.Task) *ekaerr.Error {
+
+		task.Logger().Copy().
+			WithString("bokchoy_queue_name", task.QueueName()).
+			WithString("bokchoy_task_id", task.ID()).
+			Info("Bokchoy.Logger: Task handler is started.")
+
+		startedAt := time.Now()
+		err := next(task)
+		elapsed := time.Since(startedAt)
+
+		logger := task.Logger().Copy().
+			WithString("bokchoy_queue_name", task.QueueName()).
+			WithString("bokchoy_task_id", task.ID()).
+			WithDuration("bokchoy_task_elapsed", elapsed)
+
+		if err.IsNotNil() {
+			logger.
+				WithString("bokchoy_task_error_id", err.ID()).
+				WithString("bokchoy_task_error_class", err.Class().FullName()).
+				Warn("Bokchoy.Logger: Task handler is finished with error.")
+		} else {
+			logger.Info("Bokchoy.Logger: Task handler is finished.")
+		}
+
+		return err
+	}
+}
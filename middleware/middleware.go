@@ -0,0 +1,31 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+// Package middleware provides a suite of standard Bokchoy's middlewares.
+//
+// Each middleware is a bokchoy.Middleware: it wraps a bokchoy.HandlerFunc
+// and returns a new one. Thus it can be used with Queue.Wrap() to wrap
+// all queue's handlers:
+//
+//     queue.Use(handler).Wrap(middleware.RequestID, middleware.Logger, middleware.Recoverer)
+//
+// or with Queue.Use() to wrap only one handler:
+//
+//     queue.Use(middleware.Logger(handler))
+//
+package middleware
@@ -0,0 +1,96 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package middleware_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+	"github.com/qioalice/ekago/v3/ekalog"
+
+	"github.com/qioalice/bokchoy"
+	"github.com/qioalice/bokchoy/internal/brokertest"
+	"github.com/qioalice/bokchoy/middleware"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTask(t *testing.T) *bokchoy.Task {
+
+	b, err := bokchoy.New(
+		bokchoy.WithBroker(brokertest.NewMemoryBroker()),
+		bokchoy.WithSerializer(bokchoy.DefaultSerializerDummy()),
+		bokchoy.WithDisableOutput(true),
+	)
+	require.True(t, err.IsNil())
+
+	return b.Queue("tasks.test").NewTask(nil)
+}
+
+func TestRecoverer(t *testing.T) {
+
+	handler := middleware.Recoverer(func(_ *bokchoy.Task) *ekaerr.Error {
+		panic("something went wrong")
+	})
+
+	err := handler(newTestTask(t))
+	require.True(t, err.IsNotNil())
+	require.True(t, err.Is(ekaerr.IllegalState))
+}
+
+func TestTimeout(t *testing.T) {
+
+	handler := middleware.Timeout(10 * time.Millisecond)(func(task *bokchoy.Task) *ekaerr.Error {
+		select {
+		case <-task.Context().Done():
+			return nil
+		case <-time.After(5 * time.Second):
+			return ekaerr.IllegalState.New("Task's context has not been cancelled.").Throw()
+		}
+	})
+
+	task := newTestTask(t)
+	err := handler(task)
+	require.True(t, err.IsNotNil())
+	require.True(t, err.Is(ekaerr.TimeoutElapsed))
+	require.NoError(t, task.Context().Err())
+
+	handler = middleware.Timeout(100 * time.Millisecond)(func(_ *bokchoy.Task) *ekaerr.Error {
+		return ekaerr.IllegalArgument.New("Bad payload.").Throw()
+	})
+
+	err = handler(newTestTask(t))
+	require.True(t, err.IsNotNil())
+	require.True(t, err.Is(ekaerr.IllegalArgument))
+}
+
+func TestLoggerRequestID(t *testing.T) {
+
+	task := newTestTask(t)
+	logger := ekalog.Copy()
+	task.SetLogger(logger)
+
+	handler := middleware.RequestID(middleware.Logger(func(task *bokchoy.Task) *ekaerr.Error {
+		require.NotSame(t, logger, task.Logger())
+		return nil
+	}))
+
+	require.True(t, handler(task).IsNil())
+}
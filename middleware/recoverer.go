@@ -0,0 +1,65 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package middleware
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+)
+
+// Recoverer is a middleware that gracefully absorbs panics of the next handler,
+// logs them with the stack trace (using the Task's logger)
+// and converts them to the returned error.
+//
+// Unlike a panic that is recovered by Bokchoy itself,
+// bokchoy.Task.Panic stays nil, but bokchoy.Task.Error will contain
+// the panic's value and the stack trace of the place it has been occurred.
+func Recoverer(next bokchoy.HandlerFunc) bokchoy.HandlerFunc {
+	return func(task *bokchoy.Task) (err *ekaerr.Error) {
+
+		defer func() {
+			panicValue := recover()
+			if panicValue == nil {
+				return
+			}
+
+			panicValueStr := fmt.Sprintf("%+v", panicValue)
+			stacktrace := string(debug.Stack())
+
+			task.Logger().Copy().
+				WithString("bokchoy_queue_name", task.QueueName()).
+				WithString("bokchoy_task_id", task.ID()).
+				WithString("bokchoy_task_panic", panicValueStr).
+				WithString("bokchoy_task_panic_stacktrace", stacktrace).
+				Error("Bokchoy.Recoverer: Task handler panicked.")
+
+			err = ekaerr.IllegalState.
+				New("Bokchoy.Recoverer: Task handler panicked.").
+				WithString("bokchoy_task_panic", panicValueStr).
+				WithString("bokchoy_task_panic_stacktrace", stacktrace).
+				Throw()
+		}()
+
+		return next(task)
+	}
+}
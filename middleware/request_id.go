@@ -0,0 +1,40 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package middleware
+
+import (
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+)
+
+// RequestID is a middleware that injects the Task's ID (and its queue's name)
+// into the Task's logger, so each log entry of the next handler
+// (and middlewares after this one) will contain it.
+// See bokchoy.Task.Logger(), bokchoy.Task.SetLogger().
+func RequestID(next bokchoy.HandlerFunc) bokchoy.HandlerFunc {
+	return func(task *bokchoy.Task) *ekaerr.Error {
+
+		task.SetLogger(task.Logger().Copy().
+			WithString("bokchoy_queue_name", task.QueueName()).
+			WithString("bokchoy_task_id", task.ID()))
+
+		return next(task)
+	}
+}
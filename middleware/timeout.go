@@ -0,0 +1,79 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+)
+
+// Timeout returns a middleware that limits the execution time
+// of the next handler by the presented timeout.
+//
+// It's NOT a soft timeout and it's NOT non-blocking: Timeout always waits
+// until the next handler returns, even if the timeout is reached,
+// because the next handler uses the same Task and Go has no way to interrupt it.
+//
+// Instead, the next handler is called with the Task's context
+// (see bokchoy.Task.Context()), that is cancelled when timeout is reached.
+// If it's reached, the next handler's result is ignored and an error is returned.
+// So, make sure your handler respects the Task's context or will finish soon,
+// otherwise Timeout locks as long as the handler does.
+//
+// Unlike bokchoy.WithTimeout() option, that limits the whole Task's processing,
+// it's a per-handler timeout, and the Task will be retried (if it's allowed)
+// when it's reached.
+//
+// Does nothing (returns next as is) if timeout <= 0.
+func Timeout(timeout time.Duration) bokchoy.Middleware {
+	return func(next bokchoy.HandlerFunc) bokchoy.HandlerFunc {
+
+		if timeout <= 0 {
+			return next
+		}
+
+		return func(task *bokchoy.Task) *ekaerr.Error {
+
+			parentCtx := task.Context()
+			ctx, cancel := context.WithTimeout(parentCtx, timeout)
+			defer cancel()
+
+			task.SetContext(ctx)
+			defer task.SetContext(parentCtx)
+
+			err := next(task)
+
+			// The parent's cancellation or deadline is not the handler's timeout.
+			if ctx.Err() != context.DeadlineExceeded || parentCtx.Err() != nil {
+				return err
+			}
+
+			return ekaerr.TimeoutElapsed.
+				New("Bokchoy.Timeout: Task handler timed out.").
+				WithString("bokchoy_queue_name", task.QueueName()).
+				WithString("bokchoy_task_id", task.ID()).
+				WithDuration("bokchoy_task_handler_timeout", timeout).
+				Throw()
+		}
+	}
+}
@@ -19,6 +19,8 @@
 package bokchoy
 
 import (
+	"context"
+	"database/sql"
 	"time"
 
 	"github.com/qioalice/ekago/v3/ekalog"
@@ -37,6 +39,105 @@ func WithDisableOutput(disableOutput bool) Option {
 	}
 }
 
+// WithHeartbeatInterval defines how often the running Bokchoy instance (worker)
+// saves its information (hostname, PID, queues, etc) to the Broker.
+// The worker is considered dead, if it has missed 3 heartbeats in a row.
+// See Bokchoy.Workers(), WithOrphanPolicy().
+//
+// Each heartbeat costs one Broker.SaveWorker() call per worker.
+//
+// Makes sense only if Broker implements BrokerWorkerRegistry.
+// Default is: 10s. Zero disables registering of the worker.
+func WithHeartbeatInterval(interval time.Duration) Option {
+	if interval < 0 {
+		interval = 0
+	}
+	return func(opts *options) {
+		opts.HeartbeatInterval = interval
+	}
+}
+
+// WithOutbox defines the outbox table in db, the tasks are written to
+// by Queue.PublishTx() inside the caller's transaction.
+// The running Bokchoy relays them to the Broker then.
+//
+// The table must be created by the caller, e.g. for PostgreSQL:
+//
+//     CREATE TABLE bokchoy_outbox (
+//         task_id    VARCHAR(64)  PRIMARY KEY,
+//         queue_name VARCHAR(255) NOT NULL,
+//         task       BYTEA        NOT NULL,
+//         created_at BIGINT       NOT NULL
+//     );
+//     CREATE INDEX ON bokchoy_outbox (queue_name, created_at);
+//
+// Table's name is not escaped. Default is: "bokchoy_outbox".
+// Placeholder is the style of bind parameters supported by db's driver.
+// Default is: OUTBOX_PLACEHOLDER_QUESTION.
+//
+// Makes sense only as Bokchoy's option.
+// Tasks are relayed only for the queues, that are declared before Run().
+func WithOutbox(db *sql.DB, table string, placeholder OutboxPlaceholder) Option {
+	if table == "" {
+		table = _DEFAULT_OUTBOX_TABLE
+	}
+	if placeholder != OUTBOX_PLACEHOLDER_DOLLAR {
+		placeholder = OUTBOX_PLACEHOLDER_QUESTION
+	}
+	return func(opts *options) {
+		opts.OutboxDB = db
+		opts.OutboxTable = table
+		opts.OutboxPlaceholder = placeholder
+	}
+}
+
+// WithOrphanPolicy defines what to do with the orphaned Task,
+// whose worker has died while it's been processing it.
+//
+// Orphaned tasks are recovered by the oldest alive worker
+// once the dead one has missed its heartbeats (see WithHeartbeatInterval()).
+// Tasks that are processed by unregistered workers are never considered orphaned.
+//
+// Recovery is not free: each consumed Task is claimed by one more Broker.Set()
+// and unclaimed by one more Broker.Delete() when it's processed,
+// and the oldest alive worker lists the claims of the dead ones
+// each 3 heartbeat intervals.
+// Default is: ORPHAN_POLICY_IGNORE (tasks are not claimed, nor recovered).
+func WithOrphanPolicy(policy OrphanPolicy) Option {
+	if policy != ORPHAN_POLICY_RETRY && policy != ORPHAN_POLICY_FAIL {
+		policy = ORPHAN_POLICY_IGNORE
+	}
+	return func(opts *options) {
+		opts.OrphanPolicy = policy
+	}
+}
+
+// WithIdempotency enables the idempotency keys of the succeeded tasks.
+// A key is stored for ttl once the Task's handlers are succeeded,
+// and the Task with the same key is not handled again then,
+// but it's considered succeeded immediately.
+//
+// Thus the Task, that is re-delivered after the worker's crash or retried
+// (e.g. if it's timed out, but its handlers are succeeded eventually)
+// is not handled twice. The key is Task's ID by default,
+// use WithIdempotencyKey() to deduplicate different tasks.
+//
+// The key is reserved before the Task is handled, so the Task with the same key,
+// that is consumed meanwhile, is not handled concurrently, but it's postponed.
+// The reservation is expired after Task's timeout (see WithTimeout()),
+// if the worker dies while it's handling the Task.
+//
+// Makes sense only if Broker implements BrokerIdempotencyStore.
+// Default is: 0 (disabled).
+func WithIdempotency(ttl time.Duration) Option {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return func(opts *options) {
+		opts.IdempotencyTTL = ttl
+	}
+}
+
 // WithBroker registers new broker.
 func WithBroker(broker Broker) Option {
 	return func(opts *options) {
@@ -73,6 +174,7 @@ func WithLogger(logger *ekalog.Logger) Option {
 }
 
 // WithTimeout defines the timeout used to execute a task.
+// Task's context (see Task.Context()) is cancelled when it's reached.
 func WithTimeout(timeout time.Duration) Option {
 	if timeout < 0 {
 		timeout = 0
@@ -99,6 +201,60 @@ func WithConcurrency(concurrency int8) Option {
 	}
 }
 
+// WithAutoscaling enables autoscaling of the number of concurrent consumers
+// between min and max (inclusive), according with the queue's backlog
+// (see Queue.Count()) and the measured exec time of its tasks.
+//
+// Autoscaler tries to keep the number of consumers, that is enough
+// to process the whole backlog for about a second. It grows the number
+// of consumers immediately, but shrinks it one by one.
+// Concurrency defined by WithConcurrency() is the initial one.
+//
+// min less than 1 is considered as 1. max less than min is considered as min.
+func WithAutoscaling(min, max int8) Option {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	return func(opts *options) {
+		opts.AutoscalingMin = min
+		opts.AutoscalingMax = max
+	}
+}
+
+// WithCircuitBreaker configures the queue's circuit breaker, that decreases load
+// to the either Broker, or any your service when errors occur in a row.
+//
+// When brokerErrors Broker's errors (consuming, saving tasks)
+// or handlerErrors handlers' errors (failed, timed out tasks) occur in a row,
+// the circuit breaker is opened: all consumers but one are frozen,
+// and the remaining one does nothing for coolDown.
+// Then the circuit breaker is half-open, the remaining consumer tries again.
+// The first success closes the circuit breaker (and unfreezes all consumers),
+// the first error opens it again.
+//
+// Zero threshold means the corresponding errors are not counted.
+// Default is: 32 Broker's errors, handlers' errors are not counted, 5s of cool-down.
+// See EVENT_TYPE_CIRCUIT_BREAKER_OPENED, Queue.CircuitBreakerStats().
+func WithCircuitBreaker(brokerErrors, handlerErrors int, coolDown time.Duration) Option {
+	if brokerErrors < 0 {
+		brokerErrors = 0
+	}
+	if handlerErrors < 0 {
+		handlerErrors = 0
+	}
+	if coolDown < 0 {
+		coolDown = 0
+	}
+	return func(opts *options) {
+		opts.CircuitBreakerBrokerErrors = brokerErrors
+		opts.CircuitBreakerHandlerErrors = handlerErrors
+		opts.CircuitBreakerCoolDown = coolDown
+	}
+}
+
 // WithMaxRetries defines the number of maximum retries for a failed task.
 func WithMaxRetries(maxRetries int8) Option {
 	if maxRetries < 0 {
@@ -135,6 +291,109 @@ func WithTTL(ttl time.Duration) Option {
 	}
 }
 
+// WithHeader adds a header (metadata) with the given key and value
+// to the Task being published. Overwrites the header with the same key if any.
+//
+// Headers are stored along with Task, but separately from its payload,
+// and are available from both of handlers and callbacks using Task.Headers.
+// It's a good place for cross-cutting metadata like tenant ID, trace ID, etc.
+//
+// If it's used as Bokchoy's or Queue's option, the header will be added
+// to each Task of the corresponding queues.
+func WithHeader(key, value string) Option {
+	return func(opts *options) {
+		// Options objects are copied by value at the queue or task creation,
+		// thus the map may be shared. Copy-on-write.
+		headers := make(map[string]string, len(opts.Headers)+1)
+		for k, v := range opts.Headers {
+			headers[k] = v
+		}
+		headers[key] = value
+		opts.Headers = headers
+	}
+}
+
+// WithSigningKey enables HMAC-SHA256 signing of encoded tasks using provided key.
+//
+// Each Task being published or saved will be signed, and each Task
+// being retrieved from the Broker will be verified before it's decoded.
+// Tasks with invalid (or absent) signature will never reach your handlers:
+// consumed ones are moved to the quarantine queue (see WithQuarantineQueue()),
+// and the others are rejected with an error.
+//
+// All your producers and consumers must use the same key.
+// Passing an empty key disables signing.
+func WithSigningKey(key []byte) Option {
+	keyCopy := append(key[:0:0], key...)
+	return func(opts *options) {
+		opts.SigningKey = keyCopy
+	}
+}
+
+// WithQuarantineQueue defines the name of queue (in terms of Broker),
+// consumed tasks with invalid signature (see WithSigningKey())
+// or tasks that can't be decoded will be moved to.
+// Default is: "<queue_name>.quarantine".
+func WithQuarantineQueue(queueName string) Option {
+	return func(opts *options) {
+		opts.QuarantineQueue = queueName
+	}
+}
+
+// WithCollector registers a Collector, that will be notified about
+// the lifecycle events of Task s. See Collector for more details.
+// Passing nil disables collecting.
+func WithCollector(collector Collector) Option {
+	return func(opts *options) {
+		opts.Collector = collector
+	}
+}
+
+// WithTracer registers a Tracer, that is used to propagate a trace
+// from the publishing to the processing of Task. See Tracer for more details.
+// Passing nil disables tracing.
+func WithTracer(tracer Tracer) Option {
+	return func(opts *options) {
+		opts.Tracer = tracer
+	}
+}
+
+// WithContext associates a context.Context with the Task being published.
+// If Tracer is registered (WithTracer() option), the span from ctx
+// will be the parent of the spans of Task's publishing and processing.
+// See Task.Context() for more details.
+//
+// Makes sense only as an option of Queue.NewTask(), Queue.Publish(), etc.
+// It's ignored by New() and Bokchoy.Queue().
+func WithContext(ctx context.Context) Option {
+	return func(opts *options) {
+		opts.Context = ctx
+	}
+}
+
+// WithTaskType defines the type of the Task being published.
+// Consumers dispatch Task to the handlers, registered for its type
+// by Queue.Handle(). See Queue.Handle() for more details.
+//
+// Makes sense only as an option of Queue.NewTask(), Queue.Publish(), etc.
+// It's ignored by New() and Bokchoy.Queue().
+func WithTaskType(taskType string) Option {
+	return func(opts *options) {
+		opts.TaskType = taskType
+	}
+}
+
+// WithIdempotencyKey defines the idempotency key of the Task being published,
+// instead of its ID. See WithIdempotency() for more details.
+//
+// Makes sense only as an option of Queue.NewTask(), Queue.Publish(), etc.
+// It's ignored by New() and Bokchoy.Queue().
+func WithIdempotencyKey(key string) Option {
+	return func(opts *options) {
+		opts.IdempotencyKey = key
+	}
+}
+
 // WithCustomSerializerJSON is an alias for
 // WithSerializer(CustomSerializerJSON(example)).
 func WithCustomSerializerJSON(example interface{}) Option {
@@ -19,6 +19,8 @@
 package bokchoy
 
 import (
+	"context"
+	"database/sql"
 	"strings"
 	"time"
 
@@ -39,13 +41,35 @@ type (
 		Logger            *ekalog.Logger
 
 		Concurrency       int8
+		AutoscalingMin    int8
+		AutoscalingMax    int8 // 0 means autoscaling is disabled
+		CircuitBreakerBrokerErrors  int // 0 means Broker's errors are not counted
+		CircuitBreakerHandlerErrors int // 0 means handlers' errors are not counted
+		CircuitBreakerCoolDown      time.Duration
 		MaxRetries        int8
 		TTL               time.Duration
 		Countdown         time.Duration
 		Timeout           time.Duration
 		RetryIntervals    []time.Duration
+		Headers           map[string]string
 		Queues            []string
 		DisableOutput     bool
+		HeartbeatInterval time.Duration // 0 means worker is not registered
+		OrphanPolicy      OrphanPolicy
+		IdempotencyTTL    time.Duration // 0 means idempotency keys are not stored
+		OutboxDB          *sql.DB // nil means there is no outbox
+		OutboxTable       string
+		OutboxPlaceholder OutboxPlaceholder
+
+		SigningKey        []byte
+		QuarantineQueue   string
+
+		Collector         Collector
+		Tracer            Tracer
+
+		Context           context.Context // makes sense only for Task
+		TaskType          string          // makes sense only for Task
+		IdempotencyKey    string          // makes sense only for Task
 	}
 )
 
@@ -61,6 +85,15 @@ func (o *options) apply(options []Option) {
 	}
 }
 
+// resetTaskOptions resets the options, that make sense only for Task
+// (see WithContext(), WithTaskType(), WithIdempotencyKey()),
+// so they are ignored if they've been applied to Bokchoy or Queue.
+func (o *options) resetTaskOptions() {
+	o.Context = nil
+	o.TaskType = ""
+	o.IdempotencyKey = ""
+}
+
 func initDefaultOptions() {
 	defaultOptions = new(options)
 	defaultOptions.apply([]Option{
@@ -69,6 +102,13 @@ func initDefaultOptions() {
 		WithTTL(_DEFAULT_TTL),
 		WithTimeout(_DEFAULT_TIMEOUT),
 		WithRetryIntervals(defaultRetryIntervals),
+		WithHeartbeatInterval(_DEFAULT_HEARTBEAT_INTERVAL),
+		WithOrphanPolicy(ORPHAN_POLICY_IGNORE),
+		WithCircuitBreaker(
+			_DEFAULT_CIRCUIT_BREAKER_BROKER_ERRORS,
+			_DEFAULT_CIRCUIT_BREAKER_HANDLER_ERRORS,
+			_DEFAULT_CIRCUIT_BREAKER_COOL_DOWN,
+		),
 	})
 }
 
@@ -0,0 +1,44 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+type (
+	// OrphanPolicy defines what to do with the orphaned Task,
+	// whose worker has died while it's been processing it.
+	// See WithOrphanPolicy() for more details.
+	OrphanPolicy int8
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	ORPHAN_POLICY_INVALID OrphanPolicy = 0
+	ORPHAN_POLICY_RETRY   OrphanPolicy = 1 // requeue, counting a retry; fail if there's no retries left
+	ORPHAN_POLICY_FAIL    OrphanPolicy = 2 // mark as failed
+	ORPHAN_POLICY_IGNORE  OrphanPolicy = 3 // do not recover, tasks are not claimed
+)
+
+func (op OrphanPolicy) String() string {
+	switch op {
+	case ORPHAN_POLICY_INVALID: return "Invalid"
+	case ORPHAN_POLICY_RETRY:   return "Retry"
+	case ORPHAN_POLICY_FAIL:    return "Fail"
+	case ORPHAN_POLICY_IGNORE:  return "Ignore"
+	default:                    return "Incorrect"
+	}
+}
@@ -0,0 +1,417 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+)
+
+type (
+	// reaper recovers orphaned Task s according with their queues' policies
+	// (see WithOrphanPolicy()). Task is orphaned, if it's been claimed
+	// by the worker (see Queue.claim()), that is dead now.
+	//
+	// Claimed tasks are stored by the Broker as a pseudo-queue per worker
+	// (see Queue.claimsQueue()), so only the claims of the dead workers are read,
+	// not the whole Queue.
+	//
+	// It's created by Bokchoy.Run() along with heartbeat,
+	// its loop is run in a separate goroutine until stop() is called.
+	//
+	// Only the oldest alive worker recovers orphaned tasks,
+	// so they are not recovered twice by different workers at the same time.
+	//
+	// WARNING!
+	// It must not lock Bokchoy.sema by the same reasons as heartbeat.
+	reaper struct {
+		b        *Bokchoy
+		workerID string
+		interval time.Duration
+		queues   []*Queue // only those, whose tasks are claimed
+
+		stopChan chan struct{}
+		stopOnce sync.Once
+		doneChan chan struct{}
+	}
+)
+
+// newReaper returns a new reaper of the worker, that is registered by heartbeat,
+// or nil if heartbeat is nil (worker is not registered)
+// or there is no queue, whose orphaned tasks are recovered.
+// Caller must take responsibility about locking Bokchoy.sema.
+func newReaper(b *Bokchoy, h *heartbeat) *reaper {
+
+	if h == nil {
+		return nil
+	}
+
+	var queues []*Queue
+	for _, q := range h.queues {
+		if q.options.OrphanPolicy != ORPHAN_POLICY_IGNORE {
+			queues = append(queues, q)
+		}
+	}
+
+	if len(queues) == 0 {
+		return nil
+	}
+
+	return &reaper{
+		b:        b,
+		workerID: h.info.ID,
+		interval: h.info.HeartbeatInterval * _WORKER_HEARTBEAT_MISSES,
+		queues:   queues,
+		stopChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+}
+
+// register saves the current worker as the claimant of the tasks of its queues,
+// so the claims are found by the reaper of another worker if the current one dies.
+// Must be called before the queues are started, and it's called by reaper's loop
+// then, because the worker might be considered dead and forgotten
+// if it's missed some heartbeats (e.g. because of the Broker's errors).
+func (r *reaper) register() {
+	const s = "Bokchoy: Failed to register worker as the claimant of tasks. "
+
+	for _, q := range r.queues {
+		err := q.parent.broker.Set(q.claimsQueue(""), r.workerID, []byte(r.workerID), 0)
+		if err.IsNotNil() {
+			q.parent.logger.Copy().
+				WithString("bokchoy_queue_name", q.name).
+				Warne(s, err)
+		}
+	}
+}
+
+// run is reaper's loop. It's done when stop() is called.
+func (r *reaper) run() {
+	defer close(r.doneChan)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopChan:
+			return
+		case <-ticker.C:
+			r.register()
+			r.reap()
+		}
+	}
+}
+
+// stop stops reaper's loop and waits until it's done.
+// Then unregisters the current worker as the claimant of the tasks,
+// so it must be called after the queues are stopped.
+// It's safe to call it more than once.
+func (r *reaper) stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+		<-r.doneChan
+
+		for _, q := range r.queues {
+			_ = q.parent.broker.Delete(q.claimsQueue(""), r.workerID)
+		}
+	})
+}
+
+// reap recovers orphaned tasks of all queues,
+// if the current worker is the oldest alive one.
+func (r *reaper) reap() {
+	const s = "Bokchoy: Failed to recover orphaned tasks. "
+
+	workers, err := r.b.Workers()
+	if err.IsNotNil() {
+		r.b.logger.Warne(s, err)
+		return
+	}
+
+	var (
+		alive  = make(map[string]struct{}, len(workers))
+		leader string
+	)
+
+	// Workers are sorted by their start time.
+	for i := range workers {
+		if workers[i].IsAlive {
+			alive[workers[i].ID] = struct{}{}
+			if leader == "" {
+				leader = workers[i].ID
+			}
+		}
+	}
+
+	if leader != r.workerID {
+		return
+	}
+
+	for _, q := range r.queues {
+
+		claimants, err := q.parent.broker.List(q.claimsQueue(""))
+		if err.IsNotNil() {
+			q.parent.logger.Copy().
+				WithString("bokchoy_queue_name", q.name).
+				Warne(s, err)
+			continue
+		}
+
+		for _, claimant := range claimants {
+			if _, isAlive := alive[string(claimant)]; !isAlive {
+				q.reapWorker(string(claimant))
+			}
+		}
+	}
+}
+
+// reapWorker recovers the tasks, claimed by the dead worker with workerID.
+// The worker is forgotten as the claimant, once all its claims are processed,
+// unless it's claimed something else since then or it's alive again.
+func (q *Queue) reapWorker(workerID string) {
+	const s = "Bokchoy: Failed to recover orphaned tasks. "
+
+	logger := q.parent.logger.Copy().
+		WithString("bokchoy_queue_name", q.name).
+		WithString("bokchoy_worker_id", workerID)
+
+	claims, err := q.parent.broker.List(q.claimsQueue(workerID))
+	if err.IsNotNil() {
+		logger.Warne(s, err)
+		return
+	}
+
+	for _, claim := range claims {
+		taskID, maxRetries := decodeClaim(claim)
+		if err = q.reapTask(workerID, taskID, maxRetries); err.IsNotNil() {
+			logger.Warne(s, err)
+		}
+	}
+
+	if claims, err = q.parent.broker.List(q.claimsQueue(workerID)); err.IsNotNil() {
+		logger.Warne(s, err)
+		return
+	}
+
+	if len(claims) > 0 {
+		return
+	}
+
+	isAlive, err := q.parent.isWorkerAlive(workerID)
+	switch {
+	case err.IsNotNil():
+		logger.Warne(s, err)
+	case !isAlive:
+		if err = q.parent.broker.Delete(q.claimsQueue(""), workerID); err.IsNotNil() {
+			logger.Warne(s, err)
+		}
+	}
+}
+
+// reapTask recovers the Task with taskID, claimed by the dead worker with workerID,
+// if it's not finished yet, and removes the claim then.
+// The claim is kept if the Task can not be recovered, so it's tried again later.
+//
+// maxRetries is the Task's MaxRetries at the moment it's been claimed
+// (see decodeClaim()). If the Task is retrying and its MaxRetries is less,
+// it's been returned back to the pool by the dead worker already.
+func (q *Queue) reapTask(workerID, taskID string, maxRetries int8) *ekaerr.Error {
+	const s = "Bokchoy: Failed to recover orphaned task. "
+
+	t, err := q.Get(taskID)
+	if err.IsNotNil() {
+		return err.
+			AddMessage(s).
+			WithString("bokchoy_task_id", taskID).
+			Throw()
+	}
+
+	// Task may be expired already or processed, but not unclaimed.
+	isProcessed := t == nil ||
+		(t.status != TASK_STATUS_WAITING &&
+			t.status != TASK_STATUS_RETRYING &&
+			t.status != TASK_STATUS_PROCESSING)
+
+	isRequeued := t != nil && t.status == TASK_STATUS_RETRYING &&
+		maxRetries >= 0 && t.MaxRetries < maxRetries
+
+	if !isProcessed && !isRequeued {
+		t.workerID = workerID
+		if err = q.recoverOrphan(t); err.IsNotNil() {
+			return err.Throw()
+		}
+	}
+
+	if err = q.parent.broker.Delete(q.claimsQueue(workerID), taskID); err.IsNotNil() {
+		return err.
+			AddMessage(s + "Failed to remove the claim.").
+			WithString("bokchoy_task_id", taskID).
+			Throw()
+	}
+
+	return nil
+}
+
+// claimsQueue returns the name of the Broker's pseudo-queue, the IDs of the tasks,
+// claimed by the worker with workerID, are stored in.
+// If workerID is empty, it's the pseudo-queue of the workers, that claim tasks.
+func (q *Queue) claimsQueue(workerID string) string {
+	if workerID == "" {
+		return q.name + _CLAIMS_QUEUE_SUFFIX
+	}
+	return q.name + _CLAIMS_QUEUE_SUFFIX + "." + workerID
+}
+
+// claim marks the consumed Task as being processed by the current worker
+// and claims it, so it may be recovered if the worker dies (see reaper).
+// Does nothing if the worker is not registered (see heartbeat),
+// the Task is not claimed if its orphans are ignored (see WithOrphanPolicy()).
+func (q *Queue) claim(t *Task) {
+	const s = "Bokchoy: Failed to claim task. It won't be recovered if worker dies. "
+
+	if q.parent.heartbeat == nil {
+		return
+	}
+
+	t.workerID = q.parent.heartbeat.info.ID
+
+	if q.options.OrphanPolicy == ORPHAN_POLICY_IGNORE || q.parent.reaper == nil {
+		return
+	}
+
+	if err := q.parent.broker.Set(q.claimsQueue(t.workerID), t.id, encodeClaim(t), 0); err.IsNotNil() {
+		q.parent.logger.Copy().
+			WithString("bokchoy_queue_name", q.name).
+			WithString("bokchoy_task_id", t.id).
+			Warne(s, err)
+	}
+}
+
+// encodeClaim returns the claim of the Task, that is stored by claim():
+// the Task's ID and its MaxRetries, separated by space.
+func encodeClaim(t *Task) []byte {
+	return []byte(t.id + " " + strconv.Itoa(int(t.MaxRetries)))
+}
+
+// decodeClaim returns the Task's ID and its MaxRetries from the claim,
+// that is encoded by encodeClaim(). MaxRetries is -1 if the claim
+// has no it (e.g. it's been stored by the older version).
+func decodeClaim(claim []byte) (taskID string, maxRetries int8) {
+
+	taskID, encodedMaxRetries, ok := strings.Cut(string(claim), " ")
+	if !ok {
+		return taskID, -1
+	}
+
+	n, legacyErr := strconv.ParseInt(encodedMaxRetries, 10, 8)
+	if legacyErr != nil {
+		return taskID, -1
+	}
+
+	return taskID, int8(n)
+}
+
+// isWorkerAlive reports whether the worker with workerID is alive (see Workers()).
+func (b *Bokchoy) isWorkerAlive(workerID string) (bool, *ekaerr.Error) {
+
+	workers, err := b.Workers()
+	if err.IsNotNil() {
+		return false, err.Throw()
+	}
+
+	for i := range workers {
+		if workers[i].ID == workerID {
+			return workers[i].IsAlive, nil
+		}
+	}
+
+	return false, nil
+}
+
+// unclaim removes the claim of the processed Task, made by claim().
+func (q *Queue) unclaim(t *Task) {
+	const s = "Bokchoy: Failed to unclaim processed task. "
+
+	if q.options.OrphanPolicy == ORPHAN_POLICY_IGNORE || q.parent.reaper == nil {
+		return
+	}
+
+	workerID := q.parent.reaper.workerID
+	if err := q.parent.broker.Delete(q.claimsQueue(workerID), t.id); err.IsNotNil() {
+		q.parent.logger.Copy().
+			WithString("bokchoy_queue_name", q.name).
+			WithString("bokchoy_task_id", t.id).
+			Warne(s, err)
+	}
+}
+
+// recoverOrphan requeues (counting a retry) or fails the orphaned Task
+// according with WithOrphanPolicy().
+func (q *Queue) recoverOrphan(t *Task) *ekaerr.Error {
+	const s = "Bokchoy: Failed to recover orphaned task. "
+
+	t.Error = ekaerr.Interrupted.
+		New("Worker has died while it's been processing task.").
+		WithString("bokchoy_worker_id", t.workerID).
+		Throw()
+
+	t.workerID = ""
+
+	var err *ekaerr.Error
+
+	if q.options.OrphanPolicy == ORPHAN_POLICY_RETRY && t.MaxRetries > 0 {
+		t.markAsRetrying()
+
+		// FIRST: calculating next ETA, THEN: decreasing MaxRetries.
+		// The same as consumer does. See consumer.processTask().
+
+		t.ETA = t.nextETA()
+		t.MaxRetries--
+
+		t.enqueuedAt = time.Now().UnixNano()
+
+		err = q.PublishTask(t)
+
+	} else {
+		t.markAsFailed()
+		err = q.save(t)
+	}
+
+	if err.IsNotNil() {
+		return err.AddMessage(s).Throw()
+	}
+
+	q.parent.logger.Copy().
+		WithString("bokchoy_queue_name", q.name).
+		WithString("bokchoy_task_id", t.id).
+		WithStringer("bokchoy_task_status", t.status).
+		Warn("Bokchoy: Orphaned task has been recovered.")
+
+	if q.options.Collector != nil {
+		q.options.Collector.TaskProcessed(t)
+	}
+	q.parent.events.emitTaskProcessed(q.name, t)
+
+	return nil
+}
@@ -0,0 +1,120 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+)
+
+type (
+	// OutboxPlaceholder is a style of bind parameters' placeholders
+	// in SQL queries of the outbox, that is supported by the database driver.
+	// See WithOutbox() for more details.
+	OutboxPlaceholder int8
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	OUTBOX_PLACEHOLDER_INVALID  OutboxPlaceholder = 0
+	OUTBOX_PLACEHOLDER_QUESTION OutboxPlaceholder = 1 // ?, e.g. MySQL, SQLite
+	OUTBOX_PLACEHOLDER_DOLLAR   OutboxPlaceholder = 2 // $1, e.g. PostgreSQL
+)
+
+func (op OutboxPlaceholder) String() string {
+	switch op {
+	case OUTBOX_PLACEHOLDER_INVALID:  return "Invalid"
+	case OUTBOX_PLACEHOLDER_QUESTION: return "Question"
+	case OUTBOX_PLACEHOLDER_DOLLAR:   return "Dollar"
+	default:                          return "Incorrect"
+	}
+}
+
+// PublishTx writes a new task to the outbox (see WithOutbox()) inside
+// the caller's transaction, instead of publishing it to the Broker.
+//
+// The running Bokchoy relays tasks from the outbox to the Broker
+// using PublishTask(), once the transaction is committed.
+// Thus the task is never published if the transaction is rolled back:
+//
+//     tx, _ := db.Begin()
+//     _, _ = tx.Exec("INSERT INTO users (name) VALUES (?)", name)
+//     _ = queue.PublishTx(tx, queue.NewTask(name))
+//     _ = tx.Commit()
+//
+// Task is published at least once: if the relay fails to delete
+// the published task from the outbox, it will be published again.
+//
+// Returns an error if Bokchoy has no outbox, see WithOutbox().
+func (q *Queue) PublishTx(tx *sql.Tx, task *Task) *ekaerr.Error {
+	const s = "Bokchoy: Failed to publish task to the outbox. "
+
+	switch {
+
+	case !q.isValid():
+		return ekaerr.IllegalArgument.
+			New(s + "Queue is invalid. Has it been initialized correctly?").
+			WithString("bokchoy_queue_why_invalid", q.whyInvalid()).
+			Throw()
+
+	case tx == nil:
+		return ekaerr.IllegalArgument.
+			New(s + "Transaction is nil.").
+			WithString("bokchoy_queue_name", q.name).
+			Throw()
+
+	case q.parent.defaultOptions.OutboxDB == nil:
+		return ekaerr.UnsupportedOperation.
+			New(s + "Outbox is not configured. Use WithOutbox() option.").
+			WithString("bokchoy_queue_name", q.name).
+			Throw()
+	}
+
+	// No need to check task,
+	// because task.Serialize (under q.encodeTask) already has all checks.
+
+	// Span context must be injected before encoding.
+	// The relay continues the trace, see outboxRelay.relayTask().
+	span := q.tracePublishing(task)
+
+	encodedTask, err := q.encodeTask(task)
+	if err.IsNotNil() {
+		err.AddMessage(s).WithString("bokchoy_queue_name", q.name)
+		traceEnd(span, err, false, "")
+		return err.Throw()
+	}
+
+	query := outboxQuery(_OUTBOX_QUERY_INSERT, q.parent.defaultOptions)
+	_, legacyErr := tx.Exec(query, task.id, q.name, encodedTask, time.Now().UnixNano())
+
+	if legacyErr != nil {
+		err = ekaerr.ExternalError.
+			Wrap(legacyErr, s + "Failed to insert task into the outbox table.").
+			WithString("bokchoy_queue_name", q.name).
+			WithString("bokchoy_task_id", task.id).
+			WithString("bokchoy_outbox_table", q.parent.defaultOptions.OutboxTable)
+		traceEnd(span, err, false, "")
+		return err.Throw()
+	}
+
+	traceEnd(span, nil, true, "")
+	return nil
+}
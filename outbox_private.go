@@ -0,0 +1,309 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+)
+
+type (
+	// outboxRelay moves tasks from the outbox table (see WithOutbox())
+	// to the Broker, publishing them using Queue.PublishTask().
+	//
+	// It's created by Bokchoy.Run() if the outbox is configured,
+	// its loop is run in a separate goroutine until stop() is called.
+	//
+	// Many workers may relay the same outbox at the same time.
+	// The task is deleted from the outbox in the same transaction it's published in,
+	// so only one of them will publish it (the others will wait for the row lock
+	// and delete nothing then).
+	//
+	// WARNING!
+	// It must not lock Bokchoy.sema by the same reasons as heartbeat.
+	outboxRelay struct {
+		b        *Bokchoy
+		db       *sql.DB
+		queues   []*Queue
+
+		stopChan chan struct{}
+		stopOnce sync.Once
+		doneChan chan struct{}
+	}
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	// _OUTBOX_RELAY_INTERVAL is how often the outbox relay checks outbox table.
+	_OUTBOX_RELAY_INTERVAL = 1 * time.Second
+
+	// _OUTBOX_RELAY_BATCH_SIZE is how many tasks of one queue
+	// are read from the outbox table at once.
+	_OUTBOX_RELAY_BATCH_SIZE = 100
+
+	// Queries of the outbox. Table's name and limit are substituted by outboxQuery().
+
+	_OUTBOX_QUERY_INSERT =
+		"INSERT INTO %table% (task_id, queue_name, task, created_at) VALUES (?, ?, ?, ?)"
+	_OUTBOX_QUERY_SELECT =
+		"SELECT task_id, task FROM %table% WHERE queue_name = ? ORDER BY created_at LIMIT %limit%"
+	_OUTBOX_QUERY_DELETE =
+		"DELETE FROM %table% WHERE task_id = ?"
+)
+
+// outboxQuery returns the query of the outbox with substituted table's name,
+// limit and the placeholders of the database driver (see WithOutbox()).
+func outboxQuery(query string, opts *options) string {
+
+	query = strings.Replace(query, "%table%", opts.OutboxTable, 1)
+	query = strings.Replace(query, "%limit%", strconv.Itoa(_OUTBOX_RELAY_BATCH_SIZE), 1)
+
+	if opts.OutboxPlaceholder != OUTBOX_PLACEHOLDER_DOLLAR {
+		return query
+	}
+
+	var (
+		sb strings.Builder
+		n  = 0
+	)
+
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String()
+}
+
+// newOutboxRelay returns a new outbox relay of the Bokchoy,
+// or nil if the outbox is not configured (see WithOutbox()).
+// Caller must take responsibility about locking Bokchoy.sema.
+func newOutboxRelay(b *Bokchoy) *outboxRelay {
+
+	if b.defaultOptions.OutboxDB == nil {
+		return nil
+	}
+
+	return &outboxRelay{
+		b:        b,
+		db:       b.defaultOptions.OutboxDB,
+		queues:   b.sortedQueues(),
+		stopChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+}
+
+// run is outbox relay's loop. It's done when stop() is called.
+func (r *outboxRelay) run() {
+	defer close(r.doneChan)
+
+	ticker := time.NewTicker(_OUTBOX_RELAY_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopChan:
+			return
+		case <-ticker.C:
+			r.relay()
+		}
+	}
+}
+
+// stop stops outbox relay's loop and waits until it's done.
+// It's safe to call it more than once.
+func (r *outboxRelay) stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+		<-r.doneChan
+	})
+}
+
+// isStopped reports whether stop() has been called.
+func (r *outboxRelay) isStopped() bool {
+	select {
+	case <-r.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// relay publishes all tasks of all queues, that are in the outbox now.
+func (r *outboxRelay) relay() {
+	const s = "Bokchoy: Failed to relay tasks from the outbox. "
+
+	for _, q := range r.queues {
+		for !r.isStopped() {
+
+			relayed, err := r.relayQueue(q)
+			if err.IsNotNil() {
+				q.parent.logger.Copy().
+					WithString("bokchoy_queue_name", q.name).
+					Warne(s, err)
+			}
+
+			// Maybe there are more tasks?
+			// But not if some of them can't be relayed now, they'll be read again.
+			if err.IsNotNil() || relayed < _OUTBOX_RELAY_BATCH_SIZE {
+				break
+			}
+		}
+	}
+}
+
+// relayQueue publishes the oldest tasks of the queue, that are in the outbox,
+// returning the number of the tasks that have been relayed (removed from the outbox).
+func (r *outboxRelay) relayQueue(q *Queue) (int, *ekaerr.Error) {
+	const s = "Bokchoy: Failed to read tasks from the outbox. "
+
+	rows, legacyErr := r.db.Query(outboxQuery(_OUTBOX_QUERY_SELECT, q.parent.defaultOptions), q.name)
+	if legacyErr != nil {
+		return 0, ekaerr.ExternalError.
+			Wrap(legacyErr, s).
+			WithString("bokchoy_outbox_table", q.parent.defaultOptions.OutboxTable).
+			Throw()
+	}
+
+	type outboxRow struct {
+		taskID      string
+		encodedTask []byte
+	}
+
+	var outboxRows []outboxRow
+
+	for rows.Next() {
+		var row outboxRow
+		if legacyErr = rows.Scan(&row.taskID, &row.encodedTask); legacyErr != nil {
+			break
+		}
+		outboxRows = append(outboxRows, row)
+	}
+
+	if legacyErr == nil {
+		legacyErr = rows.Err()
+	}
+	_ = rows.Close()
+
+	if legacyErr != nil {
+		return 0, ekaerr.ExternalError.
+			Wrap(legacyErr, s).
+			WithString("bokchoy_outbox_table", q.parent.defaultOptions.OutboxTable).
+			Throw()
+	}
+
+	relayed := 0
+
+	for i := range outboxRows {
+		err := r.relayTask(q, outboxRows[i].taskID, outboxRows[i].encodedTask)
+		if err.IsNotNil() {
+			q.parent.logger.Copy().
+				WithString("bokchoy_queue_name", q.name).
+				WithString("bokchoy_task_id", outboxRows[i].taskID).
+				Warne("Bokchoy: Failed to relay task from the outbox. ", err)
+			continue
+		}
+		relayed++
+	}
+
+	return relayed, nil
+}
+
+// relayTask deletes the task from the outbox and publishes it
+// in the same transaction. Does nothing if the task has been deleted
+// by another relay. Task is kept in the outbox if it can't be published.
+//
+// Task, that can't be decoded, would block the outbox forever.
+// So, it's quarantined (see Queue.quarantine()) and deleted from the outbox.
+func (r *outboxRelay) relayTask(q *Queue, taskID string, encodedTask []byte) *ekaerr.Error {
+	const s = "Bokchoy: Failed to relay task from the outbox. "
+
+	tx, legacyErr := r.db.Begin()
+	if legacyErr != nil {
+		return ekaerr.ExternalError.
+			Wrap(legacyErr, s + "Failed to begin transaction.").
+			Throw()
+	}
+
+	// Rollback does nothing if transaction is committed.
+	defer func() { _ = tx.Rollback() }()
+
+	res, legacyErr := tx.Exec(outboxQuery(_OUTBOX_QUERY_DELETE, q.parent.defaultOptions), taskID)
+	if legacyErr != nil {
+		return ekaerr.ExternalError.
+			Wrap(legacyErr, s + "Failed to delete task from the outbox.").
+			Throw()
+	}
+
+	if deleted, legacyErr := res.RowsAffected(); legacyErr == nil && deleted == 0 {
+		return nil // relayed by someone else
+	}
+
+	var task Task
+	if _, err := q.decodeTask(encodedTask, &task); err.IsNotNil() {
+
+		q.parent.logger.Copy().
+			WithString("bokchoy_queue_name", q.name).
+			WithString("bokchoy_task_id", taskID).
+			Warne(s + "Task can't be decoded. Quarantining and deleting it from the outbox.", err)
+
+		if !q.quarantine(encodedTask) {
+			return ekaerr.ExternalError.
+				New(s + "Failed to quarantine task that can't be decoded.").
+				Throw()
+		}
+
+		return r.commit(tx)
+	}
+
+	// Continue the trace, started by Queue.PublishTx().
+	if q.options.Tracer != nil {
+		task.ctx = q.options.Tracer.Extract(task.Context(), task.traceContext)
+	}
+
+	if err := q.PublishTask(&task); err.IsNotNil() {
+		return err.AddMessage(s).Throw()
+	}
+
+	return r.commit(tx).
+		AddMessage("Task is published, but it's kept in the outbox and will be published again.").
+		Throw()
+}
+
+// commit commits the transaction of relayTask().
+func (r *outboxRelay) commit(tx *sql.Tx) *ekaerr.Error {
+	const s = "Bokchoy: Failed to relay task from the outbox. "
+
+	if legacyErr := tx.Commit(); legacyErr != nil {
+		return ekaerr.ExternalError.
+			Wrap(legacyErr, s + "Failed to commit transaction.").
+			Throw()
+	}
+
+	return nil
+}
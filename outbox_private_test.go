@@ -0,0 +1,38 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxQuery(t *testing.T) {
+
+	opts := &options{OutboxTable: "outbox", OutboxPlaceholder: OUTBOX_PLACEHOLDER_DOLLAR}
+	require.Equal(t,
+		"INSERT INTO outbox (task_id, queue_name, task, created_at) VALUES ($1, $2, $3, $4)",
+		outboxQuery(_OUTBOX_QUERY_INSERT, opts))
+
+	opts.OutboxPlaceholder = OUTBOX_PLACEHOLDER_QUESTION
+	require.Equal(t,
+		"SELECT task_id, task FROM outbox WHERE queue_name = ? ORDER BY created_at LIMIT 100",
+		outboxQuery(_OUTBOX_QUERY_SELECT, opts))
+}
@@ -0,0 +1,261 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+	"github.com/qioalice/bokchoy/internal/brokertest"
+
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	// memoryOutbox is a database/sql connector of the in-memory outbox table,
+	// that supports only outbox's queries (see outboxQuery()).
+	// Task written inside a transaction is visible only once it's committed.
+	memoryOutbox struct {
+		mu   sync.Mutex
+		rows map[string]memoryOutboxRow // by task's ID
+	}
+
+	memoryOutboxRow struct {
+		queueName string
+		task      []byte
+		createdAt int64
+	}
+
+	memoryOutboxConn struct {
+		outbox   *memoryOutbox
+		inserted map[string]memoryOutboxRow // nil if there is no transaction
+		deleted  []string
+	}
+
+	memoryOutboxStmt struct {
+		conn  *memoryOutboxConn
+		query string
+	}
+
+	memoryOutboxRows struct {
+		values [][]driver.Value
+	}
+)
+
+func newMemoryOutbox() *memoryOutbox {
+	return &memoryOutbox{rows: make(map[string]memoryOutboxRow)}
+}
+
+func (o *memoryOutbox) Connect(_ context.Context) (driver.Conn, error) {
+	return &memoryOutboxConn{outbox: o}, nil
+}
+
+func (o *memoryOutbox) Driver() driver.Driver {
+	return nil
+}
+
+func (o *memoryOutbox) len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.rows)
+}
+
+func (c *memoryOutboxConn) Prepare(query string) (driver.Stmt, error) {
+	return &memoryOutboxStmt{conn: c, query: query}, nil
+}
+
+func (c *memoryOutboxConn) Close() error {
+	return nil
+}
+
+func (c *memoryOutboxConn) Begin() (driver.Tx, error) {
+	c.inserted, c.deleted = make(map[string]memoryOutboxRow), nil
+	return c, nil
+}
+
+func (c *memoryOutboxConn) Commit() error {
+	c.outbox.mu.Lock()
+	defer c.outbox.mu.Unlock()
+
+	for taskID, row := range c.inserted {
+		c.outbox.rows[taskID] = row
+	}
+	for _, taskID := range c.deleted {
+		delete(c.outbox.rows, taskID)
+	}
+
+	c.inserted, c.deleted = nil, nil
+	return nil
+}
+
+func (c *memoryOutboxConn) Rollback() error {
+	c.inserted, c.deleted = nil, nil
+	return nil
+}
+
+func (s *memoryOutboxStmt) Close() error {
+	return nil
+}
+
+func (s *memoryOutboxStmt) NumInput() int {
+	return -1
+}
+
+func (s *memoryOutboxStmt) Exec(args []driver.Value) (driver.Result, error) {
+
+	if s.conn.inserted == nil {
+		return nil, errors.New("outbox is changed outside transaction")
+	}
+
+	switch {
+	case strings.HasPrefix(s.query, "INSERT"):
+		s.conn.inserted[args[0].(string)] = memoryOutboxRow{
+			queueName: args[1].(string),
+			task:      args[2].([]byte),
+			createdAt: args[3].(int64),
+		}
+		return driver.RowsAffected(1), nil
+
+	case strings.HasPrefix(s.query, "DELETE"):
+		s.conn.outbox.mu.Lock()
+		_, exists := s.conn.outbox.rows[args[0].(string)]
+		s.conn.outbox.mu.Unlock()
+
+		if !exists {
+			return driver.RowsAffected(0), nil
+		}
+
+		s.conn.deleted = append(s.conn.deleted, args[0].(string))
+		return driver.RowsAffected(1), nil
+	}
+
+	return nil, errors.New("unexpected query: " + s.query)
+}
+
+func (s *memoryOutboxStmt) Query(args []driver.Value) (driver.Rows, error) {
+
+	if !strings.HasPrefix(s.query, "SELECT") {
+		return nil, errors.New("unexpected query: " + s.query)
+	}
+
+	s.conn.outbox.mu.Lock()
+	defer s.conn.outbox.mu.Unlock()
+
+	var taskIDs []string
+	for taskID, row := range s.conn.outbox.rows {
+		if row.queueName == args[0].(string) {
+			taskIDs = append(taskIDs, taskID)
+		}
+	}
+
+	sort.Slice(taskIDs, func(i, j int) bool {
+		return s.conn.outbox.rows[taskIDs[i]].createdAt < s.conn.outbox.rows[taskIDs[j]].createdAt
+	})
+
+	values := new(memoryOutboxRows)
+	for _, taskID := range taskIDs {
+		values.values = append(values.values, []driver.Value{taskID, s.conn.outbox.rows[taskID].task})
+	}
+
+	return values, nil
+}
+
+func (r *memoryOutboxRows) Columns() []string {
+	return []string{"task_id", "task"}
+}
+
+func (r *memoryOutboxRows) Close() error {
+	return nil
+}
+
+func (r *memoryOutboxRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestOutbox(t *testing.T) {
+
+	var (
+		q      *bokchoy.Queue
+		wait   func() *bokchoy.Task
+		broker = brokertest.NewMemoryBroker()
+		outbox = newMemoryOutbox()
+		db     = sql.OpenDB(outbox)
+	)
+
+	defer db.Close()
+
+	// Task that can't be decoded must not block the outbox.
+	outbox.rows["invalid"] = memoryOutboxRow{queueName: "tasks.test", task: []byte("invalid")}
+
+	_, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		q = b.Queue("tasks.test").Use(func(_ *bokchoy.Task) *ekaerr.Error {
+			return nil
+		})
+		wait = waitTask(t, q)
+	}, bokchoy.WithBroker(broker), bokchoy.WithOutbox(db, "", bokchoy.OUTBOX_PLACEHOLDER_QUESTION))
+	defer stop()
+
+	publishTx := func(data string, commit bool) *bokchoy.Task {
+		tx, legacyErr := db.Begin()
+		require.NoError(t, legacyErr)
+
+		task := q.NewTask(testTaskPayload{Data: data})
+		require.True(t, q.PublishTx(tx, task).IsNil())
+
+		if commit {
+			require.NoError(t, tx.Commit())
+		} else {
+			require.NoError(t, tx.Rollback())
+		}
+		return task
+	}
+
+	rolledBack := publishTx("rolled back", false)
+	committed := publishTx("committed", true)
+
+	task := wait()
+	require.Equal(t, committed.ID(), task.ID())
+	require.Equal(t, bokchoy.TASK_STATUS_SUCCEEDED, task.Status())
+
+	require.Zero(t, outbox.len())
+
+	quarantined, err := broker.List("tasks.test.quarantine")
+	require.True(t, err.IsNil())
+	require.Equal(t, [][]byte{[]byte("invalid")}, quarantined)
+
+	// The rolled back task has never been in the outbox.
+	stored, err := q.Get(rolledBack.ID())
+	require.True(t, err.IsNil())
+	require.Nil(t, stored)
+}
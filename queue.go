@@ -21,6 +21,8 @@ package bokchoy
 import (
 	"reflect"
 	"sync"
+	"sync/atomic"
+	"time"
 	"unsafe"
 
 	"github.com/qioalice/ekago/v3/ekaerr"
@@ -32,36 +34,49 @@ type (
 	// Queue contains consumers to enqueue.
 	Queue struct {
 
-		// WARNING!
-		// DO NOT CHANGE THE ORDER OF FIELDS!
-		// https://golang.org/pkg/sync/atomic/#pkg-note-BUG :
-		//
-		//   > On ARM, x86-32, and 32-bit MIPS,
-		//   > it is the caller's responsibility to arrange for 64-bit alignment
-		//   > of 64-bit words accessed atomically.
-		//   > The first word in a variable or in an allocated struct, array,
-		//   > or slice can be relied upon to be 64-bit aligned.
-		//
-		// Also:
-		// https://stackoverflow.com/questions/28670232/atomic-addint64-causes-invalid-memory-address-or-nil-pointer-dereference/51012703#51012703
-
-		errCounter     int32 // must be aligned, protected by atomic operations
-
 		parent         *Bokchoy
 
 		options        *options
 
 		name           string  // set by Bokchoy.Queue(), immutable
 
-		consumers      []consumer
+		consumers      []*consumer    // protected by consumersMu, see consumersSnapshot()
+		consumersMu    sync.RWMutex
+		isConsuming    bool           // protected by consumersMu, see start(), stop()
+		concurrency    int8           // protected by consumersMu, 0 means options.Concurrency
+		autoscaler     *autoscaler    // nil if autoscaling is disabled, see WithAutoscaling()
+		breaker        *circuitBreaker // see WithCircuitBreaker()
 		wg             *sync.WaitGroup
 
 		handlers       []HandlerFunc
+		typedHandlers  map[string][]HandlerFunc // by Task's type, see Handle()
+
+		typedSerializers   map[string]Serializer // by Task's type, see Register()
+		typedSerializersMu sync.RWMutex
+		unknownTypeHandlers []HandlerFunc       // see HandleUnknown()
+		middlewares    []Middleware
+		chain          HandlerFunc // built at the start(), see buildChain()
+
+		batchHandler   BatchHandlerFunc // see HandleBatch()
+		batchMaxSize   int
+		batchMaxWait   time.Duration
 
 		onFailure      []HandlerFunc
 		onSuccess      []HandlerFunc
 		onComplete     []HandlerFunc
 		onStart        []HandlerFunc
+
+		processing     map[string]*taskCancellation // by Task's ID, see trackProcessing()
+		processingMu   sync.Mutex
+
+		unsubscribeCancellation func() // see subscribeCancellation()
+	}
+
+	// ConsumersStats is the statistics of Queue's consumers.
+	ConsumersStats struct {
+		Total  int
+		Active int
+		Frozen int
 	}
 )
 
@@ -73,29 +88,128 @@ func (q *Queue) Name() string {
 	return q.name
 }
 
-// Use appends a new handler middleware to the queue.
-func (q *Queue) Use(callback ...HandlerFunc) *Queue {
-	const s = "Bokchoy: Failed to register middleware for consuming queue. "
+// ConsumersStats returns the statistics of Queue's consumers:
+// * total: number of consumers (0 if Queue has never been started)
+// * active: number of consumers that are consuming tasks
+// * frozen: number of consumers that are frozen by the circuit breaker
+func (q *Queue) ConsumersStats() ConsumersStats {
+
+	var stats ConsumersStats
 
 	if !q.isValid() {
-		return nil
+		return stats
 	}
 
-	if len(callback) > 0 {
-		// Filter handlers. Remain only not-nil.
-		handlersBeingRegistered := make([]HandlerFunc, 0, len(callback))
-		for _, handlerBeingRegistered := range callback {
-			if handlerBeingRegistered != nil {
-				handlersBeingRegistered =
-					append(handlersBeingRegistered, handlerBeingRegistered)
-			}
+	consumers := q.consumersSnapshot()
+
+	stats.Total = len(consumers)
+	for i := range consumers {
+		switch atomic.LoadInt32(&consumers[i].status) {
+		case _CONSUMER_STATUS_ACTIVE: stats.Active++
+		case _CONSUMER_STATUS_FROZEN: stats.Frozen++
 		}
-		callback = handlersBeingRegistered
 	}
 
-	// Now 'callback' contains only not nil handlers.
+	return stats
+}
+
+// Concurrency returns the number of the queue's consumers,
+// or the number of consumers, that will be started, if Bokchoy is not running.
+// See SetConcurrency().
+func (q *Queue) Concurrency() int8 {
+
+	if !q.isValid() {
+		return 0
+	}
+
+	q.consumersMu.RLock()
+	defer q.consumersMu.RUnlock()
+
+	if q.isConsuming {
+		return int8(len(q.consumers))
+	}
+
+	return q.concurrencyOrDefault()
+}
+
+// SetConcurrency changes the number of the queue's consumers (see WithConcurrency()).
+//
+// It may be called while Bokchoy is running. New consumers are started immediately,
+// the retired ones stop once they've processed the tasks they already consumed.
+// n less than 1 is considered as 1.
+//
+// If autoscaling is enabled (see WithAutoscaling()), n is clamped by its bounds,
+// and autoscaler will change it later according with the queue's backlog.
+func (q *Queue) SetConcurrency(n int8) *Queue {
+
+	if !q.isValid() {
+		return nil
+	}
+
+	q.consumersMu.Lock()
+	defer q.consumersMu.Unlock()
+
+	q.setConcurrency(n)
+	return q
+}
+
+// Serializer returns a Serializer of Task's payload, the queue uses.
+func (q *Queue) Serializer() Serializer {
+	if !q.isValid() {
+		return nil
+	}
+	return q.options.Serializer
+}
+
+// SerializerOf returns a Serializer of payloads of tasks of the given type.
+// It's the one, that is registered for that type by Register() or PublishTyped(),
+// or the queue's one (see Serializer()) otherwise.
+func (q *Queue) SerializerOf(taskType string) Serializer {
+
+	if !q.isValid() {
+		return nil
+	}
+
+	if serializer, ok := q.typedSerializerOf(taskType); ok {
+		return serializer
+	}
+
+	return q.options.Serializer
+}
+
+// SerializerOfTask returns a Serializer of the task's payload.
+// It's the same as SerializerOf(task.Type()), but if the task's payload
+// has been encoded by Register() or PublishTyped() in another process,
+// and its type is not registered in the current one,
+// it's DefaultSerializerJSON(), thus the payload is decoded as JSON
+// of unknown structure instead of being decoded by the queue's Serializer.
+func (q *Queue) SerializerOfTask(task *Task) Serializer {
+
+	if !q.isValid() || !task.isValid() {
+		return nil
+	}
+
+	if serializer, ok := q.typedSerializerOf(task.taskType); ok {
+		return serializer
+	}
 
-	if len(callback) == 0 {
+	if task.isPayloadTyped {
+		return DefaultSerializerJSON()
+	}
+
+	return q.options.Serializer
+}
+
+// Use appends a new handler middleware to the queue.
+func (q *Queue) Use(callback ...HandlerFunc) *Queue {
+	const s = "Bokchoy: Failed to register middleware for consuming queue. "
+
+	if !q.isValid() {
+		return nil
+	}
+
+	// Remain only not-nil handlers.
+	if callback = filterHandlers(callback); len(callback) == 0 {
 		return q
 	}
 
@@ -129,6 +243,189 @@ func (q *Queue) Use(callback ...HandlerFunc) *Queue {
 	return q
 }
 
+// Handle appends a new handlers to the queue, that will be called
+// only for tasks of the given type (see WithTaskType() option),
+// instead of the handlers registered by Use().
+//
+//     queue.Handle("email", sendEmail).Handle("sms", sendSMS)
+//     queue.Publish(payload, bokchoy.WithTaskType("email"))
+//
+// Handlers registered by Use() are called only for tasks without type then.
+// Tasks of type, that has no handlers, are handled by the handlers
+// registered by HandleUnknown(). If there are none, such tasks are failed
+// (and retried as usual, so the consumers that know the type may handle them).
+//
+// Middlewares (see Wrap()) wrap the handlers of all types.
+// Does nothing if Bokchoy already running (Run() has called)
+// or taskType is empty (IllegalArgument error is logged).
+func (q *Queue) Handle(taskType string, handlers ...HandlerFunc) *Queue {
+	const s = "Bokchoy: Failed to register typed handler for consuming queue. "
+
+	if !q.isValid() {
+		return nil
+	}
+
+	if handlers = filterHandlers(handlers); len(handlers) == 0 {
+		return q
+	}
+
+	// Tasks without type are handled by the handlers registered by Use(),
+	// so the handlers of empty type would never be called.
+	if taskType == "" {
+		q.parent.logger.Copy().
+			WithString("bokchoy_queue_name", q.name).
+			Warne(s, ekaerr.IllegalArgument.
+				New("Task type is empty. Use Use() to handle tasks without type.").
+				Throw())
+		return q
+	}
+
+	q.parent.sema.Lock()
+	defer q.parent.sema.Unlock()
+
+	if q.parent.isStarted {
+		q.parent.logger.Copy().
+			WithString("bokchoy_queue_name", q.name).
+			WithString("bokchoy_task_type", taskType).
+			Warnw(s + "Consumers already running.")
+		return q
+	}
+
+	if q.typedHandlers == nil {
+		q.typedHandlers = make(map[string][]HandlerFunc)
+	}
+
+	q.typedHandlers[taskType] = append(q.typedHandlers[taskType], handlers...)
+	return q
+}
+
+// HandleUnknown appends a new fallback handlers to the queue, that will be called
+// for tasks of type, that has no handlers registered by Handle().
+// See Handle() for more details.
+//
+// Does nothing if Bokchoy already running (Run() has called).
+func (q *Queue) HandleUnknown(handlers ...HandlerFunc) *Queue {
+	const s = "Bokchoy: Failed to register unknown type handler for consuming queue. "
+
+	if !q.isValid() {
+		return nil
+	}
+
+	if handlers = filterHandlers(handlers); len(handlers) == 0 {
+		return q
+	}
+
+	q.parent.sema.Lock()
+	defer q.parent.sema.Unlock()
+
+	if q.parent.isStarted {
+		q.parent.logger.Copy().
+			WithString("bokchoy_queue_name", q.name).
+			Warnw(s + "Consumers already running.")
+		return q
+	}
+
+	q.unknownTypeHandlers = append(q.unknownTypeHandlers, handlers...)
+	return q
+}
+
+// HandleBatch sets the handler, that will be called for the batches of tasks
+// instead of the handlers registered by Use(), Handle() or HandleUnknown().
+// It's useful for the workloads that are far cheaper in batches (e.g. bulk inserts).
+//
+// Each consumer accumulates consumed tasks until there are maxSize of them,
+// or maxWait is passed since the first one has been received (as soon as
+// Broker.Consume() returns), and calls handler for them then.
+// maxWait <= 0 means that tasks are never waited for, so the batch is
+// what Broker.Consume() has returned (but not more than maxSize tasks).
+//
+//     queue.HandleBatch(100, time.Second, func(tasks []*bokchoy.Task) *ekaerr.Error {
+//         for _, task := range tasks {
+//             if err := insert(task.Payload); err != nil {
+//                 task.Error = ekaerr.ExternalError.Wrap(err, "Failed to insert.")
+//             }
+//         }
+//         return nil
+//     })
+//
+// Each task of the batch is reported (and retried if allowed) independently,
+// according with its Task.Error (see BatchHandlerFunc).
+// Task status changed callbacks (OnStart(), OnSuccess(), etc) are called
+// for each task as usual, but middlewares (see Wrap()) and Task.Timeout
+// are not applied to batch handler.
+//
+// Does nothing if Bokchoy already running (Run() has called).
+func (q *Queue) HandleBatch(maxSize int, maxWait time.Duration, handler BatchHandlerFunc) *Queue {
+	const s = "Bokchoy: Failed to register batch handler for consuming queue. "
+
+	if !q.isValid() {
+		return nil
+	}
+
+	if handler == nil {
+		return q
+	}
+
+	q.parent.sema.Lock()
+	defer q.parent.sema.Unlock()
+
+	if q.parent.isStarted {
+		q.parent.logger.Copy().
+			WithString("bokchoy_queue_name", q.name).
+			Warnw(s + "Consumers already running.")
+		return q
+	}
+
+	if maxSize < 1 {
+		maxSize = 1
+	}
+	if maxWait < 0 {
+		maxWait = 0
+	}
+
+	q.batchHandler = handler
+	q.batchMaxSize = maxSize
+	q.batchMaxWait = maxWait
+	return q
+}
+
+// Wrap appends a new around-style middlewares to the queue.
+//
+// Unlike handlers (see Use()), that are called one after another,
+// middlewares wrap the whole handlers list.
+// The first registered middleware is the outermost one:
+//
+//     queue.Use(h1, h2).Wrap(m1, m2)
+//
+// leads to m1(m2(h1 -> h2)) call chain at the each Task processing.
+//
+// Does nothing if Bokchoy already running (Run() has called).
+func (q *Queue) Wrap(middlewares ...Middleware) *Queue {
+	const s = "Bokchoy: Failed to register around-style middleware for consuming queue. "
+
+	if !q.isValid() {
+		return nil
+	}
+
+	q.parent.sema.Lock()
+	defer q.parent.sema.Unlock()
+
+	if q.parent.isStarted {
+		q.parent.logger.Copy().
+			WithString("bokchoy_queue_name", q.name).
+			Warnw(s + "Consumers already running.")
+		return q
+	}
+
+	for _, middleware := range middlewares {
+		if middleware != nil {
+			q.middlewares = append(q.middlewares, middleware)
+		}
+	}
+
+	return q
+}
+
 // OnStart registers a new handler to be executed when a task is started.
 func (q *Queue) OnStart(callback HandlerFunc) *Queue {
 	return q.onFunc(TASK_STATUS_PROCESSING, callback)
@@ -179,6 +476,17 @@ func (q *Queue) Empty() *ekaerr.Error {
 }
 
 // Cancel cancels a task using its ID.
+//
+// The waiting task won't be processed. It's removed from the Broker's pending
+// structures if Broker implements BrokerPendingRemover,
+// and it's dropped by the consumer anyway (if it's already consumed).
+//
+// If the task is under processing, the cancellation signal is delivered
+// to the consumer that processes it (see BrokerCancellationNotifier).
+// Task's context (Task.Context()) is cancelled then, and once the handler returns,
+// Task is considered cancelled regardless of handler's result,
+// and onFailure callbacks are called with TASK_STATUS_CANCELLED.
+// Thus handlers should watch for Task.Context().Done() if they take a while.
 func (q *Queue) Cancel(taskID string) (*Task, *ekaerr.Error) {
 	const s = "Bokchoy: Failed to cancel the task. "
 	switch {
@@ -198,15 +506,129 @@ func (q *Queue) Cancel(taskID string) (*Task, *ekaerr.Error) {
 
 	task, err := q.Get(taskID)
 
+	if err.IsNil() && task == nil {
+		err = ekaerr.NotFound.
+			New("Task is not found.").
+			WithString("bokchoy_queue_name", q.name).
+			WithString("bokchoy_task_id", taskID)
+	}
+
 	if err.IsNil() {
 		task.MarkAsCanceled()
 		err = q.save(task)
 	}
 
+	if err.IsNil() {
+		q.removePending(taskID)
+		err = q.notifyCancellation(taskID).
+			AddMessage("Task is cancelled, but its processing (if any) might not be interrupted.")
+	}
+
+	if err.IsNil() {
+		if q.options.Collector != nil {
+			q.options.Collector.TaskCanceled(task)
+		}
+		q.parent.events.emit(EVENT_TYPE_TASK_CANCELLED, q.name, task)
+	}
+
 	return task, err.AddMessage(s).Throw()
 }
 
+// Requeue publishes a finished (succeeded, failed, timed out or cancelled)
+// task using its ID to the current Queue again, as a new one,
+// keeping its ID, payload and options, but forgetting its error and panic.
+// It's useful to retry tasks that are failed because of some outage.
+// The idempotency key of the succeeded task is forgotten (see WithIdempotency()).
+func (q *Queue) Requeue(taskID string) (*Task, *ekaerr.Error) {
+	const s = "Bokchoy: Failed to requeue the task. "
+
+	// Queue's validity, taskID are checked by q.Get().
+	task, err := q.Get(taskID)
+
+	switch {
+	case err.IsNotNil():
+		return nil, err.AddMessage(s).Throw()
+
+	case task == nil:
+		return nil, ekaerr.NotFound.
+			New(s + "Task is not found.").
+			WithString("bokchoy_queue_name", q.name).
+			WithString("bokchoy_task_id", taskID).
+			Throw()
+
+	case task.status == TASK_STATUS_WAITING ||
+			task.status == TASK_STATUS_PROCESSING ||
+			task.status == TASK_STATUS_RETRYING:
+		return nil, ekaerr.RejectedOperation.
+			New(s + "Task is not finished yet.").
+			WithString("bokchoy_queue_name", q.name).
+			WithString("bokchoy_task_id", taskID).
+			WithStringer("bokchoy_task_status", task.status).
+			Throw()
+	}
+
+	// Otherwise requeued task would be skipped as succeeded already.
+	if store := q.idempotencyStore(); store != nil && task.status == TASK_STATUS_SUCCEEDED {
+		if err = store.DeleteIdempotencyKey(q.name, task.IdempotencyKey()); err.IsNotNil() {
+			return nil, err.
+				AddMessage(s + "Failed to remove idempotency key of succeeded task.").
+				WithString("bokchoy_task_idempotency_key", task.IdempotencyKey()).
+				Throw()
+		}
+	}
+
+	task.status = TASK_STATUS_WAITING
+	task.enqueuedAt = time.Now().UnixNano()
+	task.ETA = 0
+	task.ExecTime = 0
+	task.startedAt = 0
+	task.processedAt = 0
+	task.Error = nil
+	task.Panic = nil
+
+	if err = q.PublishTask(task); err.IsNotNil() {
+		return nil, err.AddMessage(s).Throw()
+	}
+
+	return task, nil
+}
+
+// Delete deletes a task using its ID from the current Queue.
+// It's not an error if there is no such task.
+func (q *Queue) Delete(taskID string) *ekaerr.Error {
+	const s = "Bokchoy: Failed to delete the task. "
+	switch {
+
+	case !q.isValid():
+		return ekaerr.IllegalArgument.
+			New(s + "Queue is invalid. Has it been initialized correctly?").
+			WithString("bokchoy_queue_why_invalid", q.whyInvalid()).
+			Throw()
+
+	case taskID == "":
+		return ekaerr.IllegalArgument.
+			New(s + "Task ID is empty.").
+			WithString("bokchoy_queue_name", q.name).
+			Throw()
+	}
+
+	if err := q.parent.broker.Delete(q.name, taskID); err.IsNotNil() {
+		return err.AddMessage(s).
+			WithString("bokchoy_queue_name", q.name).
+			WithString("bokchoy_task_id", taskID).
+			Throw()
+	}
+
+	q.parent.logger.Copy().
+		WithString("bokchoy_queue_name", q.name).
+		WithString("bokchoy_task_id", taskID).
+		Debug("Bokchoy: Task has been deleted")
+
+	return nil
+}
+
 // List returns tasks from the broker.
+// Tasks that can not be decoded are skipped and logged.
 func (q *Queue) List() ([]Task, *ekaerr.Error) {
 	const s = "Bokchoy: Failed to retrieve all tasks from queue. "
 	switch {
@@ -223,12 +645,21 @@ func (q *Queue) List() ([]Task, *ekaerr.Error) {
 		return nil, err.AddMessage(s).Throw()
 	}
 
-	tasks, err := q.decodeTasks(encodedTasks)
-	if err.IsNotNil() {
-		return nil, err.AddMessage(s).Throw()
+	tasks := make([]Task, len(encodedTasks))
+	decoded := 0
+
+	for i := range encodedTasks {
+		if _, err = q.decodeTask(encodedTasks[i], &tasks[decoded]); err.IsNotNil() {
+			q.parent.logger.Copy().
+				WithString("bokchoy_queue_name", q.name).
+				Warne(s + "Task can not be decoded. Skipped.", err)
+			tasks[decoded] = Task{}
+			continue
+		}
+		decoded++
 	}
 
-	return tasks, nil
+	return tasks[:decoded], nil
 }
 
 // Get returns a Task instance from the current Broker's Queue with its id.
@@ -259,7 +690,7 @@ func (q *Queue) Get(taskID string) (*Task, *ekaerr.Error) {
 	}
 
 	if err.IsNil() {
-		err = task.Deserialize(encodedTask, q.options.Serializer)
+		_, err = q.decodeTask(encodedTask, &task)
 	}
 
 	if err.IsNotNil() {
@@ -312,7 +743,7 @@ func (q *Queue) Consume() ([]Task, *ekaerr.Error) {
 	encodedTasks, err := q.parent.broker.Consume(q.name, 0)
 
 	if err.IsNil() {
-		tasks, err = q.decodeTasks(encodedTasks)
+		tasks, err = q.decodeTasks(encodedTasks, true)
 	} else {
 		err.WithString("bokchoy_queue_name", q.name)
 	}
@@ -361,26 +792,96 @@ func (q *Queue) PublishTask(task *Task) *ekaerr.Error {
 	}
 
 	// No need to check task,
-	// because task.Serialize already has all checks.
+	// because task.Serialize (under q.encodeTask) already has all checks.
+
+	// Span context must be injected before encoding.
+	span := q.tracePublishing(task)
 
-	serializedTask, err := task.Serialize(q.options.Serializer)
+	serializedTask, err := q.encodeTask(task)
 	if err.IsNotNil() {
-		return err.AddMessage(s).WithString("bokchoy_queue_name", q.name).Throw()
+		err.AddMessage(s).WithString("bokchoy_queue_name", q.name)
+		traceEnd(span, err, false, "")
+		return err.Throw()
 	}
 
 	if err = q.parent.broker.Publish(q.name, task.id, serializedTask, task.ETA); err.IsNotNil() {
-		return err.AddMessage(s).
+		err.AddMessage(s).
 			WithString("bokchoy_queue_name", q.name).
 			WithString("bokchoy_task_id", task.id).
-			WithString("bokchoy_task_user_payload", spew.Sdump(task.Payload)).
-			Throw()
+			WithString("bokchoy_task_user_payload", spew.Sdump(task.Payload))
+		traceEnd(span, err, false, "")
+		return err.Throw()
 	}
 
-	q.parent.logger.Copy().
-		WithString("bokchoy_queue_name", q.name).
-		WithString("bokchoy_task_id", task.id).
-		//WithString("bokchoy_task_user_payload", spew.Sdump(task.Payload)).
-		Debug("Bokchoy: Task has been published")
+	traceEnd(span, nil, true, "")
+	q.reportPublished(task)
 
 	return nil
 }
+
+// PublishBatch publishes many new tasks to the current Queue at once.
+//
+// Tasks are encoded in parallel, and if Broker implements BrokerBatchPublisher,
+// they're published by one its call (one round-trip or transaction).
+// Otherwise, they're published one-by-one, the same way PublishTask() does.
+//
+// Returns nil if all tasks are published. Otherwise, returns errors
+// of the same length as tasks: errs[i] is the error of tasks[i], nil if it's published.
+func (q *Queue) PublishBatch(tasks []*Task) (errs []*ekaerr.Error) {
+	const s = "Bokchoy: Failed to publish task to the queue as a part of batch. "
+
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	errs = make([]*ekaerr.Error, len(tasks))
+
+	if !q.isValid() {
+		for i := range errs {
+			errs[i] = ekaerr.IllegalArgument.
+				New(s + "Queue is invalid. Has it been initialized correctly?").
+				WithString("bokchoy_queue_why_invalid", q.whyInvalid()).
+				Throw()
+		}
+		return errs
+	}
+
+	// Span contexts must be injected before encoding.
+	spans := make([]Span, len(tasks))
+	items := make([]BrokerPublishItem, len(tasks))
+	q.encodeBatch(tasks, spans, items, errs)
+
+	if publisher, ok := q.parent.broker.(BrokerBatchPublisher); ok {
+		q.publishMany(publisher, items, errs)
+	} else {
+		for i := range items {
+			if errs[i].IsNil() {
+				errs[i] = q.parent.broker.Publish(q.name,
+					items[i].TaskID, items[i].TaskPayload, items[i].TaskEtaUnixNano)
+			}
+		}
+	}
+
+	isPublished := true
+	for i := range tasks {
+
+		if errs[i].IsNotNil() {
+			errs[i].AddMessage(s).
+				WithString("bokchoy_queue_name", q.name).
+				WithString("bokchoy_task_id", tasks[i].ID())
+			traceEnd(spans[i], errs[i], false, "")
+			errs[i] = errs[i].Throw()
+			isPublished = false
+			continue
+		}
+
+		traceEnd(spans[i], nil, true, "")
+		q.reportPublished(tasks[i])
+	}
+
+	if isPublished {
+		return nil
+	}
+
+	return errs
+}
@@ -19,7 +19,8 @@
 package bokchoy
 
 import (
-	"sync/atomic"
+	"runtime"
+	"sync"
 	"time"
 
 	"github.com/qioalice/ekago/v3/ekaerr"
@@ -91,30 +92,50 @@ func (q *Queue) start() {
 
 	handlersCount :=
 		len(q.handlers) +
+		len(q.typedHandlers) +
+		len(q.unknownTypeHandlers) +
+		len(q.middlewares) +
 		len(q.onStart) +
 		len(q.onSuccess) +
 		len(q.onFailure) +
 		len(q.onComplete)
 
+	if q.batchHandler != nil {
+		handlersCount++
+	}
+
 	if handlersCount == 0 {
 		q.parent.logger.Copy().
 			WithString("bokchoy_queue_name", q.name).
 			Warn(s + "Queue has no registered handlers or task status changed callbacks. " +
-				"Did you ever call Use() or any of " +
+				"Did you ever call Use(), Handle(), HandleBatch(), Wrap() or any of " +
 				"OnStart(), OnComplete(), OnFailure(), OnSuccess() setters?")
 		return
 	}
 
-	q.consumers = make([]consumer, q.options.Concurrency)
-	for i, n := 0, len(q.consumers); i < n; i++ {
-		q.consumers[i].queue = q
-		q.consumers[i].idx = int8(i)
-		q.consumers[i].requestStart()
+	q.chain = q.buildChain()
+	q.subscribeCancellation()
+
+	// Autoscaler must be created before consumers, they report to it.
+	q.autoscaler = nil
+	if q.options.AutoscalingMax > 0 {
+		q.autoscaler = newAutoscaler(q)
+	}
+
+	q.consumersMu.Lock()
+	q.isConsuming = true
+	q.consumers = nil
+	q.setConcurrency(q.concurrencyOrDefault())
+	consumersNumber := len(q.consumers)
+	q.consumersMu.Unlock()
+
+	if q.autoscaler != nil {
+		go q.autoscaler.run()
 	}
 
 	q.parent.logger.Copy().
 		WithString("bokchoy_queue_name", q.name).
-		WithInt("bokchoy_queue_consumers_number", len(q.consumers)).
+		WithInt("bokchoy_queue_consumers_number", consumersNumber).
 		Debug("Bokchoy: Queue consumers has been started.")
 }
 
@@ -124,39 +145,434 @@ func (q *Queue) stop() {
 	// do not lock/unlock q.parent.sema.
 	// Already protected by callers.
 
-	if len(q.consumers) == 0 {
-		return
+	// Autoscaler must not change concurrency while consumers are stopping.
+	if q.autoscaler != nil {
+		q.autoscaler.stop()
 	}
 
-	for i, n := 0, len(q.consumers); i < n; i++ {
+	q.consumersMu.Lock()
+	consumersNumber := len(q.consumers)
+	for i := 0; i < consumersNumber; i++ {
 		q.consumers[i].requestStop()
 	}
+	q.isConsuming = false
+	q.consumersMu.Unlock()
+
+	if consumersNumber == 0 {
+		return
+	}
 
 	q.wg.Wait()
-	atomic.StoreInt32(&q.errCounter, 0)
+	q.breaker.reset()
+
+	if q.unsubscribeCancellation != nil {
+		q.unsubscribeCancellation()
+		q.unsubscribeCancellation = nil
+	}
 
 	q.parent.logger.Copy().
 		WithString("bokchoy_queue_name", q.name).
-		WithInt("bokchoy_queue_consumers_number", len(q.consumers)).
+		WithInt("bokchoy_queue_consumers_number", consumersNumber).
 		Debug("Bokchoy: Queue consumers has been stopped.")
 }
 
-func (q *Queue) decodeTasks(encodedTasks [][]byte) ([]Task, *ekaerr.Error) {
+// unfreezeConsumers unfreezes all frozen slave consumer s.
+// It's called when circuit breaker is closed.
+func (q *Queue) unfreezeConsumers() {
+	consumers := q.consumersSnapshot()
+	for i, n := 1, len(consumers); i < n; i++ {
+		consumers[i].requestUnfreeze()
+	}
+}
+
+// concurrencyOrDefault returns the number of consumers, that has been set
+// by SetConcurrency() or by WithConcurrency() option otherwise.
+// Caller must take responsibility about locking consumersMu.
+func (q *Queue) concurrencyOrDefault() int8 {
+	if q.concurrency != 0 {
+		return q.concurrency
+	}
+	return q.options.Concurrency
+}
+
+// setConcurrency is SetConcurrency() w/o locking.
+// Starts or retires consumers if queue is consuming now.
+// Master consumer (see consumer) is never retired.
+// Caller must take responsibility about locking consumersMu.
+func (q *Queue) setConcurrency(n int8) {
+
+	if n < 1 {
+		n = 1
+	}
+
+	if max := q.options.AutoscalingMax; max > 0 {
+		switch min := q.options.AutoscalingMin; {
+		case n < min: n = min
+		case n > max: n = max
+		}
+	}
+
+	q.concurrency = n
+	current := int8(len(q.consumers))
+
+	if !q.isConsuming || n == current {
+		return
+	}
+
+	if n > current {
+		for i := current; i < n; i++ {
+			c := &consumer{queue: q, idx: i}
+			q.consumers = append(q.consumers, c)
+			c.requestStart()
+		}
+	} else {
+		for i := n; i < current; i++ {
+			q.consumers[i].requestStop()
+		}
+		// Copy, because the old slice may be used by consumersSnapshot() callers.
+		q.consumers = append(q.consumers[:0:0], q.consumers[:n]...)
+	}
+
+	q.parent.logger.Copy().
+		WithString("bokchoy_queue_name", q.name).
+		WithInt8("bokchoy_queue_consumers_number_old", current).
+		WithInt8("bokchoy_queue_consumers_number", n).
+		Debug("Bokchoy: Queue concurrency has been changed.")
+}
+
+// consumersSnapshot returns the current consumers of the Queue.
+// Returned slice must not be modified, but it's safe to use it w/o locking.
+func (q *Queue) consumersSnapshot() []*consumer {
+	q.consumersMu.RLock()
+	defer q.consumersMu.RUnlock()
+	return q.consumers
+}
+
+// buildChain wraps registered handlers (Use()) by middlewares (Wrap()), the first one is outermost.
+func (q *Queue) buildChain() HandlerFunc {
+
+	chain := q.handle
+	for i := len(q.middlewares) - 1; i >= 0; i-- {
+		chain = q.middlewares[i](chain)
+	}
+
+	return chain
+}
+
+// handle is the innermost HandlerFunc of the Queue's handlers chain.
+// It calls all handlers, registered for the Task's type (see handlersOf())
+// one-by-one, until one of them returns an error or changes Task's status.
+func (q *Queue) handle(task *Task) *ekaerr.Error {
+
+	handlers, err := q.handlersOf(task)
+	if err.IsNotNil() {
+		return err.Throw()
+	}
+
+	oldStatus := task.status
+	for i, n := 0, len(handlers); i < n; i++ {
+		if err := handlers[i](task); err.IsNotNil() {
+			return err.Throw()
+		}
+		if oldStatus != task.status {
+			break
+		}
+	}
+
+	return nil
+}
+
+// handlersOf returns handlers, that must be called for the presented Task:
+//  - Registered by Use(), if Task has no type,
+//  - Registered by Handle() for the Task's type,
+//  - Registered by HandleUnknown(), if there is no handlers for the Task's type.
+// Returns an error if there is no handlers at all for the Task's type.
+func (q *Queue) handlersOf(task *Task) ([]HandlerFunc, *ekaerr.Error) {
+	const s = "Bokchoy: Failed to handle task. "
+
+	if task.taskType == "" {
+		return q.handlers, nil
+	}
+
+	if handlers, ok := q.typedHandlers[task.taskType]; ok {
+		return handlers, nil
+	}
+
+	if len(q.unknownTypeHandlers) > 0 {
+		return q.unknownTypeHandlers, nil
+	}
+
+	return nil, ekaerr.UnsupportedOperation.
+		New(s + "There is no handlers for the task's type. " +
+			"Did you register them using Handle() or HandleUnknown()?").
+		WithString("bokchoy_queue_name", q.name).
+		WithString("bokchoy_task_id", task.id).
+		WithString("bokchoy_task_type", task.taskType).
+		Throw()
+}
+
+// typedSerializerOf returns a Serializer of payloads of tasks of the given type,
+// that is registered by registerSerializerOf(), and reports whether it's registered.
+func (q *Queue) typedSerializerOf(taskType string) (Serializer, bool) {
+
+	q.typedSerializersMu.RLock()
+	defer q.typedSerializersMu.RUnlock()
+
+	serializer, ok := q.typedSerializers[taskType]
+	return serializer, ok
+}
+
+// registerSerializerOf registers a Serializer of payloads of tasks of the given type
+// (see SerializerOf()), if there is no registered one yet.
+func (q *Queue) registerSerializerOf(taskType string, serializer Serializer) {
+
+	q.typedSerializersMu.Lock()
+	defer q.typedSerializersMu.Unlock()
+
+	if q.typedSerializers == nil {
+		q.typedSerializers = make(map[string]Serializer)
+	}
+
+	if _, ok := q.typedSerializers[taskType]; !ok {
+		q.typedSerializers[taskType] = serializer
+	}
+}
+
+// filterHandlers returns only not nil handlers of presented ones.
+func filterHandlers(handlers []HandlerFunc) []HandlerFunc {
+
+	if len(handlers) == 0 {
+		return nil
+	}
+
+	filtered := make([]HandlerFunc, 0, len(handlers))
+	for _, handler := range handlers {
+		if handler != nil {
+			filtered = append(filtered, handler)
+		}
+	}
+
+	return filtered
+}
+
+// decodeTasks decodes many encoded tasks using decodeTask().
+//
+// If quarantineInvalid is true, tasks with invalid signature and tasks
+// that can't be decoded are moved to the quarantine queue and skipped,
+// instead of failing the whole decoding (they're consumed already,
+// they'd be lost otherwise along with the valid ones).
+// Thus the len of returned tasks may be less than len of encodedTasks.
+func (q *Queue) decodeTasks(encodedTasks [][]byte, quarantineInvalid bool) ([]Task, *ekaerr.Error) {
 	const s = "Bokchoy: Failed to decode many tasks using msgpack. "
 
 	tasks := make([]Task, len(encodedTasks))
+	decoded := 0
+
 	for i, n := 0, len(encodedTasks); i < n; i++ {
 
-		if err := tasks[i].Deserialize(encodedTasks[i], q.options.Serializer); err.IsNotNil() {
+		_, err := q.decodeTask(encodedTasks[i], &tasks[decoded])
+		if err.IsNotNil() && quarantineInvalid {
+
+			q.parent.logger.Copy().
+				WithString("bokchoy_queue_name", q.name).
+				Warne(s + "Task is invalid. Quarantining it.", err)
+
+			tasks[decoded] = Task{} // might be decoded partially
+			_ = q.quarantine(encodedTasks[i])
+			continue
+		}
+
+		if err.IsNotNil() {
 			return nil, err.AddMessage(s).
 				WithString("bokchoy_queue_name", q.name).
 				WithInt("bokchoy_decode_tasks_decoded", i).
 				WithInt("bokchoy_decode_tasks_total", len(encodedTasks)).
 				Throw()
 		}
+
+		decoded++
+	}
+
+	return tasks[:decoded], nil
+}
+
+// encodeTask serializes presented Task using Queue's Serializer
+// and signs it if signing key is presented (WithSigningKey() option).
+func (q *Queue) encodeTask(t *Task) ([]byte, *ekaerr.Error) {
+
+	// The Task's payload must be decoded as typed one by any process.
+	if _, ok := q.typedSerializerOf(t.taskType); ok {
+		t.isPayloadTyped = true
+	}
+
+	encodedTask, err := t.Serialize(q.SerializerOfTask(t))
+	if err.IsNotNil() {
+		return nil, err.Throw()
+	}
+
+	return signTask(encodedTask, q.options.SigningKey), nil
+}
+
+// decodeTask verifies the signature of encodedTask
+// if signing key is presented (WithSigningKey() option),
+// and deserializes it to the presented Task using Queue's Serializer.
+//
+// Returned bool is false only if signature is invalid.
+// An error is returned in that case too.
+func (q *Queue) decodeTask(encodedTask []byte, t *Task) (bool, *ekaerr.Error) {
+	const s = "Bokchoy: Failed to verify task's signature. "
+
+	encodedTask, isSignatureValid := verifyTask(encodedTask, q.options.SigningKey)
+	if !isSignatureValid {
+		return false, ekaerr.RejectedOperation.
+			New(s + "Signature is invalid or absent. Task has been tampered?").
+			WithString("bokchoy_queue_name", q.name).
+			Throw()
+	}
+
+	// Payload's Serializer depends on Task's type (see Register()),
+	// thus Task's fields must be decoded first.
+	const s2 = "Bokchoy: Failed to decode task using msgpack. "
+
+	if err := t.deserializeEnvelope(encodedTask); err.IsNotNil() {
+		return true, err.AddMessage(s2).Throw()
+	}
+
+	if err := t.deserializePayload(q.SerializerOfTask(t)); err.IsNotNil() {
+		return true, err.AddMessage(s2).Throw()
+	}
+
+	// Task.queueName is not saved into encoded RAW data of task.
+	t.queueName = q.name
+	return true, nil
+}
+
+// quarantine saves encodedTask, that has an invalid signature or can't be decoded,
+// to the quarantine queue (WithQuarantineQueue() option) as is,
+// using newly generated ID, because task's one cannot be trusted.
+//
+// An error of saving is not returned, but logged,
+// because there is nothing caller can do with it anyway.
+// Reports whether the task has been quarantined.
+func (q *Queue) quarantine(encodedTask []byte) bool {
+	const s = "Bokchoy: Failed to quarantine invalid task. "
+
+	quarantineQueueName := q.options.QuarantineQueue
+	if quarantineQueueName == "" {
+		quarantineQueueName = q.name + _QUARANTINE_QUEUE_SUFFIX
+	}
+
+	quarantineID := ekatyp.ULID_New_OrNil().String()
+
+	err := q.parent.broker.Set(quarantineQueueName, quarantineID, encodedTask, 0)
+	if err.IsNotNil() {
+		q.parent.logger.Copy().
+			WithString("bokchoy_queue_name", q.name).
+			WithString("bokchoy_quarantine_queue_name", quarantineQueueName).
+			WithString("bokchoy_quarantine_id", quarantineID).
+			Errore(s, err)
+		return false
+	}
+
+	q.parent.logger.Copy().
+		WithString("bokchoy_queue_name", q.name).
+		WithString("bokchoy_quarantine_queue_name", quarantineQueueName).
+		WithString("bokchoy_quarantine_id", quarantineID).
+		Warn("Bokchoy: Invalid task has been quarantined.")
+	return true
+}
+
+// reportPublished logs, collects and emits an event about published Task.
+func (q *Queue) reportPublished(task *Task) {
+
+	q.parent.logger.Copy().
+		WithString("bokchoy_queue_name", q.name).
+		WithString("bokchoy_task_id", task.id).
+		//WithString("bokchoy_task_user_payload", spew.Sdump(task.Payload)).
+		Debug("Bokchoy: Task has been published")
+
+	if q.options.Collector != nil {
+		q.options.Collector.TaskPublished(task)
+	}
+
+	// Returning Task back to be retried is reported by EVENT_TYPE_TASK_RETRIED.
+	if task.status != TASK_STATUS_RETRYING {
+		q.parent.events.emit(EVENT_TYPE_TASK_PUBLISHED, q.name, task)
+	}
+}
+
+// encodeBatch encodes tasks in parallel, starting their publishing spans.
+// spans, items and errs must have the same length as tasks,
+// spans[i], items[i] and errs[i] are set for tasks[i].
+func (q *Queue) encodeBatch(tasks []*Task, spans []Span, items []BrokerPublishItem, errs []*ekaerr.Error) {
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(tasks) {
+		workers = len(tasks)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+
+			for i := w; i < len(tasks); i += workers {
+				if tasks[i] == nil {
+					errs[i] = ekaerr.IllegalArgument.
+						New("Task is nil.").
+						Throw()
+					continue
+				}
+
+				spans[i] = q.tracePublishing(tasks[i])
+
+				items[i].TaskID = tasks[i].id
+				items[i].TaskEtaUnixNano = tasks[i].ETA
+				items[i].TaskPayload, errs[i] = q.encodeTask(tasks[i])
+			}
+		}(w)
+	}
+
+	wg.Wait()
+}
+
+// publishMany publishes encoded items (those that have no error) by one call
+// of BrokerBatchPublisher, saving its errors to errs.
+func (q *Queue) publishMany(publisher BrokerBatchPublisher, items []BrokerPublishItem, errs []*ekaerr.Error) {
+
+	var (
+		batch   = make([]BrokerPublishItem, 0, len(items))
+		indexes = make([]int, 0, len(items))
+	)
+
+	for i := range items {
+		if errs[i].IsNil() {
+			batch = append(batch, items[i])
+			indexes = append(indexes, i)
+		}
+	}
+
+	if len(batch) == 0 {
+		return
 	}
 
-	return tasks, nil
+	batchErrs := publisher.PublishMany(q.name, batch)
+
+	for j, i := range indexes {
+		switch {
+		case batchErrs == nil:
+		case len(batchErrs) != len(batch):
+			errs[i] = ekaerr.IllegalState.
+				New("Broker returned unexpected number of errors. Task might be published or not.").
+				WithString("bokchoy_broker", q.parent.broker.String()).
+				WithInt("bokchoy_broker_errors_number", len(batchErrs)).
+				Throw()
+		default:
+			errs[i] = batchErrs[j]
+		}
+	}
 }
 
 // save saves (creates or updates) a presented Task to the Queue's tasks list,
@@ -172,7 +588,7 @@ func (q *Queue) save(t *Task) *ekaerr.Error {
 			Throw()
 	}
 
-	encodedTask, err := t.Serialize(q.options.Serializer)
+	encodedTask, err := q.encodeTask(t)
 	if err.IsNotNil() {
 		return err.AddMessage(s).WithString("bokchoy_queue_name", q.name).Throw()
 	}
@@ -217,10 +633,16 @@ func (q *Queue) newTask(payload interface{}, options []Option) *Task {
 
 	task := &Task{
 		id:             ekatyp.ULID_New_OrNil().String(),
+		queueName:      q.name,
+		taskType:       optionsObject.TaskType,
+		idempotencyKey: optionsObject.IdempotencyKey,
 		status:         TASK_STATUS_WAITING,
 
 		Payload:        payload,
 		PublishedAt:    ekatime.Now(),
+		enqueuedAt:     time.Now().UnixNano(),
+
+		ctx:            optionsObject.Context,
 
 		MaxRetries:     optionsObject.MaxRetries,
 		TTL:            optionsObject.TTL,
@@ -228,6 +650,13 @@ func (q *Queue) newTask(payload interface{}, options []Option) *Task {
 		RetryIntervals: optionsObject.RetryIntervals,
 	}
 
+	if len(optionsObject.Headers) > 0 {
+		task.Headers = make(map[string]string, len(optionsObject.Headers))
+		for k, v := range optionsObject.Headers {
+			task.Headers[k] = v
+		}
+	}
+
 	if optionsObject.Countdown > 0 {
 		task.ETA = time.Now().UnixNano() + optionsObject.Countdown.Nanoseconds()
 	}
@@ -0,0 +1,977 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+	"github.com/qioalice/bokchoy/internal/brokertest"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testTaskPayload struct {
+	Data string
+}
+
+var testTaskPayloadSerializer = bokchoy.CustomSerializerJSON(testTaskPayload{})
+
+// newTestBokchoy returns a new Bokchoy, that uses brokertest.MemoryBroker,
+// and starts it in a separate goroutine once prepare is done.
+// Returned func stops it.
+func newTestBokchoy(t *testing.T, prepare func(b *bokchoy.Bokchoy), options ...bokchoy.Option) (*bokchoy.Bokchoy, func()) {
+
+	options = append([]bokchoy.Option{
+		bokchoy.WithBroker(brokertest.NewMemoryBroker()),
+		bokchoy.WithSerializer(testTaskPayloadSerializer),
+		bokchoy.WithDisableOutput(true),
+		bokchoy.WithRetryIntervals([]time.Duration{time.Millisecond}),
+	}, options...)
+
+	b, err := bokchoy.New(options...)
+	require.True(t, err.IsNil())
+
+	prepare(b)
+
+	go func() { _ = b.Run() }()
+	return b, b.Stop
+}
+
+// waitTask returns a func that returns a Task once it's succeeded or failed
+// (onSuccess, onFailure callbacks) or fails the test after a timeout.
+func waitTask(t *testing.T, q *bokchoy.Queue) func() *bokchoy.Task {
+
+	completed := make(chan *bokchoy.Task, 1)
+	callback := func(task *bokchoy.Task) *ekaerr.Error {
+		completed <- task
+		return nil
+	}
+
+	q.OnSuccess(callback).OnFailure(callback)
+
+	return func() *bokchoy.Task {
+		select {
+		case task := <-completed:
+			return task
+		case <-time.After(5 * time.Second):
+			t.Fatal("Task has not been completed in time.")
+			return nil
+		}
+	}
+}
+
+func TestQueueWrap(t *testing.T) {
+
+	var (
+		calls []string
+		wait  func() *bokchoy.Task
+	)
+
+	middleware := func(name string) bokchoy.Middleware {
+		return func(next bokchoy.HandlerFunc) bokchoy.HandlerFunc {
+			return func(task *bokchoy.Task) *ekaerr.Error {
+				calls = append(calls, name + ".before")
+				err := next(task)
+				calls = append(calls, name + ".after")
+				return err
+			}
+		}
+	}
+
+	b, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		q := b.Queue("tasks.test")
+		wait = waitTask(t, q)
+		q.Use(
+			func(_ *bokchoy.Task) *ekaerr.Error { calls = append(calls, "h1"); return nil },
+			func(_ *bokchoy.Task) *ekaerr.Error { calls = append(calls, "h2"); return nil },
+		)
+		q.Wrap(middleware("m1"), middleware("m2"))
+	})
+	defer stop()
+
+	_, err := b.Publish("tasks.test", testTaskPayload{Data: "hello world"})
+	require.True(t, err.IsNil())
+
+	task := wait()
+	require.Equal(t, bokchoy.TASK_STATUS_SUCCEEDED, task.Status())
+	require.Equal(t, []string{"m1.before", "m2.before", "h1", "h2", "m2.after", "m1.after"}, calls)
+}
+
+// testTracer is a Tracer, that records names of ended spans with their parents.
+type testTracer struct {
+	mu    sync.Mutex
+	ended []string // "parent -> name"
+}
+
+type testSpan struct {
+	tracer       *testTracer
+	name, parent string
+}
+
+type testSpanKey struct{}
+
+func (tr *testTracer) Start(ctx context.Context, spanName string) (context.Context, bokchoy.Span) {
+	parent, _ := ctx.Value(testSpanKey{}).(string)
+	return context.WithValue(ctx, testSpanKey{}, spanName), &testSpan{tr, spanName, parent}
+}
+
+func (tr *testTracer) Inject(ctx context.Context, carrier map[string]string) {
+	carrier["span"], _ = ctx.Value(testSpanKey{}).(string)
+}
+
+func (tr *testTracer) Extract(ctx context.Context, carrier map[string]string) context.Context {
+	return context.WithValue(ctx, testSpanKey{}, carrier["span"])
+}
+
+func (tr *testTracer) spans() []string {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return append(tr.ended[:0:0], tr.ended...)
+}
+
+func (s *testSpan) SetAttribute(_ string, _ interface{}) {}
+func (s *testSpan) RecordError(_ *ekaerr.Error)           {}
+func (s *testSpan) SetStatus(_ bool, _ string)            {}
+
+func (s *testSpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.ended = append(s.tracer.ended, s.parent + " -> " + s.name)
+}
+
+func TestQueueTracing(t *testing.T) {
+
+	var (
+		tracer = new(testTracer)
+		wait   func() *bokchoy.Task
+	)
+
+	b, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		q := b.Queue("tasks.test")
+		wait = waitTask(t, q)
+		q.Use(func(task *bokchoy.Task) *ekaerr.Error {
+			require.Equal(t, "bokchoy.process", task.Context().Value(testSpanKey{}))
+			return nil
+		})
+	}, bokchoy.WithTracer(tracer))
+	defer stop()
+
+	ctx := context.WithValue(context.Background(), testSpanKey{}, "http.request")
+	_, err := b.Publish("tasks.test", testTaskPayload{Data: "hello world"}, bokchoy.WithContext(ctx))
+	require.True(t, err.IsNil())
+
+	require.Equal(t, bokchoy.TASK_STATUS_SUCCEEDED, wait().Status())
+	require.Eventually(t, func() bool { return len(tracer.spans()) == 2 }, time.Second, time.Millisecond)
+	require.Equal(t, []string{
+		"http.request -> " + "bokchoy.publish",
+		"bokchoy.publish" + " -> " + "bokchoy.process",
+	}, tracer.spans())
+}
+
+func TestEvents(t *testing.T) {
+
+	var attempts int
+
+	b, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		b.Queue("tasks.test").Use(func(_ *bokchoy.Task) *ekaerr.Error {
+			if attempts++; attempts == 1 {
+				return ekaerr.IllegalState.New("Try again.").Throw()
+			}
+			return nil
+		})
+	}, bokchoy.WithMaxRetries(1))
+	defer stop()
+
+	// Subscribed after Run().
+	events, unsubscribe := b.Events().Channel(16)
+	defer unsubscribe()
+
+	task, err := b.Publish("tasks.test", testTaskPayload{Data: "hello world"})
+	require.True(t, err.IsNil())
+
+	var types []bokchoy.EventType
+	for len(types) == 0 || types[len(types)-1] != bokchoy.EVENT_TYPE_TASK_SUCCEEDED {
+		select {
+		case event := <-events:
+			require.Equal(t, "tasks.test", event.QueueName)
+			require.Equal(t, task.ID(), event.Task.ID())
+			types = append(types, event.Type)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Task has not been succeeded in time. Events: %v", types)
+		}
+	}
+
+	require.Equal(t, []bokchoy.EventType{
+		bokchoy.EVENT_TYPE_TASK_PUBLISHED,
+		bokchoy.EVENT_TYPE_TASK_STARTED,
+		bokchoy.EVENT_TYPE_TASK_RETRIED,
+		bokchoy.EVENT_TYPE_TASK_STARTED,
+		bokchoy.EVENT_TYPE_TASK_SUCCEEDED,
+	}, types)
+}
+
+func TestQueueCancelProcessing(t *testing.T) {
+
+	var (
+		q         *bokchoy.Queue
+		started   = make(chan struct{})
+		cancelled = make(chan bokchoy.TaskStatus, 1)
+	)
+
+	b, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		q = b.Queue("tasks.test")
+		q.Use(func(task *bokchoy.Task) *ekaerr.Error {
+			close(started)
+			<-task.Context().Done()
+			return ekaerr.Interrupted.New("Task's context is done.").Throw()
+		})
+		q.OnFailure(func(task *bokchoy.Task) *ekaerr.Error {
+			cancelled <- task.Status()
+			return nil
+		})
+	}, bokchoy.WithMaxRetries(3))
+	defer stop()
+
+	task, err := b.Publish("tasks.test", testTaskPayload{Data: "hello world"})
+	require.True(t, err.IsNil())
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Task has not been started in time.")
+	}
+
+	_, err = q.Cancel(task.ID())
+	require.True(t, err.IsNil())
+
+	select {
+	case status := <-cancelled:
+		require.Equal(t, bokchoy.TASK_STATUS_CANCELLED, status)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Task has not been cancelled in time.")
+	}
+
+	stop() // waits until processed task is saved
+
+	// Neither retried nor overwritten despite of handler's error.
+	task, err = q.Get(task.ID())
+	require.True(t, err.IsNil())
+	require.Equal(t, bokchoy.TASK_STATUS_CANCELLED, task.Status())
+}
+
+func TestQueueCancelWaiting(t *testing.T) {
+
+	brokers := map[string]bokchoy.Broker{
+		"pending remover": brokertest.NewMemoryBroker(),
+		// Hides BrokerPendingRemover, so the consumer must drop the task.
+		"consumer check": struct{ bokchoy.Broker }{brokertest.NewMemoryBroker()},
+	}
+
+	for name, broker := range brokers {
+		t.Run(name, func(t *testing.T) {
+
+			var (
+				q         *bokchoy.Queue
+				wait      func() *bokchoy.Task
+				cancelled *bokchoy.Task
+				processed []string
+			)
+
+			_, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+				q = b.Queue("tasks.test")
+				q.Use(func(task *bokchoy.Task) *ekaerr.Error {
+					processed = append(processed, task.ID())
+					return nil
+				})
+				wait = waitTask(t, q)
+
+				var err *ekaerr.Error
+				cancelled, err = q.Publish(testTaskPayload{Data: "cancelled"})
+				require.True(t, err.IsNil())
+				_, err = q.Cancel(cancelled.ID())
+				require.True(t, err.IsNil())
+			}, bokchoy.WithBroker(broker))
+			defer stop()
+
+			task, err := q.Publish(testTaskPayload{Data: "processed"})
+			require.True(t, err.IsNil())
+			require.Equal(t, task.ID(), wait().ID())
+
+			stop()
+			require.Equal(t, []string{task.ID()}, processed)
+
+			cancelled, err = q.Get(cancelled.ID())
+			require.True(t, err.IsNil())
+			require.Equal(t, bokchoy.TASK_STATUS_CANCELLED, cancelled.Status())
+		})
+	}
+}
+
+// cancellingBroker calls cancel once, when the tasks are consumed,
+// but before they're returned to the consumer.
+type cancellingBroker struct {
+	*brokertest.MemoryBroker
+	once   sync.Once
+	cancel func()
+}
+
+func (b *cancellingBroker) Consume(queueName string, maxETA int64) ([][]byte, *ekaerr.Error) {
+	data, err := b.MemoryBroker.Consume(queueName, maxETA)
+	if len(data) > 0 {
+		b.once.Do(b.cancel)
+	}
+	return data, err
+}
+
+func TestQueueCancelConsumed(t *testing.T) {
+
+	var (
+		q         *bokchoy.Queue
+		wait      func() *bokchoy.Task
+		cancelled *bokchoy.Task
+		processed []string
+		broker    = &cancellingBroker{MemoryBroker: brokertest.NewMemoryBroker()}
+	)
+
+	_, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		q = b.Queue("tasks.test")
+		q.Use(func(task *bokchoy.Task) *ekaerr.Error {
+			processed = append(processed, task.ID())
+			return nil
+		})
+		wait = waitTask(t, q)
+
+		var err *ekaerr.Error
+		cancelled, err = q.Publish(testTaskPayload{Data: "cancelled"})
+		require.True(t, err.IsNil())
+
+		// Neither pending task to remove, nor tracked one to notify.
+		broker.cancel = func() {
+			_, err := q.Cancel(cancelled.ID())
+			require.True(t, err.IsNil())
+		}
+	}, bokchoy.WithBroker(broker))
+	defer stop()
+
+	task, err := q.Publish(testTaskPayload{Data: "processed"})
+	require.True(t, err.IsNil())
+	require.Equal(t, task.ID(), wait().ID())
+
+	stop()
+	require.Equal(t, []string{task.ID()}, processed)
+
+	cancelled, err = q.Get(cancelled.ID())
+	require.True(t, err.IsNil())
+	require.Equal(t, bokchoy.TASK_STATUS_CANCELLED, cancelled.Status())
+}
+
+func TestQueueQuarantine(t *testing.T) {
+
+	var (
+		q      *bokchoy.Queue
+		wait   func() *bokchoy.Task
+		broker = brokertest.NewMemoryBroker()
+	)
+
+	_, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		q = b.Queue("tasks.test").Use(func(_ *bokchoy.Task) *ekaerr.Error {
+			return nil
+		})
+		wait = waitTask(t, q)
+
+		// Consumed along with the valid one, but can't be decoded.
+		require.True(t, broker.Publish("tasks.test", "invalid", []byte("invalid"), 0).IsNil())
+	}, bokchoy.WithBroker(broker))
+	defer stop()
+
+	task, err := q.Publish(testTaskPayload{Data: "hello world"})
+	require.True(t, err.IsNil())
+	require.Equal(t, task.ID(), wait().ID())
+
+	quarantined, err := broker.List("tasks.test.quarantine")
+	require.True(t, err.IsNil())
+	require.Equal(t, [][]byte{[]byte("invalid")}, quarantined)
+}
+
+func TestQueueHeaders(t *testing.T) {
+
+	var (
+		q       *bokchoy.Queue
+		wait    func() *bokchoy.Task
+		headers = make(chan map[string]string, 1)
+	)
+
+	_, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		q = b.Queue("tasks.test", bokchoy.WithHeader("tenant_id", "42")).
+			Use(func(task *bokchoy.Task) *ekaerr.Error {
+				headers <- map[string]string{
+					"tenant_id":  task.Header("tenant_id"),
+					"request_id": task.Header("request_id"),
+				}
+				task.SetHeader("handled_by", "test")
+				return nil
+			})
+		wait = waitTask(t, q)
+	})
+	defer stop()
+
+	// Task's headers are written to a copy of the Queue's ones.
+	withRequestID := q.NewTask(testTaskPayload{}, bokchoy.WithHeader("request_id", "1"))
+	withoutRequestID := q.NewTask(testTaskPayload{})
+	require.Equal(t, "1", withRequestID.Header("request_id"))
+	require.Equal(t, "", withoutRequestID.Header("request_id"))
+
+	withRequestID.SetHeader("tenant_id", "43")
+	require.Equal(t, "42", withoutRequestID.Header("tenant_id"))
+	require.Equal(t, "42", q.NewTask(testTaskPayload{}).Header("tenant_id"))
+
+	// Headers survive publishing and consuming.
+	task, err := q.Publish(testTaskPayload{Data: "hello world"}, bokchoy.WithHeader("request_id", "2"))
+	require.True(t, err.IsNil())
+	require.Equal(t, task.ID(), wait().ID())
+	require.Equal(t, map[string]string{"tenant_id": "42", "request_id": "2"}, <-headers)
+
+	stop() // waits until processed task is saved
+
+	// Headers, that are set by handler, are saved along with Task.
+	task, err = q.Get(task.ID())
+	require.True(t, err.IsNil())
+	require.Equal(t, "test", task.Header("handled_by"))
+	require.Equal(t, "2", task.Header("request_id"))
+}
+
+func TestQueueHandle(t *testing.T) {
+
+	var (
+		q       *bokchoy.Queue
+		mu      sync.Mutex
+		handled = make(map[string]string) // task's payload -> handler
+		done    = make(chan struct{}, 4)
+	)
+
+	handler := func(name string) bokchoy.HandlerFunc {
+		return func(task *bokchoy.Task) *ekaerr.Error {
+			mu.Lock()
+			handled[task.Payload.(testTaskPayload).Data] = name
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	_, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		q = b.Queue("tasks.test").
+			Use(handler("default")).
+			Handle("email", handler("email")).
+			Handle("sms", handler("sms")).
+			Handle("", handler("empty")). // ignored, tasks without type are handled by Use()
+			HandleUnknown(handler("unknown"))
+		q.OnSuccess(func(_ *bokchoy.Task) *ekaerr.Error {
+			done <- struct{}{}
+			return nil
+		})
+	})
+	defer stop()
+
+	for data, taskType := range map[string]string{
+		"untyped": "",
+		"email":   "email",
+		"sms":     "sms",
+		"push":    "push",
+	} {
+		task, err := q.Publish(testTaskPayload{Data: data}, bokchoy.WithTaskType(taskType))
+		require.True(t, err.IsNil())
+		require.Equal(t, taskType, task.Type())
+	}
+
+	for i := 0; i < 4; i++ {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Tasks have not been completed in time.")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Equal(t, map[string]string{
+		"untyped": "default",
+		"email":   "email",
+		"sms":     "sms",
+		"push":    "unknown",
+	}, handled)
+}
+
+func TestQueueHandleUnknown(t *testing.T) {
+
+	var (
+		q    *bokchoy.Queue
+		wait func() *bokchoy.Task
+	)
+
+	_, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		q = b.Queue("tasks.test").Handle("email", func(_ *bokchoy.Task) *ekaerr.Error {
+			return nil
+		})
+		wait = waitTask(t, q)
+	}, bokchoy.WithMaxRetries(0))
+	defer stop()
+
+	task, err := q.Publish(testTaskPayload{Data: "hello"}, bokchoy.WithTaskType("push"))
+	require.True(t, err.IsNil())
+
+	task = wait()
+	require.Equal(t, bokchoy.TASK_STATUS_FAILED, task.Status())
+	require.True(t, task.Error.Is(ekaerr.UnsupportedOperation))
+}
+
+func TestQueueHandleBatch(t *testing.T) {
+
+	var (
+		q       *bokchoy.Queue
+		mu      sync.Mutex
+		batches [][]string // tasks' payloads of each batch
+		done    = make(chan *bokchoy.Task, 3)
+	)
+
+	_, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		q = b.Queue("tasks.test").HandleBatch(3, 50*time.Millisecond, func(tasks []*bokchoy.Task) *ekaerr.Error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			batch := make([]string, 0, len(tasks))
+			for _, task := range tasks {
+				data := task.Payload.(testTaskPayload).Data
+				batch = append(batch, data)
+				// Only the first attempt fails.
+				if data == "bad" && len(batches) == 0 {
+					task.Error = ekaerr.ExternalError.New("Bad task.")
+				}
+			}
+
+			batches = append(batches, batch)
+			return nil
+		})
+		q.OnSuccess(func(task *bokchoy.Task) *ekaerr.Error {
+			done <- task
+			return nil
+		})
+
+		for _, data := range []string{"first", "bad", "second"} {
+			_, err := q.Publish(testTaskPayload{Data: data})
+			require.True(t, err.IsNil())
+		}
+	}, bokchoy.WithConcurrency(1), bokchoy.WithMaxRetries(1))
+	defer stop()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case task := <-done:
+			require.Equal(t, bokchoy.TASK_STATUS_SUCCEEDED, task.Status())
+		case <-time.After(5 * time.Second):
+			t.Fatal("Tasks have not been completed in time.")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Equal(t, [][]string{{"first", "bad", "second"}, {"bad"}}, batches)
+}
+
+func TestQueueIdempotency(t *testing.T) {
+
+	var (
+		q       *bokchoy.Queue
+		wait    func() *bokchoy.Task
+		handled int32
+		broker  = brokertest.NewMemoryBroker()
+	)
+
+	_, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		q = b.Queue("tasks.test").Use(func(_ *bokchoy.Task) *ekaerr.Error {
+			atomic.AddInt32(&handled, 1)
+			return nil
+		})
+		wait = waitTask(t, q)
+	}, bokchoy.WithBroker(broker), bokchoy.WithIdempotency(time.Hour))
+	defer stop()
+
+	first, err := q.Publish(testTaskPayload{Data: "first"}, bokchoy.WithIdempotencyKey("key"))
+	require.True(t, err.IsNil())
+	require.Equal(t, "key", first.IdempotencyKey())
+	require.Equal(t, first.ID(), wait().ID())
+
+	// The key is remembered once the succeeded task is saved, after its callbacks.
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		require.True(t, time.Now().Before(deadline), "Idempotency key has not been saved in time.")
+		time.Sleep(time.Millisecond)
+
+		isSucceeded, err := broker.HasIdempotencyKey(q.Name(), "key")
+		require.True(t, err.IsNil())
+
+		if isSucceeded {
+			break
+		}
+	}
+
+	// The same key, so it's succeeded already.
+	second, err := q.Publish(testTaskPayload{Data: "second"}, bokchoy.WithIdempotencyKey("key"))
+	require.True(t, err.IsNil())
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		require.True(t, time.Now().Before(deadline), "Task has not been skipped in time.")
+		time.Sleep(time.Millisecond)
+
+		stored, err := q.Get(second.ID())
+		require.True(t, err.IsNil())
+
+		if stored.Status() == bokchoy.TASK_STATUS_SUCCEEDED {
+			break
+		}
+	}
+
+	require.Equal(t, int32(1), atomic.LoadInt32(&handled))
+
+	// Another key (task's ID by default), so it's handled.
+	third, err := q.Publish(testTaskPayload{Data: "third"})
+	require.True(t, err.IsNil())
+	require.Equal(t, third.ID(), third.IdempotencyKey())
+	require.Equal(t, third.ID(), wait().ID())
+	require.Equal(t, int32(2), atomic.LoadInt32(&handled))
+
+	// Requeued task is handled again, its key is forgotten.
+	_, err = q.Requeue(first.ID())
+	require.True(t, err.IsNil())
+	require.Equal(t, first.ID(), wait().ID())
+	require.Equal(t, int32(3), atomic.LoadInt32(&handled))
+}
+
+func TestQueueIdempotencyConcurrent(t *testing.T) {
+
+	var (
+		q       *bokchoy.Queue
+		handled int32
+		release = make(chan struct{})
+	)
+
+	_, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		q = b.Queue("tasks.test", bokchoy.WithConcurrency(2)).Use(func(_ *bokchoy.Task) *ekaerr.Error {
+			atomic.AddInt32(&handled, 1)
+			select {
+			case <-release:
+			case <-time.After(5 * time.Second): // test is failed, don't lock stopping
+			}
+			return nil
+		})
+	}, bokchoy.WithIdempotency(time.Hour))
+	defer stop()
+
+	first, err := q.Publish(testTaskPayload{Data: "first"}, bokchoy.WithIdempotencyKey("key"))
+	require.True(t, err.IsNil())
+
+	for deadline := time.Now().Add(5 * time.Second); atomic.LoadInt32(&handled) == 0; {
+		require.True(t, time.Now().Before(deadline), "Task has not been started in time.")
+		time.Sleep(time.Millisecond)
+	}
+
+	// The same key is reserved by the first task, that is under processing.
+	second, err := q.Publish(testTaskPayload{Data: "second"}, bokchoy.WithIdempotencyKey("key"))
+	require.True(t, err.IsNil())
+
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, int32(1), atomic.LoadInt32(&handled))
+
+	close(release)
+
+	for _, task := range []*bokchoy.Task{first, second} {
+		for deadline := time.Now().Add(5 * time.Second); ; {
+			require.True(t, time.Now().Before(deadline), "Task has not been succeeded in time.")
+			time.Sleep(time.Millisecond)
+
+			stored, err := q.Get(task.ID())
+			require.True(t, err.IsNil())
+
+			if stored.Status() == bokchoy.TASK_STATUS_SUCCEEDED {
+				break
+			}
+		}
+	}
+
+	require.Equal(t, int32(1), atomic.LoadInt32(&handled))
+}
+
+func TestQueueSetConcurrency(t *testing.T) {
+
+	var (
+		q       *bokchoy.Queue
+		started = make(chan struct{})
+		release = make(chan struct{})
+		done    = make(chan struct{}, 4)
+	)
+
+	_, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		q = b.Queue("tasks.test").Use(func(_ *bokchoy.Task) *ekaerr.Error {
+			started <- struct{}{}
+			<-release
+			done <- struct{}{}
+			return nil
+		})
+	}, bokchoy.WithConcurrency(1))
+	defer stop()
+
+	// Blocked handlers must be released before stop(), even if test is failed.
+	var releaseOnce sync.Once
+	unblock := func() { releaseOnce.Do(func() { close(release) }) }
+	defer unblock()
+
+	// Wait until Bokchoy is running.
+	for deadline := time.Now().Add(5 * time.Second); q.ConsumersStats().Total == 0; {
+		require.True(t, time.Now().Before(deadline), "Consumers have not been started in time.")
+		time.Sleep(time.Millisecond)
+	}
+
+	require.Equal(t, int8(1), q.Concurrency())
+	q.SetConcurrency(4)
+	require.Equal(t, 4, q.ConsumersStats().Total)
+
+	// Each consumer is blocked by its task, so the next task
+	// may be started only by another consumer.
+	for i := 0; i < 4; i++ {
+		_, err := q.Publish(testTaskPayload{Data: "hello world"})
+		require.True(t, err.IsNil())
+
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Task %d has not been started concurrently.", i+1)
+		}
+	}
+
+	unblock()
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+
+	q.SetConcurrency(0)
+	require.Equal(t, int8(1), q.Concurrency())
+	require.Equal(t, 1, q.ConsumersStats().Total)
+}
+
+func TestTaskProgress(t *testing.T) {
+
+	var (
+		q       *bokchoy.Queue
+		release = make(chan struct{})
+	)
+
+	b, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		q = b.Queue("tasks.test").Use(func(task *bokchoy.Task) *ekaerr.Error {
+			if err := task.SetProgress(50, "Half way."); err.IsNotNil() {
+				return err
+			}
+			<-release
+			// Throttled, but saved along with processed task.
+			return task.SetProgress(150, "Done.")
+		})
+	})
+	defer stop()
+
+	events, unsubscribe := b.Events().Channel(16)
+	defer unsubscribe()
+
+	wait := waitTask(t, q)
+
+	task, err := q.Publish(testTaskPayload{Data: "hello world"})
+	require.True(t, err.IsNil())
+
+	for isReported := false; !isReported; {
+		select {
+		case event := <-events:
+			if isReported = event.Type == bokchoy.EVENT_TYPE_TASK_PROGRESS; isReported {
+				percent, message := event.Task.Progress()
+				require.Equal(t, 50, percent)
+				require.Equal(t, "Half way.", message)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Task's progress has not been reported in time.")
+		}
+	}
+
+	stored, err := q.Get(task.ID())
+	require.True(t, err.IsNil())
+	percent, message := stored.Progress()
+	require.Equal(t, 50, percent)
+	require.Equal(t, "Half way.", message)
+
+	close(release)
+	require.Equal(t, bokchoy.TASK_STATUS_SUCCEEDED, wait().Status())
+
+	// Processed task is saved after its callbacks are called.
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		require.True(t, time.Now().Before(deadline), "Processed task has not been saved in time.")
+		time.Sleep(time.Millisecond)
+
+		stored, err = q.Get(task.ID())
+		require.True(t, err.IsNil())
+
+		if stored.Status() == bokchoy.TASK_STATUS_SUCCEEDED {
+			percent, message = stored.Progress()
+			require.Equal(t, 100, percent)
+			require.Equal(t, "Done.", message)
+			break
+		}
+	}
+}
+
+func TestQueuePublishBatch(t *testing.T) {
+
+	brokers := map[string]bokchoy.Broker{
+		"batch publisher": brokertest.NewMemoryBroker(),
+		// Hides BrokerBatchPublisher, so tasks must be published one-by-one.
+		"one by one": struct{ bokchoy.Broker }{brokertest.NewMemoryBroker()},
+	}
+
+	for name, broker := range brokers {
+		t.Run(name, func(t *testing.T) {
+
+			b, err := bokchoy.New(
+				bokchoy.WithBroker(broker),
+				bokchoy.WithSerializer(testTaskPayloadSerializer),
+				bokchoy.WithDisableOutput(true),
+			)
+			require.True(t, err.IsNil())
+
+			q := b.Queue("tasks.test")
+			require.Nil(t, q.PublishBatch(nil))
+
+			tasks := []*bokchoy.Task{
+				q.NewTask(testTaskPayload{Data: "first"}),
+				nil,
+				q.NewTask(testTaskPayload{Data: "second"}),
+			}
+
+			errs := q.PublishBatch(tasks)
+			require.Len(t, errs, len(tasks))
+			require.True(t, errs[0].IsNil())
+			require.True(t, errs[1].IsNotNil())
+			require.True(t, errs[2].IsNil())
+
+			stats, err := q.Count()
+			require.True(t, err.IsNil())
+			require.Equal(t, 2, stats.Direct)
+
+			for _, task := range []*bokchoy.Task{tasks[0], tasks[2]} {
+				stored, err := q.Get(task.ID())
+				require.True(t, err.IsNil())
+				require.Equal(t, task.Payload, stored.Payload)
+			}
+
+			require.Nil(t, q.PublishBatch(tasks[2:]))
+		})
+	}
+}
+
+func TestQueueList(t *testing.T) {
+
+	broker := brokertest.NewMemoryBroker()
+
+	b, err := bokchoy.New(
+		bokchoy.WithBroker(broker),
+		bokchoy.WithSerializer(testTaskPayloadSerializer),
+		bokchoy.WithDisableOutput(true),
+	)
+	require.True(t, err.IsNil())
+
+	q := b.Queue("tasks.test")
+
+	task, err := q.Publish(testTaskPayload{Data: "hello world"})
+	require.True(t, err.IsNil())
+
+	// Task that can't be decoded must not fail the whole listing.
+	require.True(t, broker.Set("tasks.test", "invalid", []byte("invalid"), 0).IsNil())
+
+	tasks, err := q.List()
+	require.True(t, err.IsNil())
+	require.Len(t, tasks, 1)
+	require.Equal(t, task.ID(), tasks[0].ID())
+}
+
+func TestQueueCircuitBreaker(t *testing.T) {
+
+	var q *bokchoy.Queue
+
+	b, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		q = b.Queue("tasks.test").Use(func(task *bokchoy.Task) *ekaerr.Error {
+			if task.Payload.(testTaskPayload).Data == "fail" {
+				return ekaerr.IllegalState.New("Failed.").Throw()
+			}
+			return nil
+		})
+	}, bokchoy.WithConcurrency(2), bokchoy.WithMaxRetries(0), bokchoy.WithCircuitBreaker(0, 2, 50*time.Millisecond))
+	defer stop()
+
+	events, unsubscribe := b.Events().Channel(16)
+	defer unsubscribe()
+
+	// waitEvent skips tasks' events.
+	waitEvent := func() bokchoy.EventType {
+		for {
+			select {
+			case event := <-events:
+				if event.Task == nil {
+					return event.Type
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Circuit breaker's event has not been occurred in time.")
+			}
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := q.Publish(testTaskPayload{Data: "fail"})
+		require.True(t, err.IsNil())
+	}
+
+	require.Equal(t, bokchoy.EVENT_TYPE_CIRCUIT_BREAKER_OPENED, waitEvent())
+	require.Equal(t, 1, q.CircuitBreakerStats().Trips)
+
+	_, err := q.Publish(testTaskPayload{Data: "hello world"})
+	require.True(t, err.IsNil())
+
+	require.Equal(t, bokchoy.EVENT_TYPE_CIRCUIT_BREAKER_HALF_OPENED, waitEvent())
+	require.Equal(t, bokchoy.EVENT_TYPE_CIRCUIT_BREAKER_CLOSED, waitEvent())
+
+	stats := q.CircuitBreakerStats()
+	require.Equal(t, bokchoy.CIRCUIT_BREAKER_STATE_CLOSED, stats.State)
+	require.Equal(t, 0, stats.HandlerErrors)
+
+	// Slave consumer is unfrozen.
+	for deadline := time.Now().Add(5 * time.Second); q.ConsumersStats().Active != 2; {
+		require.True(t, time.Now().Before(deadline), "Consumers have not been unfrozen in time.")
+		time.Sleep(time.Millisecond)
+	}
+}
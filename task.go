@@ -19,10 +19,11 @@
 package bokchoy
 
 import (
-	"encoding/hex"
+	"context"
 	"time"
 
 	"github.com/qioalice/ekago/v3/ekaerr"
+	"github.com/qioalice/ekago/v3/ekalog"
 	"github.com/qioalice/ekago/v3/ekatime"
 	"github.com/qioalice/ekago/v3/ekaunsafe"
 
@@ -31,18 +32,11 @@ import (
 
 type (
 	// Task is the model stored in a Queue.
+	//
+	// Task is encoded using taskEnvelope (task_envelope.go).
+	// If you're adding a new field that must be stored in the Broker,
+	// add it to the taskEnvelope too, following the rules described there.
 	Task struct {
-
-		// WARNING!
-		// DO NOT CHANGE THE ORDER OR FIELD,
-		// OR CHANGE THE ORDER IN taskMsgpackView TOO (task_msgpack_view.go),
-		// AND REGENERATE task_msgpack_view_gen.go, task_msgpack_view_gen_test.go,
-		// THAT ARE GENERATED BY https://github.com/tinylib/msgp .
-
-		// WARNING!
-		// DO NOT ADD NEW FIELDS OR REMOVE OLD ONES
-		// OR DO THE SAME THING AS PREVIOUS WARNING TELLS YOU.
-
 		Error          *ekaerr.Error
 		Panic          interface{}
 
@@ -59,9 +53,22 @@ type (
 
 		Payload        interface{}
 
+		// Headers is a Task's metadata, that is stored separately from Payload.
+		// See WithHeader() option.
+		Headers        map[string]string
+
 		id             string
 		queueName      string
+		taskType       string // see WithTaskType()
+		isPayloadTyped bool   // see Register(), Queue.SerializerOfTask()
+		workerID       string // see WorkerID()
+
+		progress       int8   // percent, see SetProgress()
+		progressMsg    string // see SetProgress()
+
+		idempotencyKey string // see WithIdempotencyKey()
 
+		enqueuedAt     int64 // unix nano, see EnqueuedAt()
 		startedAt      int64 // unix nano
 		processedAt    int64 // unix nano
 
@@ -69,6 +76,16 @@ type (
 
 		payloadEncoded []byte
 		payloadOldAddr uintptr
+
+		logger         *ekalog.Logger // not encoded, set by consumer
+
+		ctx            context.Context   // not encoded, see WithContext()
+		traceContext   map[string]string // see Tracer
+
+		cancellation   *taskCancellation // not encoded, set by consumer
+		progressSaver  *taskProgress     // not encoded, set by consumer
+
+		isKeyReserved  bool // not encoded, set by consumer, see WithIdempotency()
 	}
 )
 
@@ -98,6 +115,89 @@ func (t *Task) QueueName() string {
 	return t.queueName
 }
 
+// Type returns a type of the current Task, that has been set by WithTaskType() option
+// at the publishing. It's used to dispatch Task to its handlers (see Queue.Handle()).
+// Returns an empty string if Task has no type or Task is invalid.
+func (t *Task) Type() string {
+	if !t.isValid() {
+		return ""
+	}
+	return t.taskType
+}
+
+// WorkerID returns an ID of the worker (see WorkerInfo), that is processing
+// the current Task, or has been processing it the last time.
+// Returns an empty string if Task has never been processed
+// by the registered worker (see WithHeartbeatInterval()) or Task is invalid.
+func (t *Task) WorkerID() string {
+	if !t.isValid() {
+		return ""
+	}
+	return t.workerID
+}
+
+// IdempotencyKey returns a key, the current Task is considered succeeded already by,
+// if there is another succeeded Task with the same key (see WithIdempotency()).
+// It's a key, that has been set by WithIdempotencyKey() option at the publishing,
+// or Task's ID otherwise. Returns an empty string if Task is invalid.
+func (t *Task) IdempotencyKey() string {
+	if !t.isValid() {
+		return ""
+	}
+	if t.idempotencyKey != "" {
+		return t.idempotencyKey
+	}
+	return t.id
+}
+
+// Progress returns the Task's progress (percent from 0 to 100 and a message),
+// that has been set by SetProgress().
+func (t *Task) Progress() (percent int, message string) {
+	if !t.isValid() {
+		return 0, ""
+	}
+	return int(t.progress), t.progressMsg
+}
+
+// SetProgress sets the progress of the current Task: percent from 0 to 100
+// (it's clamped if it's out of range) and an optional message.
+// It's meant to be called from HandlerFunc of long-running tasks.
+//
+// If the Task is under processing, it's saved to the Broker along with its
+// progress (see Queue.Get()) and EVENT_TYPE_TASK_PROGRESS is occurred,
+// but not more often than once per second. Anyway, the last progress is saved
+// along with processed Task. Returns an error only if Task could not be saved.
+// The progress is ignored once the Task is timed out (see WithTimeout()).
+//
+// WARNING!
+// Do not call it from another goroutine, than HandlerFunc is called from.
+func (t *Task) SetProgress(percent int, message string) *ekaerr.Error {
+	const s = "Bokchoy: Failed to save task's progress. "
+
+	if !t.isValid() {
+		return ekaerr.IllegalArgument.
+			New(s + "Task is invalid. Has it been initialized correctly?").
+			WithString("bokchoy_task_why_invalid", t.whyInvalid()).
+			Throw()
+	}
+
+	switch {
+	case percent < 0:   percent = 0
+	case percent > 100: percent = 100
+	}
+
+	if t.progressSaver == nil {
+		t.progress = int8(percent)
+		t.progressMsg = message
+		return nil
+	}
+
+	return t.progressSaver.save(t, int8(percent), message).
+		AddMessage(s).
+		WithString("bokchoy_task_id", t.id).
+		Throw()
+}
+
 // Status returns the Task's status, that:
 //  - Has been sent by you, or
 //  - Task had at the moment when you retrieve the Task from a Bokchoy backend.
@@ -116,6 +216,110 @@ func (t *Task) Status() TaskStatus {
 	return t.status
 }
 
+// EnqueuedAt returns the time when the Task has been published,
+// or returned back to the pool to be retried the last time, or requeued.
+// Unlike PublishedAt, it has nanoseconds precision and it's changed on each retry.
+// Returns zero time.Time if Task has been published by the older version.
+func (t *Task) EnqueuedAt() time.Time {
+	if !t.isValid() || t.enqueuedAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, t.enqueuedAt).UTC()
+}
+
+// StartedAt returns the time when the last processing attempt of the Task
+// has been started. Returns zero time.Time if Task has never been processed.
+func (t *Task) StartedAt() time.Time {
+	if !t.isValid() || t.startedAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, t.startedAt).UTC()
+}
+
+// ProcessedAt returns the time when the last processing attempt of the Task
+// has been finished (or when Task has been cancelled).
+// Returns zero time.Time if Task has never been processed.
+func (t *Task) ProcessedAt() time.Time {
+	if !t.isValid() || t.processedAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, t.processedAt).UTC()
+}
+
+// Header returns a value of Task's header with the given key.
+// Returns an empty string if there is no such header or Task is invalid.
+func (t *Task) Header(key string) string {
+	if !t.isValid() {
+		return ""
+	}
+	return t.Headers[key]
+}
+
+// SetHeader sets a Task's header with the given key and value.
+// The changes will be saved along with Task.
+// Does nothing if Task is invalid.
+func (t *Task) SetHeader(key, value string) {
+	if !t.isValid() {
+		return
+	}
+	if t.Headers == nil {
+		t.Headers = make(map[string]string)
+	}
+	t.Headers[key] = value
+}
+
+// Logger returns a logger, that is associated with the current Task
+// while it's under processing. By default it's the Bokchoy's one.
+// Middlewares may replace it (using SetLogger()) by a logger with an additional
+// fields, and then handlers and callbacks will log with them.
+//
+// Returned logger may be shared. Use Logger().Copy() to add your own fields.
+//
+// Never returns nil. Returns a copy of ekalog's default logger
+// if there is no associated logger (e.g. Task is not under processing).
+func (t *Task) Logger() *ekalog.Logger {
+	if !t.isValid() || t.logger == nil {
+		return ekalog.Copy()
+	}
+	return t.logger
+}
+
+// SetLogger replaces a logger, that is associated with the current Task
+// while it's under processing. See Logger() for more details.
+// Does nothing if either Task is invalid or logger is nil.
+func (t *Task) SetLogger(logger *ekalog.Logger) {
+	if !t.isValid() || logger == nil {
+		return
+	}
+	t.logger = logger
+}
+
+// Context returns a context.Context, that is associated with the current Task.
+//
+// At the publishing it's the context passed by WithContext() option
+// (or set by SetContext()), the current span of which (see Tracer)
+// is propagated to the consumer.
+// While Task is under processing, it's the context of processing span,
+// that should be passed to all your calls made from handlers.
+//
+// Never returns nil. Returns context.Background() if there is no associated context.
+func (t *Task) Context() context.Context {
+	if !t.isValid() || t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
+}
+
+// SetContext replaces a context.Context, that is associated with the current Task.
+// See Context() for more details.
+// Does nothing if either Task is invalid or ctx is nil.
+func (t *Task) SetContext(ctx context.Context) {
+	if !t.isValid() || ctx == nil {
+		return
+	}
+	t.ctx = ctx
+}
+
 func (t *Task) MarkAsSucceeded() {
 	t.processedAt = time.Now().UTC().UnixNano()
 	t.status = TASK_STATUS_SUCCEEDED
@@ -172,7 +376,7 @@ func (t *Task) Serialize(userPayloadSerializer Serializer) ([]byte, *ekaerr.Erro
 		t.payloadEncoded = encodedPayload
 	}
 
-	output, legacyErr := t.toMsgpackView().MarshalMsg(nil)
+	output, legacyErr := t.toEnvelope().encode()
 	if legacyErr != nil {
 		return nil, ekaerr.ExternalError.
 			Wrap(legacyErr, s + "Failed to encode task object.").
@@ -185,6 +389,8 @@ func (t *Task) Serialize(userPayloadSerializer Serializer) ([]byte, *ekaerr.Erro
 }
 
 // Deserialize returns a Task instance from raw data.
+// Signed data (see WithSigningKey()) is rejected, because its signature
+// can not be verified here. Use Queue.Get() to read signed tasks.
 func (t *Task) Deserialize(data []byte, userPayloadSerializer Serializer) *ekaerr.Error {
 	const s = "Bokchoy: Failed to decode task using msgpack. "
 	switch {
@@ -204,21 +410,14 @@ func (t *Task) Deserialize(data []byte, userPayloadSerializer Serializer) *ekaer
 			New(s + "User payload serializer is nil.").
 			Throw()
 	}
-	_, legacyErr := t.toMsgpackView().UnmarshalMsg(data)
-	if legacyErr != nil {
-		return ekaerr.ExternalError.
-			Wrap(legacyErr, s + "Failed to decode task object.").
-			WithString("bokchoy_task_encoded_as_hex", hex.EncodeToString(data)).
-			Throw()
+
+	if err := t.deserializeEnvelope(data); err.IsNotNil() {
+		return err.AddMessage(s).Throw()
 	}
 
-	if err := userPayloadSerializer.Loads(t.payloadEncoded, &t.Payload); err.IsNotNil() {
-		return err.AddMessage(s + "Failed to deserialize user payload.").
-			WithString("bokchoy_task_id", t.id).
-			WithString("bokchoy_task_user_payload_as_hex", hex.EncodeToString(t.payloadEncoded)).
-			Throw()
+	if err := t.deserializePayload(userPayloadSerializer); err.IsNotNil() {
+		return err.AddMessage(s).Throw()
 	}
 
-	t.payloadOldAddr = uintptr(ekaunsafe.TakeRealAddr(t.Payload))
 	return nil
 }
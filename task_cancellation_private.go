@@ -0,0 +1,233 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+)
+
+type (
+	// taskCancellation is a cancellation state of the Task under processing.
+	// See Queue.Cancel(), Queue.trackProcessing().
+	//
+	// It's a separate object, because Task might be copied (see Task.snapshot())
+	// while it's under processing, but the state must be shared.
+	taskCancellation struct {
+		requested int32 // protected by atomic operations
+		cancel    context.CancelFunc
+	}
+)
+
+// isCancellationRequested reports whether the cancellation of the current Task
+// has been requested while it's under processing.
+func (t *Task) isCancellationRequested() bool {
+	return t.cancellation != nil && atomic.LoadInt32(&t.cancellation.requested) == 1
+}
+
+// trackProcessing makes the consumed Task, that is going to be processed,
+// cancellable by its ID (see cancelProcessing()),
+// replacing its context by a cancellable one.
+//
+// It's called right after the Task is consumed, before its stored status
+// is checked (see isCancelled()). So, Queue.Cancel() is never lost:
+// it's either saved before that check, or it's notified after the tracking.
+// untrackProcessing() must be called when Task's processing is done or skipped.
+func (q *Queue) trackProcessing(t *Task) {
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancellation := &taskCancellation{cancel: cancel}
+
+	t.ctx = ctx
+	t.cancellation = cancellation
+
+	q.processingMu.Lock()
+	if q.processing == nil {
+		q.processing = make(map[string]*taskCancellation)
+	}
+	q.processing[t.id] = cancellation
+	q.processingMu.Unlock()
+}
+
+// untrackProcessing makes the Task, which processing is done,
+// not cancellable anymore. See trackProcessing().
+func (q *Queue) untrackProcessing(t *Task) {
+
+	cancellation := t.cancellation
+	if cancellation == nil {
+		return
+	}
+
+	q.processingMu.Lock()
+	if q.processing[t.id] == cancellation {
+		delete(q.processing, t.id)
+	}
+	q.processingMu.Unlock()
+
+	cancellation.cancel() // GC context
+}
+
+// cancelProcessing requests the cancellation of the Task with the given ID,
+// if it's under processing by the current Bokchoy's consumers,
+// cancelling its context.
+// Reports whether such Task has been found.
+func (q *Queue) cancelProcessing(taskID string) bool {
+
+	q.processingMu.Lock()
+	cancellation := q.processing[taskID]
+	q.processingMu.Unlock()
+
+	if cancellation == nil {
+		return false
+	}
+
+	atomic.StoreInt32(&cancellation.requested, 1)
+	cancellation.cancel()
+
+	q.parent.logger.Copy().
+		WithString("bokchoy_queue_name", q.name).
+		WithString("bokchoy_task_id", taskID).
+		Debug("Bokchoy: Cancellation of the task under processing is requested.")
+
+	return true
+}
+
+// notifyCancellation delivers a cancellation signal of the Task with the given ID
+// to the consumer, that is processing it.
+//
+// If Broker implements BrokerCancellationNotifier, the signal is sent through it,
+// and it will be received by any Bokchoy's instance (including the current one,
+// see subscribeCancellation()). Otherwise only the current instance is notified.
+func (q *Queue) notifyCancellation(taskID string) *ekaerr.Error {
+
+	notifier, ok := q.parent.broker.(BrokerCancellationNotifier)
+	if !ok {
+		q.cancelProcessing(taskID)
+		return nil
+	}
+
+	return notifier.NotifyCancellation(q.name, taskID).
+		Throw()
+}
+
+// removePending removes the cancelled Task with the given ID
+// from the Broker's pending structures, if Broker implements BrokerPendingRemover.
+// Broker's error is only logged, because the Task will be dropped
+// by the consumer anyway (see isCancelled()).
+func (q *Queue) removePending(taskID string) {
+	const s = "Bokchoy: Failed to remove cancelled task from the pending ones. " +
+		"It will be dropped by the consumer. "
+
+	remover, ok := q.parent.broker.(BrokerPendingRemover)
+	if !ok {
+		return
+	}
+
+	if err := remover.RemovePending(q.name, taskID); err.IsNotNil() {
+		q.parent.logger.Copy().
+			WithString("bokchoy_queue_name", q.name).
+			WithString("bokchoy_task_id", taskID).
+			Warne(s, err)
+	}
+}
+
+// isCancelled reports whether the consumed Task has been cancelled
+// after it's been published (see Queue.Cancel()).
+// The Task must be tracked already (see trackProcessing()).
+//
+// The stored status is checked even if Broker implements BrokerPendingRemover,
+// because the Task might be cancelled after it's been consumed (removed
+// from the Broker's pending structures) but before it's been tracked.
+// The consumed copy is the published one, it knows nothing about that.
+//
+// Broker's error is only logged and Task is considered not cancelled then.
+// It's better to process the cancelled Task than to lose the not cancelled one.
+func (q *Queue) isCancelled(t *Task) bool {
+	const s = "Bokchoy: Failed to check whether consumed task is cancelled. " +
+		"It will be processed. "
+
+	if t.status == TASK_STATUS_CANCELLED || t.isCancellationRequested() {
+		return true
+	}
+
+	status, err := q.storedStatus(t.id)
+	if err.IsNotNil() {
+		q.parent.logger.Copy().
+			WithString("bokchoy_queue_name", q.name).
+			WithString("bokchoy_task_id", t.id).
+			Warne(s, err)
+		return false
+	}
+
+	return status == TASK_STATUS_CANCELLED
+}
+
+// storedStatus returns the status of the stored Task with the given ID,
+// decoding only its envelope, not its payload.
+// Returns TASK_STATUS_INVALID if there is no such Task.
+func (q *Queue) storedStatus(taskID string) (TaskStatus, *ekaerr.Error) {
+	const s = "Bokchoy: Failed to retrieve status of the stored task. "
+
+	encodedTask, err := q.parent.broker.Get(q.name, taskID)
+	if err.IsNotNil() || len(encodedTask) == 0 {
+		return TASK_STATUS_INVALID, err.AddMessage(s).Throw()
+	}
+
+	encodedTask, isSignatureValid := verifyTask(encodedTask, q.options.SigningKey)
+	if !isSignatureValid {
+		return TASK_STATUS_INVALID, ekaerr.RejectedOperation.
+			New(s + "Signature is invalid or absent. Task has been tampered?").
+			Throw()
+	}
+
+	var t Task
+	if err = t.deserializeEnvelope(encodedTask); err.IsNotNil() {
+		return TASK_STATUS_INVALID, err.AddMessage(s).Throw()
+	}
+
+	return t.status, nil
+}
+
+// subscribeCancellation subscribes to the cancellation signals of the Queue's tasks,
+// if Broker implements BrokerCancellationNotifier.
+func (q *Queue) subscribeCancellation() {
+	const s = "Bokchoy: Failed to subscribe to the cancellation signals. " +
+		"Tasks, that are cancelled by another instance, won't be interrupted. "
+
+	notifier, ok := q.parent.broker.(BrokerCancellationNotifier)
+	if !ok {
+		return
+	}
+
+	callback := func(taskID string) {
+		q.cancelProcessing(taskID)
+	}
+
+	unsubscribe, err := notifier.SubscribeCancellation(q.name, callback)
+	if err.IsNotNil() {
+		q.parent.logger.Copy().
+			WithString("bokchoy_queue_name", q.name).
+			Errore(s, err)
+		return
+	}
+
+	q.unsubscribeCancellation = unsubscribe
+}
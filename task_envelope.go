@@ -0,0 +1,282 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+	"github.com/qioalice/ekago/v3/ekatime"
+)
+
+//go:generate msgp -unexported -file task_envelope.go
+
+type (
+	// taskEnvelope is an encoding representation of Task, that is stored
+	// in the Broker. It's decoupled from Task's memory layout,
+	// and Task's fields are copied from/to it at the encoding/decoding.
+	//
+	// Each field is tagged, thus encoded taskEnvelope is a msgpack map,
+	// that allows to be forward and backward compatible:
+	//
+	//  - A decoder skips fields it doesn't know about
+	//    (encoded by newer version of this package),
+	//  - Fields that are absent in encoded data (encoded by older version)
+	//    are zero values after decoding.
+	//
+	// So, feel free to add new fields (with new unique tags),
+	// but NEVER CHANGE the tags or types of existed ones.
+	// If you absolutely have to, bump _TASK_ENVELOPE_VERSION.
+	//
+	// Code of encoding/decoding is generated by https://github.com/tinylib/msgp
+	// (task_envelope_gen.go, task_envelope_gen_test.go). Regenerate it
+	// using "go generate" after changing this type.
+	taskEnvelope struct {
+		PublishedAt    int64   `msg:"pl"` // real type: ekatime.Timestamp
+
+		TTL            int64   `msg:"tl"` // real type: time.Duration
+		ETA            int64   `msg:"et"` // real type: ekatime.Timestamp
+
+		RetryIntervals []int64 `msg:"ri"` // real type: []time.Duration
+		MaxRetries     int8    `msg:"re"`
+
+		ExecTime       int64   `msg:"ex"` // real type: time.Duration
+		Timeout        int64   `msg:"to"` // real type: time.Duration
+
+		ID             string  `msg:"id"`
+
+		StartedAt      int64   `msg:"st"`
+		ProcessedAt    int64   `msg:"pr"`
+
+		Status         int8    `msg:"s"`  // real type: TaskStatus
+
+		PayloadEncoded []byte  `msg:"p"`
+
+		Error          *taskEnvelopeError `msg:"er,omitempty"` // real type: *ekaerr.Error
+		Panic          string             `msg:"pn,omitempty"` // real type: interface{}
+
+		Headers        map[string]string  `msg:"hd,omitempty"`
+		TraceContext   map[string]string  `msg:"tc,omitempty"` // see Tracer
+
+		Type           string             `msg:"ty,omitempty"` // see WithTaskType()
+		IsPayloadTyped bool               `msg:"pt,omitempty"` // see Register()
+		WorkerID       string             `msg:"wi,omitempty"` // see WorkerInfo
+
+		Progress       int8               `msg:"pg,omitempty"` // see Task.SetProgress()
+		ProgressMsg    string             `msg:"pm,omitempty"`
+
+		IdempotencyKey string             `msg:"ik,omitempty"` // see WithIdempotencyKey()
+
+		EnqueuedAt     int64              `msg:"eq,omitempty"` // see Task.EnqueuedAt()
+	}
+
+	// taskEnvelopeError is an encoding representation of *ekaerr.Error,
+	// that is a part of taskEnvelope. See Task.Error.
+	//
+	// Error's messages and fields are linked to the stack frames,
+	// by the index of frame in Stack.
+	taskEnvelopeError struct {
+		ID             string                        `msg:"id"`
+		Class          string                        `msg:"cl"` // full name of ekaerr.Class
+		Messages       []taskEnvelopeErrorMessage    `msg:"ms"`
+		Fields         []taskEnvelopeErrorField      `msg:"fs"`
+		Stack          []taskEnvelopeErrorStackFrame `msg:"sf"`
+	}
+
+	taskEnvelopeErrorMessage struct {
+		Body           string                        `msg:"b"`
+		StackFrameIdx  int16                         `msg:"i"`
+	}
+
+	// taskEnvelopeErrorField is a field of *ekaerr.Error.
+	// Whatever the original type of field's value was,
+	// it's encoded as a string and will be restored as a string.
+	taskEnvelopeErrorField struct {
+		Key            string                        `msg:"k"`
+		Value          string                        `msg:"v"`
+		StackFrameIdx  int16                         `msg:"i"`
+	}
+
+	taskEnvelopeErrorStackFrame struct {
+		Function       string                        `msg:"fn"`
+		File           string                        `msg:"f"`
+		Line           int                           `msg:"l"`
+	}
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	// _TASK_ENVELOPE_VERSION is the version of the encoded taskEnvelope's format,
+	// that is written as the first byte of encoded Task.
+	//
+	// It must be increased ONLY IF the format is changed incompatibly.
+	// Adding new fields to the taskEnvelope is compatible change.
+	//
+	// Tasks that are encoded by an older version of this package
+	// (before the version byte has been introduced) are the msgpack map
+	// w/o any version byte. They are considered as _TASK_ENVELOPE_VERSION_LEGACY
+	// and still can be decoded.
+	_TASK_ENVELOPE_VERSION        byte = 1
+	_TASK_ENVELOPE_VERSION_LEGACY byte = 0
+)
+
+// toEnvelope returns a new taskEnvelope, filled by the current Task's fields.
+func (t *Task) toEnvelope() *taskEnvelope {
+
+	env := &taskEnvelope{
+		PublishedAt:    int64(t.PublishedAt),
+		TTL:            t.TTL.Nanoseconds(),
+		ETA:            t.ETA,
+		MaxRetries:     t.MaxRetries,
+		ExecTime:       t.ExecTime.Nanoseconds(),
+		Timeout:        t.Timeout.Nanoseconds(),
+		ID:             t.id,
+		StartedAt:      t.startedAt,
+		ProcessedAt:    t.processedAt,
+		Status:         int8(t.status),
+		PayloadEncoded: t.payloadEncoded,
+		Error:          newTaskEnvelopeError(t.Error),
+		Headers:        t.Headers,
+		TraceContext:   t.traceContext,
+		Type:           t.taskType,
+		IsPayloadTyped: t.isPayloadTyped,
+		WorkerID:       t.workerID,
+		Progress:       t.progress,
+		ProgressMsg:    t.progressMsg,
+		IdempotencyKey: t.idempotencyKey,
+		EnqueuedAt:     t.enqueuedAt,
+	}
+
+	if t.Panic != nil {
+		env.Panic = fmt.Sprintf("%+v", t.Panic)
+	}
+
+	if len(t.RetryIntervals) > 0 {
+		env.RetryIntervals = make([]int64, len(t.RetryIntervals))
+		for i, n := 0, len(t.RetryIntervals); i < n; i++ {
+			env.RetryIntervals[i] = t.RetryIntervals[i].Nanoseconds()
+		}
+	}
+
+	return env
+}
+
+// applyTo copies the current taskEnvelope's fields to the presented Task.
+func (env *taskEnvelope) applyTo(t *Task) {
+
+	t.PublishedAt = ekatime.Timestamp(env.PublishedAt)
+	t.TTL = time.Duration(env.TTL)
+	t.ETA = env.ETA
+	t.MaxRetries = env.MaxRetries
+	t.ExecTime = time.Duration(env.ExecTime)
+	t.Timeout = time.Duration(env.Timeout)
+	t.id = env.ID
+	t.startedAt = env.StartedAt
+	t.processedAt = env.ProcessedAt
+	t.status = TaskStatus(env.Status)
+	t.payloadEncoded = env.PayloadEncoded
+	t.Error = env.Error.toError()
+	t.Headers = env.Headers
+	t.traceContext = env.TraceContext
+	t.taskType = env.Type
+	t.isPayloadTyped = env.IsPayloadTyped
+	t.workerID = env.WorkerID
+	t.progress = env.Progress
+	t.progressMsg = env.ProgressMsg
+	t.idempotencyKey = env.IdempotencyKey
+	t.enqueuedAt = env.EnqueuedAt
+
+	t.Panic = nil
+	if env.Panic != "" {
+		t.Panic = env.Panic
+	}
+
+	t.RetryIntervals = nil
+	if len(env.RetryIntervals) > 0 {
+		t.RetryIntervals = make([]time.Duration, len(env.RetryIntervals))
+		for i, n := 0, len(env.RetryIntervals); i < n; i++ {
+			t.RetryIntervals[i] = time.Duration(env.RetryIntervals[i])
+		}
+	}
+}
+
+// encode returns encoded taskEnvelope prepended by _TASK_ENVELOPE_VERSION byte.
+func (env *taskEnvelope) encode() ([]byte, error) {
+	return env.MarshalMsg([]byte{_TASK_ENVELOPE_VERSION})
+}
+
+// decode decodes data, that has been encoded by encode() or by an older version
+// of this package (legacy format, w/o version byte), to the current taskEnvelope.
+func (env *taskEnvelope) decode(data []byte) *ekaerr.Error {
+	const s = "Bokchoy: Failed to decode task envelope. "
+
+	version := taskEnvelopeVersion(data)
+	switch {
+
+	// Signature must be verified and stripped by verifyTask() before decoding.
+	case version == _TASK_SIGNATURE_MARKER:
+		return ekaerr.RejectedOperation.
+			New(s + "Task is signed, but its signature has not been verified. " +
+				"Is signing key presented (WithSigningKey())?").
+			Throw()
+
+	case version == _TASK_ENVELOPE_VERSION:
+		data = data[1:]
+
+	case version > _TASK_ENVELOPE_VERSION:
+		return ekaerr.UnsupportedVersion.
+			New(s + "Task has been encoded by newer incompatible version of Bokchoy.").
+			WithInt("bokchoy_task_envelope_version_got", int(version)).
+			WithInt("bokchoy_task_envelope_version_max", int(_TASK_ENVELOPE_VERSION)).
+			Throw()
+	}
+
+	rest, legacyErr := env.UnmarshalMsg(data)
+	switch {
+
+	case legacyErr != nil:
+		return ekaerr.ExternalError.
+			Wrap(legacyErr, s).
+			WithInt("bokchoy_task_envelope_version", int(version)).
+			Throw()
+
+	case len(rest) != 0:
+		return ekaerr.IllegalFormat.
+			New(s + "Unexpected data after the encoded task.").
+			WithInt("bokchoy_task_envelope_rest_len", len(rest)).
+			Throw()
+	}
+
+	return nil
+}
+
+// taskEnvelopeVersion returns a version of encoded taskEnvelope.
+// Returns _TASK_ENVELOPE_VERSION_LEGACY if data starts with msgpack map header,
+// meaning there is no version byte.
+func taskEnvelopeVersion(data []byte) byte {
+	switch b := data[0]; {
+	case b >= 0x80 && b <= 0x8F: // fixmap
+		return _TASK_ENVELOPE_VERSION_LEGACY
+	case b == 0xDE || b == 0xDF: // map 16, map 32
+		return _TASK_ENVELOPE_VERSION_LEGACY
+	default:
+		return b
+	}
+}
@@ -0,0 +1,242 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+	"github.com/qioalice/ekago/v3/ekasys"
+	"github.com/qioalice/ekago/v3/ekaunsafe"
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	// Keys of ekaerr.Error's system fields, that are stored along with Task's error.
+	// Mirror the keys from ekago's ekaerr package, that are not exported.
+
+	_EKAERR_SYS_FIELD_KEY_CLASS_NAME = "class_name"
+	_EKAERR_SYS_FIELD_KEY_ERROR_ID   = "error_id"
+)
+
+// ekaerrBuiltinClasses are the ekaerr.Class es, that can be restored
+// by their full names, when *ekaerr.Error is decoded.
+// Errors of all other classes are restored as ekaerr.ExternalError,
+// but with the original class's name.
+var ekaerrBuiltinClasses = []ekaerr.Class{
+	ekaerr.NotFound,
+	ekaerr.AlreadyExist,
+	ekaerr.IllegalArgument,
+	ekaerr.IllegalState,
+	ekaerr.IllegalFormat,
+	ekaerr.InitializationFailed,
+	ekaerr.DataUnavailable,
+	ekaerr.ServiceUnavailable,
+	ekaerr.UnsupportedOperation,
+	ekaerr.RejectedOperation,
+	ekaerr.Interrupted,
+	ekaerr.AssertionFailed,
+	ekaerr.InternalError,
+	ekaerr.ExternalError,
+	ekaerr.ConcurrentUpdate,
+	ekaerr.TimeoutElapsed,
+	ekaerr.NotImplemented,
+	ekaerr.UnsupportedVersion,
+}
+
+// newTaskEnvelopeError returns a new taskEnvelopeError,
+// filled by the presented *ekaerr.Error's ID, class, messages, fields
+// and stacktrace.
+// Returns nil if err is nil or invalid.
+func newTaskEnvelopeError(err *ekaerr.Error) *taskEnvelopeError {
+
+	l := ekaunsafe.ErrorGetLetter(err)
+	if l == nil {
+		return nil
+	}
+
+	env := &taskEnvelopeError{
+		ID:       err.ID(),
+		Class:    err.Class().FullName(),
+		Messages: make([]taskEnvelopeErrorMessage, 0, len(l.Messages)),
+		Fields:   make([]taskEnvelopeErrorField, 0, len(l.Fields)),
+		Stack:    make([]taskEnvelopeErrorStackFrame, len(l.StackTrace)),
+	}
+
+	for i, n := 0, len(l.SystemFields); i < n; i++ {
+		if l.SystemFields[i].Key == _EKAERR_SYS_FIELD_KEY_CLASS_NAME {
+			env.Class = l.SystemFields[i].SValue
+		}
+	}
+
+	for i, n := 0, len(l.Messages); i < n; i++ {
+		if l.Messages[i].Body != "" {
+			env.Messages = append(env.Messages, taskEnvelopeErrorMessage{
+				Body:          l.Messages[i].Body,
+				StackFrameIdx: l.Messages[i].StackFrameIdx,
+			})
+		}
+	}
+
+	for i, n := 0, len(l.Fields); i < n; i++ {
+		f := &l.Fields[i]
+
+		var value string
+		switch baseType := f.BaseType(); {
+
+		case f.IsNil():
+			value = "<nil>"
+
+		case baseType == ekaunsafe.FIELD_KIND_TYPE_BOOL:
+			value = strconv.FormatBool(f.IValue != 0)
+
+		case baseType >= ekaunsafe.FIELD_KIND_TYPE_INT && baseType <= ekaunsafe.FIELD_KIND_TYPE_INT_64:
+			value = strconv.FormatInt(f.IValue, 10)
+
+		case baseType >= ekaunsafe.FIELD_KIND_TYPE_UINT && baseType <= ekaunsafe.FIELD_KIND_TYPE_UINTPTR:
+			value = strconv.FormatUint(uint64(f.IValue), 10)
+
+		case baseType == ekaunsafe.FIELD_KIND_TYPE_FLOAT_32:
+			value = strconv.FormatFloat(float64(math.Float32frombits(uint32(f.IValue))), 'f', -1, 32)
+
+		case baseType == ekaunsafe.FIELD_KIND_TYPE_FLOAT_64:
+			value = strconv.FormatFloat(math.Float64frombits(uint64(f.IValue)), 'f', -1, 64)
+
+		// Real and imaginary parts are stored as float32 bits in the high and low halves.
+		case baseType == ekaunsafe.FIELD_KIND_TYPE_COMPLEX_64:
+			value = strconv.FormatComplex(complex128(complex(
+				math.Float32frombits(uint32(f.IValue >> 32)),
+				math.Float32frombits(uint32(f.IValue)),
+			)), 'f', -1, 64)
+
+		case baseType == ekaunsafe.FIELD_KIND_TYPE_ADDR:
+			value = "0x" + strconv.FormatUint(uint64(f.IValue), 16)
+
+		case baseType == ekaunsafe.FIELD_KIND_TYPE_UNIX:
+			value = time.Unix(f.IValue, 0).UTC().Format(time.RFC3339)
+
+		case baseType == ekaunsafe.FIELD_KIND_TYPE_UNIX_NANO:
+			value = time.Unix(0, f.IValue).UTC().Format(time.RFC3339Nano)
+
+		case baseType == ekaunsafe.FIELD_KIND_TYPE_DURATION:
+			value = time.Duration(f.IValue).String()
+
+		case f.Value != nil:
+			value = fmt.Sprintf("%+v", f.Value)
+
+		default:
+			value = f.SValue
+		}
+
+		env.Fields = append(env.Fields, taskEnvelopeErrorField{
+			Key:           f.Key,
+			Value:         value,
+			StackFrameIdx: f.StackFrameIdx,
+		})
+	}
+
+	for i, n := 0, len(l.StackTrace); i < n; i++ {
+		env.Stack[i] = taskEnvelopeErrorStackFrame{
+			Function: l.StackTrace[i].Function,
+			File:     l.StackTrace[i].File,
+			Line:     l.StackTrace[i].Line,
+		}
+	}
+
+	return env
+}
+
+// toError returns a new *ekaerr.Error, restored from the current taskEnvelopeError.
+// Returns nil if env is nil.
+//
+// Restored error has the same ID, class's name, messages, stacktrace
+// and fields, but fields' values are strings.
+// Its ekaerr.Class is the same only if it's one of builtin ekago's classes,
+// ekaerr.ExternalError otherwise.
+func (env *taskEnvelopeError) toError() *ekaerr.Error {
+
+	if env == nil {
+		return nil
+	}
+
+	cls := ekaerr.ExternalError
+	for i, n := 0, len(ekaerrBuiltinClasses); i < n; i++ {
+		if ekaerrBuiltinClasses[i].FullName() == env.Class {
+			cls = ekaerrBuiltinClasses[i]
+			break
+		}
+	}
+
+	// Message will be overwritten below,
+	// but its RAM will be reused to restore original messages.
+	err := cls.New("-")
+
+	if len(env.Stack) > 0 {
+		ekaunsafe.ErrorUpdateStacktrace(err, func(_ ekasys.StackTrace) ekasys.StackTrace {
+			stacktrace := make(ekasys.StackTrace, len(env.Stack))
+			for i, n := 0, len(env.Stack); i < n; i++ {
+				stacktrace[i].Frame = runtime.Frame{
+					Function: env.Stack[i].Function,
+					File:     env.Stack[i].File,
+					Line:     env.Stack[i].Line,
+				}
+			}
+			return stacktrace
+		})
+	}
+
+	l := ekaunsafe.ErrorGetLetter(err)
+
+	for i, n := 0, len(l.SystemFields); i < n; i++ {
+		switch l.SystemFields[i].Key {
+		case _EKAERR_SYS_FIELD_KEY_CLASS_NAME:
+			l.SystemFields[i].SValue = env.Class
+		case _EKAERR_SYS_FIELD_KEY_ERROR_ID:
+			l.SystemFields[i].SValue = env.ID
+		}
+	}
+
+	// ekaerr.Error guarantees that Messages and Fields are sorted by stack frame idx,
+	// and each index is less than len of stacktrace.
+	// So, messages and fields of broken stack frames are skipped.
+
+	message := l.Messages[0]
+	l.Messages = l.Messages[:0]
+
+	for i, n := 0, len(env.Messages); i < n; i++ {
+		if int(env.Messages[i].StackFrameIdx) < len(l.StackTrace) {
+			message.Body = env.Messages[i].Body
+			message.StackFrameIdx = env.Messages[i].StackFrameIdx
+			l.Messages = append(l.Messages, message)
+		}
+	}
+
+	for i, n := 0, len(env.Fields); i < n; i++ {
+		if int(env.Fields[i].StackFrameIdx) < len(l.StackTrace) {
+			err.WithString(env.Fields[i].Key, env.Fields[i].Value)
+			l.Fields[len(l.Fields)-1].StackFrameIdx = env.Fields[i].StackFrameIdx
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,2077 @@
+package bokchoy
+
+// Code generated by github.com/tinylib/msgp DO NOT EDIT.
+
+import (
+	"github.com/tinylib/msgp/msgp"
+)
+
+// DecodeMsg implements msgp.Decodable
+func (z *taskEnvelope) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "pl":
+			z.PublishedAt, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "PublishedAt")
+				return
+			}
+		case "tl":
+			z.TTL, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "TTL")
+				return
+			}
+		case "et":
+			z.ETA, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "ETA")
+				return
+			}
+		case "ri":
+			var zb0002 uint32
+			zb0002, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "RetryIntervals")
+				return
+			}
+			if cap(z.RetryIntervals) >= int(zb0002) {
+				z.RetryIntervals = (z.RetryIntervals)[:zb0002]
+			} else {
+				z.RetryIntervals = make([]int64, zb0002)
+			}
+			for za0001 := range z.RetryIntervals {
+				z.RetryIntervals[za0001], err = dc.ReadInt64()
+				if err != nil {
+					err = msgp.WrapError(err, "RetryIntervals", za0001)
+					return
+				}
+			}
+		case "re":
+			z.MaxRetries, err = dc.ReadInt8()
+			if err != nil {
+				err = msgp.WrapError(err, "MaxRetries")
+				return
+			}
+		case "ex":
+			z.ExecTime, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "ExecTime")
+				return
+			}
+		case "to":
+			z.Timeout, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "Timeout")
+				return
+			}
+		case "id":
+			z.ID, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "ID")
+				return
+			}
+		case "st":
+			z.StartedAt, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "StartedAt")
+				return
+			}
+		case "pr":
+			z.ProcessedAt, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "ProcessedAt")
+				return
+			}
+		case "s":
+			z.Status, err = dc.ReadInt8()
+			if err != nil {
+				err = msgp.WrapError(err, "Status")
+				return
+			}
+		case "p":
+			z.PayloadEncoded, err = dc.ReadBytes(z.PayloadEncoded)
+			if err != nil {
+				err = msgp.WrapError(err, "PayloadEncoded")
+				return
+			}
+		case "er":
+			if dc.IsNil() {
+				err = dc.ReadNil()
+				if err != nil {
+					err = msgp.WrapError(err, "Error")
+					return
+				}
+				z.Error = nil
+			} else {
+				if z.Error == nil {
+					z.Error = new(taskEnvelopeError)
+				}
+				err = z.Error.DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "Error")
+					return
+				}
+			}
+		case "pn":
+			z.Panic, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Panic")
+				return
+			}
+		case "hd":
+			var zb0003 uint32
+			zb0003, err = dc.ReadMapHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Headers")
+				return
+			}
+			if z.Headers == nil {
+				z.Headers = make(map[string]string, zb0003)
+			} else if len(z.Headers) > 0 {
+				for key := range z.Headers {
+					delete(z.Headers, key)
+				}
+			}
+			for zb0003 > 0 {
+				zb0003--
+				var za0002 string
+				var za0003 string
+				za0002, err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "Headers")
+					return
+				}
+				za0003, err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "Headers", za0002)
+					return
+				}
+				z.Headers[za0002] = za0003
+			}
+		case "tc":
+			var zb0004 uint32
+			zb0004, err = dc.ReadMapHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "TraceContext")
+				return
+			}
+			if z.TraceContext == nil {
+				z.TraceContext = make(map[string]string, zb0004)
+			} else if len(z.TraceContext) > 0 {
+				for key := range z.TraceContext {
+					delete(z.TraceContext, key)
+				}
+			}
+			for zb0004 > 0 {
+				zb0004--
+				var za0004 string
+				var za0005 string
+				za0004, err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "TraceContext")
+					return
+				}
+				za0005, err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "TraceContext", za0004)
+					return
+				}
+				z.TraceContext[za0004] = za0005
+			}
+		case "ty":
+			z.Type, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Type")
+				return
+			}
+		case "pt":
+			z.IsPayloadTyped, err = dc.ReadBool()
+			if err != nil {
+				err = msgp.WrapError(err, "IsPayloadTyped")
+				return
+			}
+		case "wi":
+			z.WorkerID, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "WorkerID")
+				return
+			}
+		case "pg":
+			z.Progress, err = dc.ReadInt8()
+			if err != nil {
+				err = msgp.WrapError(err, "Progress")
+				return
+			}
+		case "pm":
+			z.ProgressMsg, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "ProgressMsg")
+				return
+			}
+		case "ik":
+			z.IdempotencyKey, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "IdempotencyKey")
+				return
+			}
+		case "eq":
+			z.EnqueuedAt, err = dc.ReadInt64()
+			if err != nil {
+				err = msgp.WrapError(err, "EnqueuedAt")
+				return
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *taskEnvelope) EncodeMsg(en *msgp.Writer) (err error) {
+	// omitempty: check for empty values
+	zb0001Len := uint32(23)
+	var zb0001Mask uint32 /* 23 bits */
+	if z.Error == nil {
+		zb0001Len--
+		zb0001Mask |= 0x1000
+	}
+	if z.Panic == "" {
+		zb0001Len--
+		zb0001Mask |= 0x2000
+	}
+	if z.Headers == nil {
+		zb0001Len--
+		zb0001Mask |= 0x4000
+	}
+	if z.TraceContext == nil {
+		zb0001Len--
+		zb0001Mask |= 0x8000
+	}
+	if z.Type == "" {
+		zb0001Len--
+		zb0001Mask |= 0x10000
+	}
+	if z.IsPayloadTyped == false {
+		zb0001Len--
+		zb0001Mask |= 0x20000
+	}
+	if z.WorkerID == "" {
+		zb0001Len--
+		zb0001Mask |= 0x40000
+	}
+	if z.Progress == 0 {
+		zb0001Len--
+		zb0001Mask |= 0x80000
+	}
+	if z.ProgressMsg == "" {
+		zb0001Len--
+		zb0001Mask |= 0x100000
+	}
+	if z.IdempotencyKey == "" {
+		zb0001Len--
+		zb0001Mask |= 0x200000
+	}
+	if z.EnqueuedAt == 0 {
+		zb0001Len--
+		zb0001Mask |= 0x400000
+	}
+	// variable map header, size zb0001Len
+	err = en.WriteMapHeader(zb0001Len)
+	if err != nil {
+		return
+	}
+	if zb0001Len == 0 {
+		return
+	}
+	// write "pl"
+	err = en.Append(0xa2, 0x70, 0x6c)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.PublishedAt)
+	if err != nil {
+		err = msgp.WrapError(err, "PublishedAt")
+		return
+	}
+	// write "tl"
+	err = en.Append(0xa2, 0x74, 0x6c)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.TTL)
+	if err != nil {
+		err = msgp.WrapError(err, "TTL")
+		return
+	}
+	// write "et"
+	err = en.Append(0xa2, 0x65, 0x74)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.ETA)
+	if err != nil {
+		err = msgp.WrapError(err, "ETA")
+		return
+	}
+	// write "ri"
+	err = en.Append(0xa2, 0x72, 0x69)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.RetryIntervals)))
+	if err != nil {
+		err = msgp.WrapError(err, "RetryIntervals")
+		return
+	}
+	for za0001 := range z.RetryIntervals {
+		err = en.WriteInt64(z.RetryIntervals[za0001])
+		if err != nil {
+			err = msgp.WrapError(err, "RetryIntervals", za0001)
+			return
+		}
+	}
+	// write "re"
+	err = en.Append(0xa2, 0x72, 0x65)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt8(z.MaxRetries)
+	if err != nil {
+		err = msgp.WrapError(err, "MaxRetries")
+		return
+	}
+	// write "ex"
+	err = en.Append(0xa2, 0x65, 0x78)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.ExecTime)
+	if err != nil {
+		err = msgp.WrapError(err, "ExecTime")
+		return
+	}
+	// write "to"
+	err = en.Append(0xa2, 0x74, 0x6f)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.Timeout)
+	if err != nil {
+		err = msgp.WrapError(err, "Timeout")
+		return
+	}
+	// write "id"
+	err = en.Append(0xa2, 0x69, 0x64)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.ID)
+	if err != nil {
+		err = msgp.WrapError(err, "ID")
+		return
+	}
+	// write "st"
+	err = en.Append(0xa2, 0x73, 0x74)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.StartedAt)
+	if err != nil {
+		err = msgp.WrapError(err, "StartedAt")
+		return
+	}
+	// write "pr"
+	err = en.Append(0xa2, 0x70, 0x72)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt64(z.ProcessedAt)
+	if err != nil {
+		err = msgp.WrapError(err, "ProcessedAt")
+		return
+	}
+	// write "s"
+	err = en.Append(0xa1, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt8(z.Status)
+	if err != nil {
+		err = msgp.WrapError(err, "Status")
+		return
+	}
+	// write "p"
+	err = en.Append(0xa1, 0x70)
+	if err != nil {
+		return
+	}
+	err = en.WriteBytes(z.PayloadEncoded)
+	if err != nil {
+		err = msgp.WrapError(err, "PayloadEncoded")
+		return
+	}
+	if (zb0001Mask & 0x1000) == 0 { // if not empty
+		// write "er"
+		err = en.Append(0xa2, 0x65, 0x72)
+		if err != nil {
+			return
+		}
+		if z.Error == nil {
+			err = en.WriteNil()
+			if err != nil {
+				return
+			}
+		} else {
+			err = z.Error.EncodeMsg(en)
+			if err != nil {
+				err = msgp.WrapError(err, "Error")
+				return
+			}
+		}
+	}
+	if (zb0001Mask & 0x2000) == 0 { // if not empty
+		// write "pn"
+		err = en.Append(0xa2, 0x70, 0x6e)
+		if err != nil {
+			return
+		}
+		err = en.WriteString(z.Panic)
+		if err != nil {
+			err = msgp.WrapError(err, "Panic")
+			return
+		}
+	}
+	if (zb0001Mask & 0x4000) == 0 { // if not empty
+		// write "hd"
+		err = en.Append(0xa2, 0x68, 0x64)
+		if err != nil {
+			return
+		}
+		err = en.WriteMapHeader(uint32(len(z.Headers)))
+		if err != nil {
+			err = msgp.WrapError(err, "Headers")
+			return
+		}
+		for za0002, za0003 := range z.Headers {
+			err = en.WriteString(za0002)
+			if err != nil {
+				err = msgp.WrapError(err, "Headers")
+				return
+			}
+			err = en.WriteString(za0003)
+			if err != nil {
+				err = msgp.WrapError(err, "Headers", za0002)
+				return
+			}
+		}
+	}
+	if (zb0001Mask & 0x8000) == 0 { // if not empty
+		// write "tc"
+		err = en.Append(0xa2, 0x74, 0x63)
+		if err != nil {
+			return
+		}
+		err = en.WriteMapHeader(uint32(len(z.TraceContext)))
+		if err != nil {
+			err = msgp.WrapError(err, "TraceContext")
+			return
+		}
+		for za0004, za0005 := range z.TraceContext {
+			err = en.WriteString(za0004)
+			if err != nil {
+				err = msgp.WrapError(err, "TraceContext")
+				return
+			}
+			err = en.WriteString(za0005)
+			if err != nil {
+				err = msgp.WrapError(err, "TraceContext", za0004)
+				return
+			}
+		}
+	}
+	if (zb0001Mask & 0x10000) == 0 { // if not empty
+		// write "ty"
+		err = en.Append(0xa2, 0x74, 0x79)
+		if err != nil {
+			return
+		}
+		err = en.WriteString(z.Type)
+		if err != nil {
+			err = msgp.WrapError(err, "Type")
+			return
+		}
+	}
+	if (zb0001Mask & 0x20000) == 0 { // if not empty
+		// write "pt"
+		err = en.Append(0xa2, 0x70, 0x74)
+		if err != nil {
+			return
+		}
+		err = en.WriteBool(z.IsPayloadTyped)
+		if err != nil {
+			err = msgp.WrapError(err, "IsPayloadTyped")
+			return
+		}
+	}
+	if (zb0001Mask & 0x40000) == 0 { // if not empty
+		// write "wi"
+		err = en.Append(0xa2, 0x77, 0x69)
+		if err != nil {
+			return
+		}
+		err = en.WriteString(z.WorkerID)
+		if err != nil {
+			err = msgp.WrapError(err, "WorkerID")
+			return
+		}
+	}
+	if (zb0001Mask & 0x80000) == 0 { // if not empty
+		// write "pg"
+		err = en.Append(0xa2, 0x70, 0x67)
+		if err != nil {
+			return
+		}
+		err = en.WriteInt8(z.Progress)
+		if err != nil {
+			err = msgp.WrapError(err, "Progress")
+			return
+		}
+	}
+	if (zb0001Mask & 0x100000) == 0 { // if not empty
+		// write "pm"
+		err = en.Append(0xa2, 0x70, 0x6d)
+		if err != nil {
+			return
+		}
+		err = en.WriteString(z.ProgressMsg)
+		if err != nil {
+			err = msgp.WrapError(err, "ProgressMsg")
+			return
+		}
+	}
+	if (zb0001Mask & 0x200000) == 0 { // if not empty
+		// write "ik"
+		err = en.Append(0xa2, 0x69, 0x6b)
+		if err != nil {
+			return
+		}
+		err = en.WriteString(z.IdempotencyKey)
+		if err != nil {
+			err = msgp.WrapError(err, "IdempotencyKey")
+			return
+		}
+	}
+	if (zb0001Mask & 0x400000) == 0 { // if not empty
+		// write "eq"
+		err = en.Append(0xa2, 0x65, 0x71)
+		if err != nil {
+			return
+		}
+		err = en.WriteInt64(z.EnqueuedAt)
+		if err != nil {
+			err = msgp.WrapError(err, "EnqueuedAt")
+			return
+		}
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *taskEnvelope) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// omitempty: check for empty values
+	zb0001Len := uint32(23)
+	var zb0001Mask uint32 /* 23 bits */
+	if z.Error == nil {
+		zb0001Len--
+		zb0001Mask |= 0x1000
+	}
+	if z.Panic == "" {
+		zb0001Len--
+		zb0001Mask |= 0x2000
+	}
+	if z.Headers == nil {
+		zb0001Len--
+		zb0001Mask |= 0x4000
+	}
+	if z.TraceContext == nil {
+		zb0001Len--
+		zb0001Mask |= 0x8000
+	}
+	if z.Type == "" {
+		zb0001Len--
+		zb0001Mask |= 0x10000
+	}
+	if z.IsPayloadTyped == false {
+		zb0001Len--
+		zb0001Mask |= 0x20000
+	}
+	if z.WorkerID == "" {
+		zb0001Len--
+		zb0001Mask |= 0x40000
+	}
+	if z.Progress == 0 {
+		zb0001Len--
+		zb0001Mask |= 0x80000
+	}
+	if z.ProgressMsg == "" {
+		zb0001Len--
+		zb0001Mask |= 0x100000
+	}
+	if z.IdempotencyKey == "" {
+		zb0001Len--
+		zb0001Mask |= 0x200000
+	}
+	if z.EnqueuedAt == 0 {
+		zb0001Len--
+		zb0001Mask |= 0x400000
+	}
+	// variable map header, size zb0001Len
+	o = msgp.AppendMapHeader(o, zb0001Len)
+	if zb0001Len == 0 {
+		return
+	}
+	// string "pl"
+	o = append(o, 0xa2, 0x70, 0x6c)
+	o = msgp.AppendInt64(o, z.PublishedAt)
+	// string "tl"
+	o = append(o, 0xa2, 0x74, 0x6c)
+	o = msgp.AppendInt64(o, z.TTL)
+	// string "et"
+	o = append(o, 0xa2, 0x65, 0x74)
+	o = msgp.AppendInt64(o, z.ETA)
+	// string "ri"
+	o = append(o, 0xa2, 0x72, 0x69)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.RetryIntervals)))
+	for za0001 := range z.RetryIntervals {
+		o = msgp.AppendInt64(o, z.RetryIntervals[za0001])
+	}
+	// string "re"
+	o = append(o, 0xa2, 0x72, 0x65)
+	o = msgp.AppendInt8(o, z.MaxRetries)
+	// string "ex"
+	o = append(o, 0xa2, 0x65, 0x78)
+	o = msgp.AppendInt64(o, z.ExecTime)
+	// string "to"
+	o = append(o, 0xa2, 0x74, 0x6f)
+	o = msgp.AppendInt64(o, z.Timeout)
+	// string "id"
+	o = append(o, 0xa2, 0x69, 0x64)
+	o = msgp.AppendString(o, z.ID)
+	// string "st"
+	o = append(o, 0xa2, 0x73, 0x74)
+	o = msgp.AppendInt64(o, z.StartedAt)
+	// string "pr"
+	o = append(o, 0xa2, 0x70, 0x72)
+	o = msgp.AppendInt64(o, z.ProcessedAt)
+	// string "s"
+	o = append(o, 0xa1, 0x73)
+	o = msgp.AppendInt8(o, z.Status)
+	// string "p"
+	o = append(o, 0xa1, 0x70)
+	o = msgp.AppendBytes(o, z.PayloadEncoded)
+	if (zb0001Mask & 0x1000) == 0 { // if not empty
+		// string "er"
+		o = append(o, 0xa2, 0x65, 0x72)
+		if z.Error == nil {
+			o = msgp.AppendNil(o)
+		} else {
+			o, err = z.Error.MarshalMsg(o)
+			if err != nil {
+				err = msgp.WrapError(err, "Error")
+				return
+			}
+		}
+	}
+	if (zb0001Mask & 0x2000) == 0 { // if not empty
+		// string "pn"
+		o = append(o, 0xa2, 0x70, 0x6e)
+		o = msgp.AppendString(o, z.Panic)
+	}
+	if (zb0001Mask & 0x4000) == 0 { // if not empty
+		// string "hd"
+		o = append(o, 0xa2, 0x68, 0x64)
+		o = msgp.AppendMapHeader(o, uint32(len(z.Headers)))
+		for za0002, za0003 := range z.Headers {
+			o = msgp.AppendString(o, za0002)
+			o = msgp.AppendString(o, za0003)
+		}
+	}
+	if (zb0001Mask & 0x8000) == 0 { // if not empty
+		// string "tc"
+		o = append(o, 0xa2, 0x74, 0x63)
+		o = msgp.AppendMapHeader(o, uint32(len(z.TraceContext)))
+		for za0004, za0005 := range z.TraceContext {
+			o = msgp.AppendString(o, za0004)
+			o = msgp.AppendString(o, za0005)
+		}
+	}
+	if (zb0001Mask & 0x10000) == 0 { // if not empty
+		// string "ty"
+		o = append(o, 0xa2, 0x74, 0x79)
+		o = msgp.AppendString(o, z.Type)
+	}
+	if (zb0001Mask & 0x20000) == 0 { // if not empty
+		// string "pt"
+		o = append(o, 0xa2, 0x70, 0x74)
+		o = msgp.AppendBool(o, z.IsPayloadTyped)
+	}
+	if (zb0001Mask & 0x40000) == 0 { // if not empty
+		// string "wi"
+		o = append(o, 0xa2, 0x77, 0x69)
+		o = msgp.AppendString(o, z.WorkerID)
+	}
+	if (zb0001Mask & 0x80000) == 0 { // if not empty
+		// string "pg"
+		o = append(o, 0xa2, 0x70, 0x67)
+		o = msgp.AppendInt8(o, z.Progress)
+	}
+	if (zb0001Mask & 0x100000) == 0 { // if not empty
+		// string "pm"
+		o = append(o, 0xa2, 0x70, 0x6d)
+		o = msgp.AppendString(o, z.ProgressMsg)
+	}
+	if (zb0001Mask & 0x200000) == 0 { // if not empty
+		// string "ik"
+		o = append(o, 0xa2, 0x69, 0x6b)
+		o = msgp.AppendString(o, z.IdempotencyKey)
+	}
+	if (zb0001Mask & 0x400000) == 0 { // if not empty
+		// string "eq"
+		o = append(o, 0xa2, 0x65, 0x71)
+		o = msgp.AppendInt64(o, z.EnqueuedAt)
+	}
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *taskEnvelope) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "pl":
+			z.PublishedAt, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "PublishedAt")
+				return
+			}
+		case "tl":
+			z.TTL, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "TTL")
+				return
+			}
+		case "et":
+			z.ETA, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "ETA")
+				return
+			}
+		case "ri":
+			var zb0002 uint32
+			zb0002, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "RetryIntervals")
+				return
+			}
+			if cap(z.RetryIntervals) >= int(zb0002) {
+				z.RetryIntervals = (z.RetryIntervals)[:zb0002]
+			} else {
+				z.RetryIntervals = make([]int64, zb0002)
+			}
+			for za0001 := range z.RetryIntervals {
+				z.RetryIntervals[za0001], bts, err = msgp.ReadInt64Bytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "RetryIntervals", za0001)
+					return
+				}
+			}
+		case "re":
+			z.MaxRetries, bts, err = msgp.ReadInt8Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "MaxRetries")
+				return
+			}
+		case "ex":
+			z.ExecTime, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "ExecTime")
+				return
+			}
+		case "to":
+			z.Timeout, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Timeout")
+				return
+			}
+		case "id":
+			z.ID, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "ID")
+				return
+			}
+		case "st":
+			z.StartedAt, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "StartedAt")
+				return
+			}
+		case "pr":
+			z.ProcessedAt, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "ProcessedAt")
+				return
+			}
+		case "s":
+			z.Status, bts, err = msgp.ReadInt8Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Status")
+				return
+			}
+		case "p":
+			z.PayloadEncoded, bts, err = msgp.ReadBytesBytes(bts, z.PayloadEncoded)
+			if err != nil {
+				err = msgp.WrapError(err, "PayloadEncoded")
+				return
+			}
+		case "er":
+			if msgp.IsNil(bts) {
+				bts, err = msgp.ReadNilBytes(bts)
+				if err != nil {
+					return
+				}
+				z.Error = nil
+			} else {
+				if z.Error == nil {
+					z.Error = new(taskEnvelopeError)
+				}
+				bts, err = z.Error.UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Error")
+					return
+				}
+			}
+		case "pn":
+			z.Panic, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Panic")
+				return
+			}
+		case "hd":
+			var zb0003 uint32
+			zb0003, bts, err = msgp.ReadMapHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Headers")
+				return
+			}
+			if z.Headers == nil {
+				z.Headers = make(map[string]string, zb0003)
+			} else if len(z.Headers) > 0 {
+				for key := range z.Headers {
+					delete(z.Headers, key)
+				}
+			}
+			for zb0003 > 0 {
+				var za0002 string
+				var za0003 string
+				zb0003--
+				za0002, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Headers")
+					return
+				}
+				za0003, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Headers", za0002)
+					return
+				}
+				z.Headers[za0002] = za0003
+			}
+		case "tc":
+			var zb0004 uint32
+			zb0004, bts, err = msgp.ReadMapHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "TraceContext")
+				return
+			}
+			if z.TraceContext == nil {
+				z.TraceContext = make(map[string]string, zb0004)
+			} else if len(z.TraceContext) > 0 {
+				for key := range z.TraceContext {
+					delete(z.TraceContext, key)
+				}
+			}
+			for zb0004 > 0 {
+				var za0004 string
+				var za0005 string
+				zb0004--
+				za0004, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "TraceContext")
+					return
+				}
+				za0005, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "TraceContext", za0004)
+					return
+				}
+				z.TraceContext[za0004] = za0005
+			}
+		case "ty":
+			z.Type, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Type")
+				return
+			}
+		case "pt":
+			z.IsPayloadTyped, bts, err = msgp.ReadBoolBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "IsPayloadTyped")
+				return
+			}
+		case "wi":
+			z.WorkerID, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "WorkerID")
+				return
+			}
+		case "pg":
+			z.Progress, bts, err = msgp.ReadInt8Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Progress")
+				return
+			}
+		case "pm":
+			z.ProgressMsg, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "ProgressMsg")
+				return
+			}
+		case "ik":
+			z.IdempotencyKey, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "IdempotencyKey")
+				return
+			}
+		case "eq":
+			z.EnqueuedAt, bts, err = msgp.ReadInt64Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "EnqueuedAt")
+				return
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *taskEnvelope) Msgsize() (s int) {
+	s = 3 + 3 + msgp.Int64Size + 3 + msgp.Int64Size + 3 + msgp.Int64Size + 3 + msgp.ArrayHeaderSize + (len(z.RetryIntervals) * (msgp.Int64Size)) + 3 + msgp.Int8Size + 3 + msgp.Int64Size + 3 + msgp.Int64Size + 3 + msgp.StringPrefixSize + len(z.ID) + 3 + msgp.Int64Size + 3 + msgp.Int64Size + 2 + msgp.Int8Size + 2 + msgp.BytesPrefixSize + len(z.PayloadEncoded) + 3
+	if z.Error == nil {
+		s += msgp.NilSize
+	} else {
+		s += z.Error.Msgsize()
+	}
+	s += 3 + msgp.StringPrefixSize + len(z.Panic) + 3 + msgp.MapHeaderSize
+	if z.Headers != nil {
+		for za0002, za0003 := range z.Headers {
+			_ = za0003
+			s += msgp.StringPrefixSize + len(za0002) + msgp.StringPrefixSize + len(za0003)
+		}
+	}
+	s += 3 + msgp.MapHeaderSize
+	if z.TraceContext != nil {
+		for za0004, za0005 := range z.TraceContext {
+			_ = za0005
+			s += msgp.StringPrefixSize + len(za0004) + msgp.StringPrefixSize + len(za0005)
+		}
+	}
+	s += 3 + msgp.StringPrefixSize + len(z.Type) + 3 + msgp.BoolSize + 3 + msgp.StringPrefixSize + len(z.WorkerID) + 3 + msgp.Int8Size + 3 + msgp.StringPrefixSize + len(z.ProgressMsg) + 3 + msgp.StringPrefixSize + len(z.IdempotencyKey) + 3 + msgp.Int64Size
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *taskEnvelopeError) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "id":
+			z.ID, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "ID")
+				return
+			}
+		case "cl":
+			z.Class, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Class")
+				return
+			}
+		case "ms":
+			var zb0002 uint32
+			zb0002, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Messages")
+				return
+			}
+			if cap(z.Messages) >= int(zb0002) {
+				z.Messages = (z.Messages)[:zb0002]
+			} else {
+				z.Messages = make([]taskEnvelopeErrorMessage, zb0002)
+			}
+			for za0001 := range z.Messages {
+				var zb0003 uint32
+				zb0003, err = dc.ReadMapHeader()
+				if err != nil {
+					err = msgp.WrapError(err, "Messages", za0001)
+					return
+				}
+				for zb0003 > 0 {
+					zb0003--
+					field, err = dc.ReadMapKeyPtr()
+					if err != nil {
+						err = msgp.WrapError(err, "Messages", za0001)
+						return
+					}
+					switch msgp.UnsafeString(field) {
+					case "b":
+						z.Messages[za0001].Body, err = dc.ReadString()
+						if err != nil {
+							err = msgp.WrapError(err, "Messages", za0001, "Body")
+							return
+						}
+					case "i":
+						z.Messages[za0001].StackFrameIdx, err = dc.ReadInt16()
+						if err != nil {
+							err = msgp.WrapError(err, "Messages", za0001, "StackFrameIdx")
+							return
+						}
+					default:
+						err = dc.Skip()
+						if err != nil {
+							err = msgp.WrapError(err, "Messages", za0001)
+							return
+						}
+					}
+				}
+			}
+		case "fs":
+			var zb0004 uint32
+			zb0004, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Fields")
+				return
+			}
+			if cap(z.Fields) >= int(zb0004) {
+				z.Fields = (z.Fields)[:zb0004]
+			} else {
+				z.Fields = make([]taskEnvelopeErrorField, zb0004)
+			}
+			for za0002 := range z.Fields {
+				var zb0005 uint32
+				zb0005, err = dc.ReadMapHeader()
+				if err != nil {
+					err = msgp.WrapError(err, "Fields", za0002)
+					return
+				}
+				for zb0005 > 0 {
+					zb0005--
+					field, err = dc.ReadMapKeyPtr()
+					if err != nil {
+						err = msgp.WrapError(err, "Fields", za0002)
+						return
+					}
+					switch msgp.UnsafeString(field) {
+					case "k":
+						z.Fields[za0002].Key, err = dc.ReadString()
+						if err != nil {
+							err = msgp.WrapError(err, "Fields", za0002, "Key")
+							return
+						}
+					case "v":
+						z.Fields[za0002].Value, err = dc.ReadString()
+						if err != nil {
+							err = msgp.WrapError(err, "Fields", za0002, "Value")
+							return
+						}
+					case "i":
+						z.Fields[za0002].StackFrameIdx, err = dc.ReadInt16()
+						if err != nil {
+							err = msgp.WrapError(err, "Fields", za0002, "StackFrameIdx")
+							return
+						}
+					default:
+						err = dc.Skip()
+						if err != nil {
+							err = msgp.WrapError(err, "Fields", za0002)
+							return
+						}
+					}
+				}
+			}
+		case "sf":
+			var zb0006 uint32
+			zb0006, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Stack")
+				return
+			}
+			if cap(z.Stack) >= int(zb0006) {
+				z.Stack = (z.Stack)[:zb0006]
+			} else {
+				z.Stack = make([]taskEnvelopeErrorStackFrame, zb0006)
+			}
+			for za0003 := range z.Stack {
+				var zb0007 uint32
+				zb0007, err = dc.ReadMapHeader()
+				if err != nil {
+					err = msgp.WrapError(err, "Stack", za0003)
+					return
+				}
+				for zb0007 > 0 {
+					zb0007--
+					field, err = dc.ReadMapKeyPtr()
+					if err != nil {
+						err = msgp.WrapError(err, "Stack", za0003)
+						return
+					}
+					switch msgp.UnsafeString(field) {
+					case "fn":
+						z.Stack[za0003].Function, err = dc.ReadString()
+						if err != nil {
+							err = msgp.WrapError(err, "Stack", za0003, "Function")
+							return
+						}
+					case "f":
+						z.Stack[za0003].File, err = dc.ReadString()
+						if err != nil {
+							err = msgp.WrapError(err, "Stack", za0003, "File")
+							return
+						}
+					case "l":
+						z.Stack[za0003].Line, err = dc.ReadInt()
+						if err != nil {
+							err = msgp.WrapError(err, "Stack", za0003, "Line")
+							return
+						}
+					default:
+						err = dc.Skip()
+						if err != nil {
+							err = msgp.WrapError(err, "Stack", za0003)
+							return
+						}
+					}
+				}
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *taskEnvelopeError) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 5
+	// write "id"
+	err = en.Append(0x85, 0xa2, 0x69, 0x64)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.ID)
+	if err != nil {
+		err = msgp.WrapError(err, "ID")
+		return
+	}
+	// write "cl"
+	err = en.Append(0xa2, 0x63, 0x6c)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Class)
+	if err != nil {
+		err = msgp.WrapError(err, "Class")
+		return
+	}
+	// write "ms"
+	err = en.Append(0xa2, 0x6d, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Messages)))
+	if err != nil {
+		err = msgp.WrapError(err, "Messages")
+		return
+	}
+	for za0001 := range z.Messages {
+		// map header, size 2
+		// write "b"
+		err = en.Append(0x82, 0xa1, 0x62)
+		if err != nil {
+			return
+		}
+		err = en.WriteString(z.Messages[za0001].Body)
+		if err != nil {
+			err = msgp.WrapError(err, "Messages", za0001, "Body")
+			return
+		}
+		// write "i"
+		err = en.Append(0xa1, 0x69)
+		if err != nil {
+			return
+		}
+		err = en.WriteInt16(z.Messages[za0001].StackFrameIdx)
+		if err != nil {
+			err = msgp.WrapError(err, "Messages", za0001, "StackFrameIdx")
+			return
+		}
+	}
+	// write "fs"
+	err = en.Append(0xa2, 0x66, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Fields)))
+	if err != nil {
+		err = msgp.WrapError(err, "Fields")
+		return
+	}
+	for za0002 := range z.Fields {
+		// map header, size 3
+		// write "k"
+		err = en.Append(0x83, 0xa1, 0x6b)
+		if err != nil {
+			return
+		}
+		err = en.WriteString(z.Fields[za0002].Key)
+		if err != nil {
+			err = msgp.WrapError(err, "Fields", za0002, "Key")
+			return
+		}
+		// write "v"
+		err = en.Append(0xa1, 0x76)
+		if err != nil {
+			return
+		}
+		err = en.WriteString(z.Fields[za0002].Value)
+		if err != nil {
+			err = msgp.WrapError(err, "Fields", za0002, "Value")
+			return
+		}
+		// write "i"
+		err = en.Append(0xa1, 0x69)
+		if err != nil {
+			return
+		}
+		err = en.WriteInt16(z.Fields[za0002].StackFrameIdx)
+		if err != nil {
+			err = msgp.WrapError(err, "Fields", za0002, "StackFrameIdx")
+			return
+		}
+	}
+	// write "sf"
+	err = en.Append(0xa2, 0x73, 0x66)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Stack)))
+	if err != nil {
+		err = msgp.WrapError(err, "Stack")
+		return
+	}
+	for za0003 := range z.Stack {
+		// map header, size 3
+		// write "fn"
+		err = en.Append(0x83, 0xa2, 0x66, 0x6e)
+		if err != nil {
+			return
+		}
+		err = en.WriteString(z.Stack[za0003].Function)
+		if err != nil {
+			err = msgp.WrapError(err, "Stack", za0003, "Function")
+			return
+		}
+		// write "f"
+		err = en.Append(0xa1, 0x66)
+		if err != nil {
+			return
+		}
+		err = en.WriteString(z.Stack[za0003].File)
+		if err != nil {
+			err = msgp.WrapError(err, "Stack", za0003, "File")
+			return
+		}
+		// write "l"
+		err = en.Append(0xa1, 0x6c)
+		if err != nil {
+			return
+		}
+		err = en.WriteInt(z.Stack[za0003].Line)
+		if err != nil {
+			err = msgp.WrapError(err, "Stack", za0003, "Line")
+			return
+		}
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *taskEnvelopeError) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 5
+	// string "id"
+	o = append(o, 0x85, 0xa2, 0x69, 0x64)
+	o = msgp.AppendString(o, z.ID)
+	// string "cl"
+	o = append(o, 0xa2, 0x63, 0x6c)
+	o = msgp.AppendString(o, z.Class)
+	// string "ms"
+	o = append(o, 0xa2, 0x6d, 0x73)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Messages)))
+	for za0001 := range z.Messages {
+		// map header, size 2
+		// string "b"
+		o = append(o, 0x82, 0xa1, 0x62)
+		o = msgp.AppendString(o, z.Messages[za0001].Body)
+		// string "i"
+		o = append(o, 0xa1, 0x69)
+		o = msgp.AppendInt16(o, z.Messages[za0001].StackFrameIdx)
+	}
+	// string "fs"
+	o = append(o, 0xa2, 0x66, 0x73)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Fields)))
+	for za0002 := range z.Fields {
+		// map header, size 3
+		// string "k"
+		o = append(o, 0x83, 0xa1, 0x6b)
+		o = msgp.AppendString(o, z.Fields[za0002].Key)
+		// string "v"
+		o = append(o, 0xa1, 0x76)
+		o = msgp.AppendString(o, z.Fields[za0002].Value)
+		// string "i"
+		o = append(o, 0xa1, 0x69)
+		o = msgp.AppendInt16(o, z.Fields[za0002].StackFrameIdx)
+	}
+	// string "sf"
+	o = append(o, 0xa2, 0x73, 0x66)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Stack)))
+	for za0003 := range z.Stack {
+		// map header, size 3
+		// string "fn"
+		o = append(o, 0x83, 0xa2, 0x66, 0x6e)
+		o = msgp.AppendString(o, z.Stack[za0003].Function)
+		// string "f"
+		o = append(o, 0xa1, 0x66)
+		o = msgp.AppendString(o, z.Stack[za0003].File)
+		// string "l"
+		o = append(o, 0xa1, 0x6c)
+		o = msgp.AppendInt(o, z.Stack[za0003].Line)
+	}
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *taskEnvelopeError) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "id":
+			z.ID, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "ID")
+				return
+			}
+		case "cl":
+			z.Class, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Class")
+				return
+			}
+		case "ms":
+			var zb0002 uint32
+			zb0002, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Messages")
+				return
+			}
+			if cap(z.Messages) >= int(zb0002) {
+				z.Messages = (z.Messages)[:zb0002]
+			} else {
+				z.Messages = make([]taskEnvelopeErrorMessage, zb0002)
+			}
+			for za0001 := range z.Messages {
+				var zb0003 uint32
+				zb0003, bts, err = msgp.ReadMapHeaderBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Messages", za0001)
+					return
+				}
+				for zb0003 > 0 {
+					zb0003--
+					field, bts, err = msgp.ReadMapKeyZC(bts)
+					if err != nil {
+						err = msgp.WrapError(err, "Messages", za0001)
+						return
+					}
+					switch msgp.UnsafeString(field) {
+					case "b":
+						z.Messages[za0001].Body, bts, err = msgp.ReadStringBytes(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Messages", za0001, "Body")
+							return
+						}
+					case "i":
+						z.Messages[za0001].StackFrameIdx, bts, err = msgp.ReadInt16Bytes(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Messages", za0001, "StackFrameIdx")
+							return
+						}
+					default:
+						bts, err = msgp.Skip(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Messages", za0001)
+							return
+						}
+					}
+				}
+			}
+		case "fs":
+			var zb0004 uint32
+			zb0004, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Fields")
+				return
+			}
+			if cap(z.Fields) >= int(zb0004) {
+				z.Fields = (z.Fields)[:zb0004]
+			} else {
+				z.Fields = make([]taskEnvelopeErrorField, zb0004)
+			}
+			for za0002 := range z.Fields {
+				var zb0005 uint32
+				zb0005, bts, err = msgp.ReadMapHeaderBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Fields", za0002)
+					return
+				}
+				for zb0005 > 0 {
+					zb0005--
+					field, bts, err = msgp.ReadMapKeyZC(bts)
+					if err != nil {
+						err = msgp.WrapError(err, "Fields", za0002)
+						return
+					}
+					switch msgp.UnsafeString(field) {
+					case "k":
+						z.Fields[za0002].Key, bts, err = msgp.ReadStringBytes(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Fields", za0002, "Key")
+							return
+						}
+					case "v":
+						z.Fields[za0002].Value, bts, err = msgp.ReadStringBytes(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Fields", za0002, "Value")
+							return
+						}
+					case "i":
+						z.Fields[za0002].StackFrameIdx, bts, err = msgp.ReadInt16Bytes(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Fields", za0002, "StackFrameIdx")
+							return
+						}
+					default:
+						bts, err = msgp.Skip(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Fields", za0002)
+							return
+						}
+					}
+				}
+			}
+		case "sf":
+			var zb0006 uint32
+			zb0006, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Stack")
+				return
+			}
+			if cap(z.Stack) >= int(zb0006) {
+				z.Stack = (z.Stack)[:zb0006]
+			} else {
+				z.Stack = make([]taskEnvelopeErrorStackFrame, zb0006)
+			}
+			for za0003 := range z.Stack {
+				var zb0007 uint32
+				zb0007, bts, err = msgp.ReadMapHeaderBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Stack", za0003)
+					return
+				}
+				for zb0007 > 0 {
+					zb0007--
+					field, bts, err = msgp.ReadMapKeyZC(bts)
+					if err != nil {
+						err = msgp.WrapError(err, "Stack", za0003)
+						return
+					}
+					switch msgp.UnsafeString(field) {
+					case "fn":
+						z.Stack[za0003].Function, bts, err = msgp.ReadStringBytes(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Stack", za0003, "Function")
+							return
+						}
+					case "f":
+						z.Stack[za0003].File, bts, err = msgp.ReadStringBytes(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Stack", za0003, "File")
+							return
+						}
+					case "l":
+						z.Stack[za0003].Line, bts, err = msgp.ReadIntBytes(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Stack", za0003, "Line")
+							return
+						}
+					default:
+						bts, err = msgp.Skip(bts)
+						if err != nil {
+							err = msgp.WrapError(err, "Stack", za0003)
+							return
+						}
+					}
+				}
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *taskEnvelopeError) Msgsize() (s int) {
+	s = 1 + 3 + msgp.StringPrefixSize + len(z.ID) + 3 + msgp.StringPrefixSize + len(z.Class) + 3 + msgp.ArrayHeaderSize
+	for za0001 := range z.Messages {
+		s += 1 + 2 + msgp.StringPrefixSize + len(z.Messages[za0001].Body) + 2 + msgp.Int16Size
+	}
+	s += 3 + msgp.ArrayHeaderSize
+	for za0002 := range z.Fields {
+		s += 1 + 2 + msgp.StringPrefixSize + len(z.Fields[za0002].Key) + 2 + msgp.StringPrefixSize + len(z.Fields[za0002].Value) + 2 + msgp.Int16Size
+	}
+	s += 3 + msgp.ArrayHeaderSize
+	for za0003 := range z.Stack {
+		s += 1 + 3 + msgp.StringPrefixSize + len(z.Stack[za0003].Function) + 2 + msgp.StringPrefixSize + len(z.Stack[za0003].File) + 2 + msgp.IntSize
+	}
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *taskEnvelopeErrorField) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "k":
+			z.Key, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Key")
+				return
+			}
+		case "v":
+			z.Value, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Value")
+				return
+			}
+		case "i":
+			z.StackFrameIdx, err = dc.ReadInt16()
+			if err != nil {
+				err = msgp.WrapError(err, "StackFrameIdx")
+				return
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z taskEnvelopeErrorField) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 3
+	// write "k"
+	err = en.Append(0x83, 0xa1, 0x6b)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Key)
+	if err != nil {
+		err = msgp.WrapError(err, "Key")
+		return
+	}
+	// write "v"
+	err = en.Append(0xa1, 0x76)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Value)
+	if err != nil {
+		err = msgp.WrapError(err, "Value")
+		return
+	}
+	// write "i"
+	err = en.Append(0xa1, 0x69)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt16(z.StackFrameIdx)
+	if err != nil {
+		err = msgp.WrapError(err, "StackFrameIdx")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z taskEnvelopeErrorField) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 3
+	// string "k"
+	o = append(o, 0x83, 0xa1, 0x6b)
+	o = msgp.AppendString(o, z.Key)
+	// string "v"
+	o = append(o, 0xa1, 0x76)
+	o = msgp.AppendString(o, z.Value)
+	// string "i"
+	o = append(o, 0xa1, 0x69)
+	o = msgp.AppendInt16(o, z.StackFrameIdx)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *taskEnvelopeErrorField) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "k":
+			z.Key, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Key")
+				return
+			}
+		case "v":
+			z.Value, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Value")
+				return
+			}
+		case "i":
+			z.StackFrameIdx, bts, err = msgp.ReadInt16Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "StackFrameIdx")
+				return
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z taskEnvelopeErrorField) Msgsize() (s int) {
+	s = 1 + 2 + msgp.StringPrefixSize + len(z.Key) + 2 + msgp.StringPrefixSize + len(z.Value) + 2 + msgp.Int16Size
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *taskEnvelopeErrorMessage) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "b":
+			z.Body, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Body")
+				return
+			}
+		case "i":
+			z.StackFrameIdx, err = dc.ReadInt16()
+			if err != nil {
+				err = msgp.WrapError(err, "StackFrameIdx")
+				return
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z taskEnvelopeErrorMessage) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 2
+	// write "b"
+	err = en.Append(0x82, 0xa1, 0x62)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Body)
+	if err != nil {
+		err = msgp.WrapError(err, "Body")
+		return
+	}
+	// write "i"
+	err = en.Append(0xa1, 0x69)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt16(z.StackFrameIdx)
+	if err != nil {
+		err = msgp.WrapError(err, "StackFrameIdx")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z taskEnvelopeErrorMessage) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 2
+	// string "b"
+	o = append(o, 0x82, 0xa1, 0x62)
+	o = msgp.AppendString(o, z.Body)
+	// string "i"
+	o = append(o, 0xa1, 0x69)
+	o = msgp.AppendInt16(o, z.StackFrameIdx)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *taskEnvelopeErrorMessage) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "b":
+			z.Body, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Body")
+				return
+			}
+		case "i":
+			z.StackFrameIdx, bts, err = msgp.ReadInt16Bytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "StackFrameIdx")
+				return
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z taskEnvelopeErrorMessage) Msgsize() (s int) {
+	s = 1 + 2 + msgp.StringPrefixSize + len(z.Body) + 2 + msgp.Int16Size
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *taskEnvelopeErrorStackFrame) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "fn":
+			z.Function, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "Function")
+				return
+			}
+		case "f":
+			z.File, err = dc.ReadString()
+			if err != nil {
+				err = msgp.WrapError(err, "File")
+				return
+			}
+		case "l":
+			z.Line, err = dc.ReadInt()
+			if err != nil {
+				err = msgp.WrapError(err, "Line")
+				return
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z taskEnvelopeErrorStackFrame) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 3
+	// write "fn"
+	err = en.Append(0x83, 0xa2, 0x66, 0x6e)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Function)
+	if err != nil {
+		err = msgp.WrapError(err, "Function")
+		return
+	}
+	// write "f"
+	err = en.Append(0xa1, 0x66)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.File)
+	if err != nil {
+		err = msgp.WrapError(err, "File")
+		return
+	}
+	// write "l"
+	err = en.Append(0xa1, 0x6c)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt(z.Line)
+	if err != nil {
+		err = msgp.WrapError(err, "Line")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z taskEnvelopeErrorStackFrame) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 3
+	// string "fn"
+	o = append(o, 0x83, 0xa2, 0x66, 0x6e)
+	o = msgp.AppendString(o, z.Function)
+	// string "f"
+	o = append(o, 0xa1, 0x66)
+	o = msgp.AppendString(o, z.File)
+	// string "l"
+	o = append(o, 0xa1, 0x6c)
+	o = msgp.AppendInt(o, z.Line)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *taskEnvelopeErrorStackFrame) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "fn":
+			z.Function, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Function")
+				return
+			}
+		case "f":
+			z.File, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "File")
+				return
+			}
+		case "l":
+			z.Line, bts, err = msgp.ReadIntBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Line")
+				return
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z taskEnvelopeErrorStackFrame) Msgsize() (s int) {
+	s = 1 + 3 + msgp.StringPrefixSize + len(z.Function) + 2 + msgp.StringPrefixSize + len(z.File) + 2 + msgp.IntSize
+	return
+}
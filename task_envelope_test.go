@@ -0,0 +1,217 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+	"github.com/qioalice/ekago/v3/ekatime"
+	"github.com/qioalice/ekago/v3/ekaunsafe"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+type testTaskPayload struct {
+	Data string
+}
+
+var testTaskPayloadSerializer = CustomSerializerJSON(testTaskPayload{})
+
+func newTestTask() *Task {
+	return &Task{
+		id:             "01F5KQ1T2T8GQ3Q0K7W1HX3W6C",
+		status:         TASK_STATUS_WAITING,
+		Payload:        testTaskPayload{Data: "hello world"},
+		PublishedAt:    ekatime.Now(),
+		enqueuedAt:     time.Now().UnixNano(),
+		TTL:            _DEFAULT_TTL,
+		Timeout:        _DEFAULT_TIMEOUT,
+		MaxRetries:     _DEFAULT_MAX_RETRIES,
+		RetryIntervals: defaultRetryIntervals,
+	}
+}
+
+func TestTaskEnvelope(t *testing.T) {
+
+	task := newTestTask()
+	task.SetHeader("tenant_id", "42")
+	task.traceContext = map[string]string{"traceparent": "00-01-02-01"}
+
+	encodedTask, err := task.Serialize(testTaskPayloadSerializer)
+	require.True(t, err.IsNil())
+	require.Equal(t, _TASK_ENVELOPE_VERSION, encodedTask[0])
+
+	var decodedTask Task
+	err = decodedTask.Deserialize(encodedTask, testTaskPayloadSerializer)
+	require.True(t, err.IsNil())
+
+	require.Equal(t, task.id, decodedTask.id)
+	require.Equal(t, task.status, decodedTask.status)
+	require.Equal(t, task.Payload, decodedTask.Payload)
+	require.Equal(t, task.PublishedAt, decodedTask.PublishedAt)
+	require.Equal(t, task.enqueuedAt, decodedTask.enqueuedAt)
+	require.Equal(t, task.TTL, decodedTask.TTL)
+	require.Equal(t, task.Timeout, decodedTask.Timeout)
+	require.Equal(t, task.MaxRetries, decodedTask.MaxRetries)
+	require.Equal(t, task.RetryIntervals, decodedTask.RetryIntervals)
+	require.Equal(t, "42", decodedTask.Header("tenant_id"))
+	require.Equal(t, task.traceContext, decodedTask.traceContext)
+}
+
+func TestTaskEnvelopeCompatibility(t *testing.T) {
+
+	task := newTestTask()
+	_, err := task.Serialize(testTaskPayloadSerializer)
+	require.True(t, err.IsNil())
+
+	// Legacy format: msgpack map w/o version byte.
+
+	legacyEncodedTask, legacyErr := task.toEnvelope().MarshalMsg(nil)
+	require.NoError(t, legacyErr)
+
+	var decodedTask Task
+	err = decodedTask.Deserialize(legacyEncodedTask, testTaskPayloadSerializer)
+	require.True(t, err.IsNil())
+	require.Equal(t, task.id, decodedTask.id)
+	require.Equal(t, task.Payload, decodedTask.Payload)
+
+	// Format from the future: unknown fields must be skipped.
+
+	futureEncodedTask := []byte{_TASK_ENVELOPE_VERSION}
+	futureEncodedTask = msgp.AppendMapHeader(futureEncodedTask, 3)
+	futureEncodedTask = msgp.AppendString(futureEncodedTask, "id")
+	futureEncodedTask = msgp.AppendString(futureEncodedTask, task.id)
+	futureEncodedTask = msgp.AppendString(futureEncodedTask, "unknown_field")
+	futureEncodedTask = msgp.AppendInt64(futureEncodedTask, time.Now().UnixNano())
+	futureEncodedTask = msgp.AppendString(futureEncodedTask, "p")
+	futureEncodedTask = msgp.AppendBytes(futureEncodedTask, task.payloadEncoded)
+
+	decodedTask = Task{}
+	err = decodedTask.Deserialize(futureEncodedTask, testTaskPayloadSerializer)
+	require.True(t, err.IsNil())
+	require.Equal(t, task.id, decodedTask.id)
+	require.Equal(t, task.Payload, decodedTask.Payload)
+
+	// Incompatible format from the future must be rejected.
+
+	futureEncodedTask[0] = _TASK_ENVELOPE_VERSION + 1
+	err = decodedTask.Deserialize(futureEncodedTask, testTaskPayloadSerializer)
+	require.True(t, err.IsNotNil())
+}
+
+func TestTaskEnvelopeError(t *testing.T) {
+
+	task := newTestTask()
+	task.Panic = "something went wrong"
+	task.Error = ekaerr.IllegalState.
+		New("Handler failed.").
+		WithString("user", "john").
+		WithInt("attempt", 2).
+		Throw()
+
+	encodedTask, err := task.Serialize(testTaskPayloadSerializer)
+	require.True(t, err.IsNil())
+
+	var decodedTask Task
+	err = decodedTask.Deserialize(encodedTask, testTaskPayloadSerializer)
+	require.True(t, err.IsNil())
+
+	require.Equal(t, "something went wrong", decodedTask.Panic)
+	require.True(t, decodedTask.Error.IsNotNil())
+	require.True(t, decodedTask.Error.Is(ekaerr.IllegalState))
+	require.Equal(t, task.Error.ID(), decodedTask.Error.ID())
+
+	var (
+		lWant = ekaunsafe.ErrorGetLetter(task.Error)
+		lGot  = ekaunsafe.ErrorGetLetter(decodedTask.Error)
+	)
+
+	require.Len(t, lGot.StackTrace, len(lWant.StackTrace))
+	for i := range lWant.StackTrace {
+		require.Equal(t, lWant.StackTrace[i].Function, lGot.StackTrace[i].Function)
+		require.Equal(t, lWant.StackTrace[i].Line, lGot.StackTrace[i].Line)
+	}
+
+	require.Len(t, lGot.Messages, 1)
+	require.Equal(t, "Handler failed.", lGot.Messages[0].Body)
+
+	require.Len(t, lGot.Fields, 2)
+	require.Equal(t, "user", lGot.Fields[0].Key)
+	require.Equal(t, "john", lGot.Fields[0].SValue)
+	require.Equal(t, "attempt", lGot.Fields[1].Key)
+	require.Equal(t, "2", lGot.Fields[1].SValue)
+}
+
+// TestTaskEnvelopeErrorFields pins the encoding of ekaerr.Error's fields of all kinds
+// and the keys of its system fields, that are mirrored from ekago,
+// against the real ekaerr.Error.
+func TestTaskEnvelopeErrorFields(t *testing.T) {
+
+	err := ekaerr.IllegalState.
+		New("Handler failed.").
+		WithBool("bool", true).
+		WithInt8("int8", -8).
+		WithInt64("int64", -64).
+		WithUint16("uint16", 16).
+		WithUintptr("uintptr", 32).
+		WithFloat32("float32", 1.5).
+		WithFloat64("float64", 2.25).
+		WithComplex64("complex64", complex(1, -2)).
+		WithComplex128("complex128", complex(3, 4)).
+		WithString("string", "john").
+		WithUnix("unix", 0).
+		WithUnixNano("unix_nano", 1).
+		WithDuration("duration", time.Second).
+		Throw()
+
+	var systemKeys []string
+	for _, f := range ekaunsafe.ErrorGetLetter(err).SystemFields {
+		systemKeys = append(systemKeys, f.Key)
+	}
+	require.Contains(t, systemKeys, _EKAERR_SYS_FIELD_KEY_CLASS_NAME)
+	require.Contains(t, systemKeys, _EKAERR_SYS_FIELD_KEY_ERROR_ID)
+
+	env := newTaskEnvelopeError(err)
+	require.Equal(t, ekaerr.IllegalState.FullName(), env.Class)
+	require.Equal(t, err.ID(), env.ID)
+
+	fields := make(map[string]string, len(env.Fields))
+	for _, f := range env.Fields {
+		fields[f.Key] = f.Value
+	}
+
+	require.Equal(t, map[string]string{
+		"bool":       "true",
+		"int8":       "-8",
+		"int64":      "-64",
+		"uint16":     "16",
+		"uintptr":    "32",
+		"float32":    "1.5",
+		"float64":    "2.25",
+		"complex64":  "(1-2i)",
+		"complex128": "(3+4i)",
+		"string":     "john",
+		"unix":       "1970-01-01T00:00:00Z",
+		"unix_nano":  "1970-01-01T00:00:00.000000001Z",
+		"duration":   "1s",
+	}, fields)
+}
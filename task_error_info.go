@@ -0,0 +1,103 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"github.com/qioalice/ekago/v3/ekaerr"
+)
+
+type (
+	// TaskErrorInfo is a plain representation of Task.Error,
+	// that is useful for inspecting and rendering (it's JSON friendly).
+	// Use Task.ErrorInfo() or NewTaskErrorInfo() to get it.
+	//
+	// Error's messages and fields are grouped by the stack frames
+	// they have been added at.
+	TaskErrorInfo struct {
+		ID    string                    `json:"id"`
+		Class string                    `json:"class"`
+		Stack []TaskErrorInfoStackFrame `json:"stack"`
+	}
+
+	// TaskErrorInfoStackFrame is a stack frame of TaskErrorInfo.
+	TaskErrorInfoStackFrame struct {
+		Function string                    `json:"function"`
+		File     string                    `json:"file"`
+		Line     int                       `json:"line"`
+		Messages []string                  `json:"messages,omitempty"`
+		Fields   []TaskErrorInfoField      `json:"fields,omitempty"`
+	}
+
+	// TaskErrorInfoField is a field of TaskErrorInfo.
+	// Whatever the original type of field's value was, it's a string.
+	TaskErrorInfoField struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+)
+
+// ErrorInfo returns a plain representation of Task.Error.
+// Returns nil if either Task is invalid or there is no error.
+func (t *Task) ErrorInfo() *TaskErrorInfo {
+	if !t.isValid() {
+		return nil
+	}
+	return NewTaskErrorInfo(t.Error)
+}
+
+// NewTaskErrorInfo returns a plain representation of the given *ekaerr.Error.
+// Returns nil if err is nil or invalid.
+func NewTaskErrorInfo(err *ekaerr.Error) *TaskErrorInfo {
+
+	env := newTaskEnvelopeError(err)
+	if env == nil {
+		return nil
+	}
+
+	info := &TaskErrorInfo{
+		ID:    env.ID,
+		Class: env.Class,
+		Stack: make([]TaskErrorInfoStackFrame, len(env.Stack)),
+	}
+
+	for i, n := 0, len(env.Stack); i < n; i++ {
+		info.Stack[i] = TaskErrorInfoStackFrame{
+			Function: env.Stack[i].Function,
+			File:     env.Stack[i].File,
+			Line:     env.Stack[i].Line,
+		}
+	}
+
+	for i, n := 0, len(env.Messages); i < n; i++ {
+		if idx := int(env.Messages[i].StackFrameIdx); idx < len(info.Stack) {
+			info.Stack[idx].Messages = append(info.Stack[idx].Messages, env.Messages[i].Body)
+		}
+	}
+
+	for i, n := 0, len(env.Fields); i < n; i++ {
+		if idx := int(env.Fields[i].StackFrameIdx); idx < len(info.Stack) {
+			info.Stack[idx].Fields = append(info.Stack[idx].Fields, TaskErrorInfoField{
+				Key:   env.Fields[i].Key,
+				Value: env.Fields[i].Value,
+			})
+		}
+	}
+
+	return info
+}
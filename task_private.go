@@ -19,10 +19,12 @@
 package bokchoy
 
 import (
+	"encoding/hex"
 	"fmt"
 	"time"
 
 	"github.com/qioalice/ekago/v3/ekaerr"
+	"github.com/qioalice/ekago/v3/ekaunsafe"
 )
 
 //goland:noinspection GoSnakeCaseUsage
@@ -45,6 +47,25 @@ func (t *Task) whyInvalid() string {
 	}
 }
 
+// snapshot returns a copy of the current Task, that may be used
+// independently (except Payload, that is shared).
+func (t *Task) snapshot() *Task {
+
+	snapshot := *t
+
+	if t.Headers != nil {
+		snapshot.Headers = make(map[string]string, len(t.Headers))
+		for k, v := range t.Headers {
+			snapshot.Headers[k] = v
+		}
+	}
+
+	snapshot.RetryIntervals = append(t.RetryIntervals[:0:0], t.RetryIntervals...)
+	snapshot.payloadEncoded = append(t.payloadEncoded[:0:0], t.payloadEncoded...)
+
+	return &snapshot
+}
+
 func (t *Task) markAsFailed() {
 	t.processedAt = time.Now().UTC().UnixNano()
 	t.status = TASK_STATUS_FAILED
@@ -54,6 +75,11 @@ func (t *Task) markAsFailed() {
 func (t *Task) markAsProcessing() {
 	t.startedAt = time.Now().UTC().UnixNano()
 	t.status = TASK_STATUS_PROCESSING
+
+	// Error and Panic of the previous attempt (if it's retried Task)
+	// are stored along with Task. Forget them, it's a new attempt.
+	t.Error = nil
+	t.Panic = nil
 }
 
 func (t *Task) markAsRetrying() {
@@ -65,6 +91,36 @@ func (t *Task) markAsTimedOut() {
 	t.status = TASK_STATUS_TIMED_OUT
 }
 
+// deserializeEnvelope decodes Task's fields (except Payload) from raw data.
+// See Deserialize(), deserializePayload().
+func (t *Task) deserializeEnvelope(data []byte) *ekaerr.Error {
+
+	var env taskEnvelope
+	if err := env.decode(data); err.IsNotNil() {
+		return err.AddMessage("Failed to decode task object.").
+			WithString("bokchoy_task_encoded_as_hex", hex.EncodeToString(data)).
+			Throw()
+	}
+
+	env.applyTo(t)
+	return nil
+}
+
+// deserializePayload decodes Task's Payload using presented Serializer.
+// Task's fields must be decoded by deserializeEnvelope() before.
+func (t *Task) deserializePayload(userPayloadSerializer Serializer) *ekaerr.Error {
+
+	if err := userPayloadSerializer.Loads(t.payloadEncoded, &t.Payload); err.IsNotNil() {
+		return err.AddMessage("Failed to deserialize user payload.").
+			WithString("bokchoy_task_id", t.id).
+			WithString("bokchoy_task_user_payload_as_hex", hex.EncodeToString(t.payloadEncoded)).
+			Throw()
+	}
+
+	t.payloadOldAddr = uintptr(ekaunsafe.TakeRealAddr(t.Payload))
+	return nil
+}
+
 // nextETA returns the next Task's ETA according with MaxRetries attempts counter.
 // Returns 0 if that counter is greater than length of RetryIntervals.
 func (t *Task) nextETA() int64 {
@@ -131,7 +187,14 @@ func (t *Task) fireMayContinue() bool {
 		// what will we do? Change status to TASK_STATUS_FAILED? Heh.
 		return true
 
-	case TASK_STATUS_WAITING, TASK_STATUS_PROCESSING, TASK_STATUS_SUCCEEDED, TASK_STATUS_CANCELLED:
+	case TASK_STATUS_CANCELLED:
+		// The same as above, but there is one more reason.
+		// Task might be cancelled while handler is under execution
+		// (see Queue.Cancel()), and handler may return an error because of that.
+		// Cancelled Task must not be retried.
+		return true
+
+	case TASK_STATUS_WAITING, TASK_STATUS_PROCESSING, TASK_STATUS_SUCCEEDED:
 		if t.Error.IsNil() && t.Panic == nil {
 			return true
 		}
@@ -0,0 +1,94 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+)
+
+type (
+	// taskProgress saves the progress of the Task under processing
+	// (see Task.SetProgress()) to the Broker, throttling it.
+	// It's set by consumer, thus progress of the Task, that is not under processing,
+	// is not saved at all.
+	taskProgress struct {
+		queue       *Queue
+		mu          sync.Mutex
+		lastSavedAt time.Time
+		isStopped   bool // see stop()
+	}
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	// _TASK_PROGRESS_SAVE_INTERVAL is how often the progress of one Task
+	// may be saved to the Broker at most.
+	_TASK_PROGRESS_SAVE_INTERVAL = 1 * time.Second
+)
+
+// trackProgress makes the progress of Task, that is going to be processed,
+// saved to the Broker. See Task.SetProgress().
+func (q *Queue) trackProgress(t *Task) {
+	t.progressSaver = &taskProgress{queue: q}
+}
+
+// save sets the progress of the Task, saves the Task and reports it,
+// unless it's been saved less than _TASK_PROGRESS_SAVE_INTERVAL ago.
+//
+// The Task is saved from the goroutine of its handlers,
+// so there's no need to copy it. But if it's timed out (see stop()),
+// it's been modified and saved by consumer and must not be touched.
+func (tp *taskProgress) save(t *Task, percent int8, message string) *ekaerr.Error {
+
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	if tp.isStopped {
+		return nil
+	}
+
+	t.progress = percent
+	t.progressMsg = message
+
+	if t.status != TASK_STATUS_PROCESSING ||
+		time.Since(tp.lastSavedAt) < _TASK_PROGRESS_SAVE_INTERVAL {
+		return nil
+	}
+
+	if err := tp.queue.save(t); err.IsNotNil() {
+		return err.Throw()
+	}
+
+	tp.lastSavedAt = time.Now()
+	tp.queue.parent.events.emit(EVENT_TYPE_TASK_PROGRESS, tp.queue.name, t)
+
+	return nil
+}
+
+// stop makes the progress of the Task, that is timed out, ignored.
+// It waits until the progress being saved right now is saved,
+// so the Task is not accessed by save() after stop() is returned.
+func (tp *taskProgress) stop() {
+	tp.mu.Lock()
+	tp.isStopped = true
+	tp.mu.Unlock()
+}
@@ -0,0 +1,86 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	// _TASK_SIGNATURE_LEN is the length of HMAC-SHA256 signature,
+	// that is appended to the end of encoded Task if signing key is presented.
+	_TASK_SIGNATURE_LEN = sha256.Size
+
+	// _TASK_SIGNATURE_MARKER is the first byte of signed Task, so it's
+	// distinguished from the not signed one explicitly (see taskEnvelope.decode()).
+	// It's neither a version of taskEnvelope, nor a msgpack map header.
+	_TASK_SIGNATURE_MARKER byte = 0xFE
+)
+
+// signTask returns encodedTask prepended by _TASK_SIGNATURE_MARKER
+// and appended by HMAC-SHA256 signature of it, calculated using provided key.
+// Returns encodedTask as is if key is empty.
+func signTask(encodedTask, key []byte) []byte {
+
+	if len(key) == 0 {
+		return encodedTask
+	}
+
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write(encodedTask)
+
+	signedTask := make([]byte, 0, 1 + len(encodedTask) + _TASK_SIGNATURE_LEN)
+	signedTask = append(signedTask, _TASK_SIGNATURE_MARKER)
+	signedTask = append(signedTask, encodedTask...)
+
+	return mac.Sum(signedTask)
+}
+
+// verifyTask checks whether signedTask is marked as signed and has valid
+// HMAC-SHA256 signature at the end, that has been calculated using provided key.
+// Returns the encoded Task w/o signature and true if signature is valid.
+//
+// Returns signedTask as is and true if key is empty
+// (signing is disabled, nothing to verify).
+func verifyTask(signedTask, key []byte) ([]byte, bool) {
+
+	if len(key) == 0 {
+		return signedTask, true
+	}
+
+	if len(signedTask) <= 1 + _TASK_SIGNATURE_LEN || signedTask[0] != _TASK_SIGNATURE_MARKER {
+		return nil, false
+	}
+
+	var (
+		dataLen       = len(signedTask) - _TASK_SIGNATURE_LEN
+		encodedTask   = signedTask[1:dataLen]
+		signatureGot  = signedTask[dataLen:]
+		mac           = hmac.New(sha256.New, key)
+	)
+
+	_, _ = mac.Write(encodedTask)
+	if !hmac.Equal(mac.Sum(nil), signatureGot) {
+		return nil, false
+	}
+
+	return encodedTask, true
+}
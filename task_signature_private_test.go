@@ -0,0 +1,89 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"testing"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskSignature(t *testing.T) {
+
+	var (
+		key         = []byte("secret")
+		encodedTask = []byte("encoded task")
+	)
+
+	signedTask := signTask(append([]byte(nil), encodedTask...), key)
+	require.Len(t, signedTask, 1 + len(encodedTask) + _TASK_SIGNATURE_LEN)
+	require.Equal(t, _TASK_SIGNATURE_MARKER, signedTask[0])
+
+	verifiedTask, ok := verifyTask(signedTask, key)
+	require.True(t, ok)
+	require.Equal(t, encodedTask, verifiedTask)
+
+	_, ok = verifyTask(signedTask, []byte("another secret"))
+	require.False(t, ok)
+
+	tamperedTask := append([]byte(nil), signedTask...)
+	tamperedTask[1] ^= 0xFF
+	_, ok = verifyTask(tamperedTask, key)
+	require.False(t, ok)
+
+	tamperedTask = append([]byte(nil), signedTask...)
+	tamperedTask[0] = _TASK_ENVELOPE_VERSION
+	_, ok = verifyTask(tamperedTask, key)
+	require.False(t, ok)
+
+	_, ok = verifyTask(encodedTask, key)
+	require.False(t, ok)
+
+	// Signing is disabled.
+	verifiedTask, ok = verifyTask(encodedTask, nil)
+	require.True(t, ok)
+	require.Equal(t, encodedTask, verifiedTask)
+}
+
+func TestTaskSignatureDeserialize(t *testing.T) {
+
+	key := []byte("secret")
+
+	encodedTask, err := newTestTask().Serialize(testTaskPayloadSerializer)
+	require.True(t, err.IsNil())
+
+	signedTask := signTask(encodedTask, key)
+
+	// Signature can not be verified w/o key, so signed task is rejected.
+	err = new(Task).Deserialize(signedTask, testTaskPayloadSerializer)
+	require.True(t, err.IsNotNil())
+	require.True(t, err.Is(ekaerr.RejectedOperation))
+
+	verifiedTask, ok := verifyTask(signedTask, key)
+	require.True(t, ok)
+	require.True(t, new(Task).Deserialize(verifiedTask, testTaskPayloadSerializer).IsNil())
+
+	// Not signed task with some garbage of the signature's length at the end.
+	garbage := make([]byte, _TASK_SIGNATURE_LEN)
+	err = new(Task).Deserialize(append(encodedTask, garbage...), testTaskPayloadSerializer)
+	require.True(t, err.IsNotNil())
+	require.True(t, err.Is(ekaerr.IllegalFormat))
+}
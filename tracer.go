@@ -0,0 +1,72 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"context"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+)
+
+type (
+	// Tracer is an OpenTelemetry-like tracer, that allows to continue a trace
+	// started by a producer (e.g. in an HTTP request) into the Task's handlers.
+	// Use WithTracer() option to register it.
+	//
+	// At the publishing, a "bokchoy.publish" span is started as a child of
+	// the Task's context span (see WithContext()), and its span context is injected
+	// into the Task (it's stored along with Task).
+	//
+	// At the processing, span context is extracted from the Task,
+	// and a "bokchoy.process" span is started as its child.
+	// It's available from handlers using Task.Context().
+	// Task's status, retries and error are recorded to the processing span.
+	//
+	// It's easy to implement Tracer as an adapter of OpenTelemetry SDK.
+	// See also tracing package for an in-memory implementation.
+	Tracer interface {
+
+		// Start starts a new span with the given name as a child of the span
+		// from ctx (if any), and returns a new context, that contains a new span.
+		Start(ctx context.Context, spanName string) (context.Context, Span)
+
+		// Inject writes span context of the span from ctx (if any) to the carrier.
+		Inject(ctx context.Context, carrier map[string]string)
+
+		// Extract reads span context from the carrier and returns a copy of ctx
+		// with that span context as a parent for the spans being started.
+		Extract(ctx context.Context, carrier map[string]string) context.Context
+	}
+
+	// Span is a started span of the Tracer.
+	Span interface {
+
+		// SetAttribute sets an attribute of the span.
+		SetAttribute(key string, value interface{})
+
+		// RecordError records an error as the span's event.
+		RecordError(err *ekaerr.Error)
+
+		// SetStatus sets the status of the span.
+		SetStatus(isOK bool, description string)
+
+		// End completes the span.
+		End()
+	}
+)
@@ -0,0 +1,113 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	_TRACE_SPAN_NAME_PUBLISH = "bokchoy.publish"
+	_TRACE_SPAN_NAME_PROCESS = "bokchoy.process"
+)
+
+// tracePublishing starts the publishing span of the Task as a child of
+// Task.Context()'s one, and injects its span context into the Task.
+// Returns nil if there is no Tracer (WithTracer() option) or Task is invalid.
+func (q *Queue) tracePublishing(t *Task) Span {
+
+	if q.options.Tracer == nil || !t.isValid() {
+		return nil
+	}
+
+	ctx, span := q.options.Tracer.Start(t.Context(), _TRACE_SPAN_NAME_PUBLISH)
+	span.SetAttribute("bokchoy.queue_name", q.name)
+	span.SetAttribute("bokchoy.task_id", t.id)
+
+	t.traceContext = make(map[string]string)
+	q.options.Tracer.Inject(ctx, t.traceContext)
+
+	return span
+}
+
+// traceProcessing starts the processing span of the Task as a child of
+// the span, which context has been injected into the Task at the publishing.
+// Task's context is replaced by the context of the processing span.
+// Returns nil if there is no Tracer (WithTracer() option).
+func (q *Queue) traceProcessing(t *Task) Span {
+
+	if q.options.Tracer == nil {
+		return nil
+	}
+
+	ctx := q.options.Tracer.Extract(t.Context(), t.traceContext)
+	ctx, span := q.options.Tracer.Start(ctx, _TRACE_SPAN_NAME_PROCESS)
+	span.SetAttribute("bokchoy.queue_name", q.name)
+	span.SetAttribute("bokchoy.task_id", t.id)
+	span.SetAttribute("bokchoy.task_retries_left", t.MaxRetries)
+
+	t.ctx = ctx
+	return span
+}
+
+// traceProcessed records the result of Task's processing to the span,
+// started by traceProcessing() and ends it.
+// err is an error of saving the Task or returning it to be retried.
+// Does nothing if span is nil.
+func (q *Queue) traceProcessed(span Span, t *Task, err *ekaerr.Error) {
+
+	if span == nil {
+		return
+	}
+
+	span.SetAttribute("bokchoy.task_status", t.status.String())
+	span.SetAttribute("bokchoy.task_exec_time", t.ExecTime.String())
+
+	if t.status == TASK_STATUS_RETRYING {
+		span.SetAttribute("bokchoy.task_retry_eta", time.Unix(0, t.ETA).UTC().String())
+	}
+	if t.Panic != nil {
+		span.SetAttribute("bokchoy.task_panicked", true)
+	}
+	if t.Error.IsNotNil() {
+		span.RecordError(t.Error)
+	}
+
+	traceEnd(span, err, t.status == TASK_STATUS_SUCCEEDED, t.status.String())
+}
+
+// traceEnd records err (if any) to the span, sets span's status and ends it.
+// Does nothing if span is nil.
+func traceEnd(span Span, err *ekaerr.Error, isOK bool, description string) {
+
+	if span == nil {
+		return
+	}
+
+	if err.IsNotNil() {
+		span.RecordError(err)
+		isOK, description = false, err.Class().FullName()
+	}
+
+	span.SetStatus(isOK, description)
+	span.End()
+}
@@ -0,0 +1,128 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+)
+
+type (
+	// MemoryTracer is a bokchoy.Tracer, that stores all ended spans in RAM.
+	// Use NewMemoryTracer() to create it.
+	MemoryTracer struct {
+		mu     sync.Mutex
+		spans  []SpanData
+	}
+
+	// SpanData is a snapshot of ended span.
+	SpanData struct {
+		Name              string
+
+		TraceID           string
+		SpanID            string
+		ParentSpanID      string // empty if it's a root span
+
+		Attributes        map[string]interface{}
+		Errors            []*ekaerr.Error
+
+		IsOK              bool
+		StatusDescription string
+
+		StartedAt         time.Time
+		EndedAt           time.Time
+	}
+)
+
+// Make sure MemoryTracer implements bokchoy.Tracer.
+var _ bokchoy.Tracer = (*MemoryTracer)(nil)
+
+// NewMemoryTracer returns a new MemoryTracer with no spans.
+func NewMemoryTracer() *MemoryTracer {
+	return new(MemoryTracer)
+}
+
+// Start implements bokchoy.Tracer.
+// A new span is a root span (of a new trace) if there is no span in ctx.
+func (t *MemoryTracer) Start(ctx context.Context, spanName string) (context.Context, bokchoy.Span) {
+
+	parent, _ := ctx.Value(spanContextKey{}).(spanContext)
+
+	sc := spanContext{
+		traceID: parent.traceID,
+		spanID:  newID(_SPAN_ID_LEN),
+	}
+	if sc.traceID == "" {
+		sc.traceID = newID(_TRACE_ID_LEN)
+	}
+
+	s := &span{
+		tracer: t,
+		data: SpanData{
+			Name:         spanName,
+			TraceID:      sc.traceID,
+			SpanID:       sc.spanID,
+			ParentSpanID: parent.spanID,
+			Attributes:   make(map[string]interface{}),
+			StartedAt:    time.Now(),
+		},
+	}
+
+	return context.WithValue(ctx, spanContextKey{}, sc), s
+}
+
+// Inject implements bokchoy.Tracer.
+// Does nothing if there is no span in ctx.
+func (t *MemoryTracer) Inject(ctx context.Context, carrier map[string]string) {
+	if sc, ok := ctx.Value(spanContextKey{}).(spanContext); ok && carrier != nil {
+		carrier[_TRACEPARENT_KEY] = sc.encode()
+	}
+}
+
+// Extract implements bokchoy.Tracer.
+// Returns ctx as is, if there is no valid span context in carrier.
+func (t *MemoryTracer) Extract(ctx context.Context, carrier map[string]string) context.Context {
+	if sc, ok := decodeSpanContext(carrier[_TRACEPARENT_KEY]); ok {
+		return context.WithValue(ctx, spanContextKey{}, sc)
+	}
+	return ctx
+}
+
+// Spans returns all ended spans in order they have been ended.
+func (t *MemoryTracer) Spans() []SpanData {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append(t.spans[:0:0], t.spans...)
+}
+
+// Reset forgets all ended spans.
+func (t *MemoryTracer) Reset() {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.spans = nil
+}
@@ -0,0 +1,133 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+)
+
+type (
+	// span is a bokchoy.Span of MemoryTracer.
+	// It's stored to the MemoryTracer at the End() call.
+	span struct {
+		tracer  *MemoryTracer
+		mu      sync.Mutex
+		data    SpanData
+		isEnded bool
+	}
+
+	// spanContext is an identity of span, that is propagated.
+	spanContext struct {
+		traceID string
+		spanID  string
+	}
+
+	// spanContextKey is a key of spanContext in context.Context.
+	spanContextKey struct{}
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	_TRACEPARENT_KEY     = "traceparent"
+	_TRACEPARENT_VERSION = "00"
+	_TRACEPARENT_FLAGS   = "01" // sampled
+
+	_TRACE_ID_LEN = 16 // in bytes
+	_SPAN_ID_LEN  = 8  // in bytes
+)
+
+func (s *span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Attributes[key] = value
+}
+
+func (s *span) RecordError(err *ekaerr.Error) {
+	if err.IsNil() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Errors = append(s.data.Errors, err)
+}
+
+func (s *span) SetStatus(isOK bool, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.IsOK = isOK
+	s.data.StatusDescription = description
+}
+
+// End stores the span to its MemoryTracer.
+// Does nothing if span has been ended already.
+func (s *span) End() {
+	s.mu.Lock()
+	if s.isEnded {
+		s.mu.Unlock()
+		return
+	}
+	s.isEnded = true
+	s.data.EndedAt = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	s.tracer.mu.Lock()
+	s.tracer.spans = append(s.tracer.spans, data)
+	s.tracer.mu.Unlock()
+}
+
+// encode returns W3C Trace Context "traceparent" representation of spanContext.
+func (sc spanContext) encode() string {
+	return _TRACEPARENT_VERSION + "-" + sc.traceID + "-" + sc.spanID + "-" + _TRACEPARENT_FLAGS
+}
+
+// decodeSpanContext parses W3C Trace Context "traceparent" value.
+func decodeSpanContext(traceparent string) (spanContext, bool) {
+
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 ||
+		len(parts[1]) != _TRACE_ID_LEN*2 || !isHex(parts[1]) ||
+		len(parts[2]) != _SPAN_ID_LEN*2 || !isHex(parts[2]) {
+		return spanContext{}, false
+	}
+
+	return spanContext{traceID: parts[1], spanID: parts[2]}, true
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// newID returns a new random hex-encoded ID of n bytes.
+func newID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,72 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy/tracing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryTracer(t *testing.T) {
+
+	tracer := tracing.NewMemoryTracer()
+
+	ctx, root := tracer.Start(context.Background(), "http.request")
+	carrier := make(map[string]string)
+	tracer.Inject(ctx, carrier)
+	root.End()
+
+	require.Len(t, carrier, 1)
+
+	_, child := tracer.Start(tracer.Extract(context.Background(), carrier), "bokchoy.process")
+	child.SetAttribute("bokchoy.task_id", "1")
+	child.RecordError(ekaerr.IllegalArgument.New("Bad payload.").Throw())
+	child.SetStatus(false, "failed")
+	child.End()
+	child.End() // must be ignored
+
+	spans := tracer.Spans()
+	require.Len(t, spans, 2)
+
+	require.Equal(t, "http.request", spans[0].Name)
+	require.Empty(t, spans[0].ParentSpanID)
+
+	require.Equal(t, "bokchoy.process", spans[1].Name)
+	require.Equal(t, spans[0].TraceID, spans[1].TraceID)
+	require.Equal(t, spans[0].SpanID, spans[1].ParentSpanID)
+	require.Equal(t, "1", spans[1].Attributes["bokchoy.task_id"])
+	require.Len(t, spans[1].Errors, 1)
+	require.False(t, spans[1].IsOK)
+
+	// Invalid carrier leads to a new trace.
+	_, orphan := tracer.Start(tracer.Extract(context.Background(), map[string]string{"traceparent": "garbage"}), "orphan")
+	orphan.End()
+
+	spans = tracer.Spans()
+	require.NotEqual(t, spans[0].TraceID, spans[2].TraceID)
+
+	tracer.Reset()
+	require.Empty(t, tracer.Spans())
+}
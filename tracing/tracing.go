@@ -0,0 +1,37 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+// Package tracing provides an in-memory implementation of bokchoy.Tracer.
+//
+// MemoryTracer keeps all ended spans in RAM, thus it's useful for tests
+// and debugging, but not for production. For production implement
+// bokchoy.Tracer as an adapter of your tracing SDK (e.g. OpenTelemetry).
+//
+// Span context is propagated using W3C Trace Context "traceparent" format:
+//
+//     tracer := tracing.NewMemoryTracer()
+//     b, err := bokchoy.New(bokchoy.WithTracer(tracer), ...)
+//
+//     ctx, span := tracer.Start(ctx, "http.request")
+//     b.Publish("tasks.send_email", payload, bokchoy.WithContext(ctx))
+//     span.End()
+//
+//     // later
+//     spans := tracer.Spans() // http.request -> bokchoy.publish -> bokchoy.process
+//
+package tracing
@@ -0,0 +1,157 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+)
+
+type (
+	// TypedHandlerFunc is a handler of tasks with payload of type T.
+	// ctx is the Task's context (see Task.Context()),
+	// the Task itself may be obtained using TaskFromContext().
+	// See Register().
+	TypedHandlerFunc[T any] func(ctx context.Context, payload T) *ekaerr.Error
+
+	// TaskTyper is an optional interface, that may be implemented by payload's type
+	// to override the task's type, used by Register() and PublishTyped().
+	// See TaskTypeOf().
+	//
+	// WARNING!
+	// TaskType() is called for the zero value of payload's type
+	// (a pointer to the zero value for pointers), so it must not depend on the value.
+	TaskTyper interface {
+		TaskType() string
+	}
+
+	// taskContextKey is a context.Context key of the Task under processing.
+	// See TaskFromContext().
+	taskContextKey struct{}
+)
+
+// Register registers a handler of tasks with payload of type T on the queue.
+//
+// Tasks are routed to the handler by their type (see Queue.Handle()),
+// that is TaskTypeOf[T](). Their payloads are encoded as JSON using
+// CustomSerializerJSON(T) regardless of the queue's Serializer
+// (see Queue.SerializerOf()), thus handler receives the payload already typed.
+// Processes, that have not registered T, decode such payloads as JSON
+// of unknown structure (see Queue.SerializerOfTask()).
+//
+//     bokchoy.Register(queue, func(ctx context.Context, email Email) *ekaerr.Error {
+//         return send(ctx, email.To, email.Body)
+//     })
+//
+//     bokchoy.PublishTyped(queue, Email{To: "john@example.com"})
+//
+// Does nothing if Bokchoy already running (Run() has called).
+func Register[T any](q *Queue, handler TypedHandlerFunc[T]) *Queue {
+	const s = "Bokchoy: Failed to handle typed task. "
+
+	if !q.isValid() {
+		return nil
+	}
+
+	if handler == nil {
+		return q
+	}
+
+	taskType := TaskTypeOf[T]()
+	q.registerSerializerOf(taskType, newTypedSerializer[T]())
+
+	return q.Handle(taskType, func(task *Task) *ekaerr.Error {
+
+		payload, ok := task.Payload.(T)
+		if !ok {
+			return ekaerr.IllegalArgument.
+				New(s + "Unexpected payload type.").
+				WithString("bokchoy_task_type", taskType).
+				WithString("bokchoy_task_payload_type", fmt.Sprintf("%T", task.Payload)).
+				Throw()
+		}
+
+		ctx := context.WithValue(task.Context(), taskContextKey{}, task)
+		return handler(ctx, payload).Throw()
+	})
+}
+
+// PublishTyped publishes a new task with payload of type T to the queue.
+// The task's type is TaskTypeOf[T](), its payload is encoded as JSON.
+// See Register() for more details.
+func PublishTyped[T any](q *Queue, payload T, options ...Option) (*Task, *ekaerr.Error) {
+
+	if q.isValid() {
+		taskType := TaskTypeOf[T]()
+		q.registerSerializerOf(taskType, newTypedSerializer[T]())
+		options = append(options[:len(options):len(options)], WithTaskType(taskType))
+	}
+
+	return q.Publish(payload, options...)
+}
+
+// TaskTypeOf returns the task's type (see WithTaskType()) of payloads of type T,
+// that is used by Register() and PublishTyped().
+//
+// It's TaskTyper.TaskType() if T implements it, or the full name of T otherwise,
+// including its package path, like "github.com/you/app/tasks.Email".
+// T and *T are different types.
+func TaskTypeOf[T any]() string {
+
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+
+	// TaskType() of *T may have a value receiver, that panics for nil,
+	// so it's called for a pointer to the zero value instead.
+	var zero interface{} = *new(T)
+	if typ.Kind() == reflect.Ptr {
+		zero = reflect.New(typ.Elem()).Interface()
+	}
+
+	if typer, ok := zero.(TaskTyper); ok {
+		return typer.TaskType()
+	}
+
+	pointers := ""
+	for typ.Kind() == reflect.Ptr && typ.Name() == "" {
+		pointers += "*"
+		typ = typ.Elem()
+	}
+
+	if typ.Name() == "" || typ.PkgPath() == "" {
+		return pointers + typ.String()
+	}
+
+	return pointers + typ.PkgPath() + "." + typ.Name()
+}
+
+// TaskFromContext returns the Task under processing from the context,
+// that is passed to TypedHandlerFunc. Returns nil if there is no Task.
+func TaskFromContext(ctx context.Context) *Task {
+	task, _ := ctx.Value(taskContextKey{}).(*Task)
+	return task
+}
+
+// newTypedSerializer returns a Serializer of payloads of type T.
+func newTypedSerializer[T any]() Serializer {
+	var zero T
+	return CustomSerializerJSON(zero)
+}
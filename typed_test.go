@@ -0,0 +1,104 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+	"github.com/qioalice/bokchoy/internal/brokertest"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testEmailPayload struct {
+	To string `json:"to"`
+}
+
+type testTypedPayload struct{}
+
+func (testTypedPayload) TaskType() string { return "typed" }
+
+func TestTaskTypeOf(t *testing.T) {
+	require.Equal(t, "github.com/qioalice/bokchoy_test.testEmailPayload", bokchoy.TaskTypeOf[testEmailPayload]())
+	require.Equal(t, "*github.com/qioalice/bokchoy_test.testEmailPayload", bokchoy.TaskTypeOf[*testEmailPayload]())
+	require.Equal(t, "typed", bokchoy.TaskTypeOf[testTypedPayload]())
+	require.Equal(t, "typed", bokchoy.TaskTypeOf[*testTypedPayload]()) // value receiver, must not panic
+	require.Equal(t, "[]string", bokchoy.TaskTypeOf[[]string]())
+}
+
+func TestRegister(t *testing.T) {
+
+	var (
+		q         *bokchoy.Queue
+		delivered = make(chan testEmailPayload, 1)
+		hasTask   bool
+	)
+
+	// Queue's serializer is CustomSerializerJSON(testTaskPayload{}),
+	// typed tasks must not be encoded by it.
+	_, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		q = bokchoy.Register(b.Queue("tasks.test"), func(ctx context.Context, email testEmailPayload) *ekaerr.Error {
+			hasTask = bokchoy.TaskFromContext(ctx) != nil
+			delivered <- email
+			return nil
+		})
+	})
+	defer stop()
+
+	task, err := bokchoy.PublishTyped(q, testEmailPayload{To: "john@example.com"})
+	require.True(t, err.IsNil())
+	require.Equal(t, bokchoy.TaskTypeOf[testEmailPayload](), task.Type())
+
+	select {
+	case email := <-delivered:
+		require.Equal(t, "john@example.com", email.To)
+		require.True(t, hasTask)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Typed task has not been delivered in time.")
+	}
+}
+
+func TestPublishTypedUnregistered(t *testing.T) {
+
+	broker := brokertest.NewMemoryBroker()
+
+	newQueue := func() *bokchoy.Queue {
+		b, err := bokchoy.New(
+			bokchoy.WithBroker(broker),
+			bokchoy.WithSerializer(testTaskPayloadSerializer),
+			bokchoy.WithDisableOutput(true),
+		)
+		require.True(t, err.IsNil())
+		return b.Queue("tasks.test")
+	}
+
+	task, err := bokchoy.PublishTyped(newQueue(), testEmailPayload{To: "john@example.com"})
+	require.True(t, err.IsNil())
+
+	// Another process, that has not registered testEmailPayload,
+	// must not decode it by the queue's serializer.
+	stored, err := newQueue().Get(task.ID())
+	require.True(t, err.IsNil())
+	require.Equal(t, map[string]interface{}{"to": "john@example.com"}, stored.Payload)
+}
@@ -0,0 +1,114 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"sort"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/json-iterator/go"
+)
+
+type (
+	// WorkerInfo is an information about the running Bokchoy instance (worker),
+	// that is registered in the Broker and is kept alive by its heartbeats.
+	// See WithHeartbeatInterval(), Bokchoy.Workers().
+	WorkerInfo struct {
+		ID                string            `json:"id"`
+		Hostname          string            `json:"hostname"`
+		PID               int               `json:"pid"`
+		Version           string            `json:"version"`
+		Queues            []WorkerQueueInfo `json:"queues"`
+		StartedAt         time.Time         `json:"started_at"`
+		LastHeartbeat     time.Time         `json:"last_heartbeat"`
+		HeartbeatInterval time.Duration     `json:"heartbeat_interval"`
+
+		// IsAlive reports whether the worker's heartbeat is not expired.
+		// It's not stored, but evaluated by Bokchoy.Workers().
+		IsAlive           bool              `json:"-"`
+	}
+
+	// WorkerQueueInfo is an information about the queue, consumed by the worker.
+	WorkerQueueInfo struct {
+		Name        string `json:"name"`
+		Concurrency int8   `json:"concurrency"`
+	}
+)
+
+// Workers returns all workers, registered in the Broker, sorted by their start time.
+//
+// The worker is considered dead (WorkerInfo.IsAlive is false),
+// if it has missed _WORKER_HEARTBEAT_MISSES heartbeats in a row.
+// Dead workers are kept by the Broker for a while, and then they're removed.
+//
+// Returns an error if Broker doesn't implement BrokerWorkerRegistry.
+func (b *Bokchoy) Workers() ([]WorkerInfo, *ekaerr.Error) {
+	const s = "Bokchoy: Failed to get workers. "
+
+	if !b.isValid() {
+		return nil, ekaerr.InitializationFailed.
+			New(s + "Bokchoy is not initialized. " +
+				"Did you just create an object instead of using constructor or initializer?").
+			Throw()
+	}
+
+	registry, ok := b.broker.(BrokerWorkerRegistry)
+	if !ok {
+		return nil, ekaerr.UnsupportedOperation.
+			New(s + "Broker doesn't support workers registry.").
+			WithString("bokchoy_broker", b.broker.String()).
+			Throw()
+	}
+
+	encodedWorkers, err := registry.Workers()
+	if err.IsNotNil() {
+		return nil, err.
+			AddMessage(s).
+			Throw()
+	}
+
+	workers := make([]WorkerInfo, 0, len(encodedWorkers))
+	for i := range encodedWorkers {
+
+		var worker WorkerInfo
+		if legacyErr := jsoniter.Unmarshal(encodedWorkers[i], &worker); legacyErr != nil {
+			err = ekaerr.IllegalState.
+				Wrap(legacyErr, "Failed to decode worker.").
+				Throw()
+			b.logger.Warne(s + "Worker is skipped.", err)
+			continue
+		}
+
+		expiration := worker.HeartbeatInterval * _WORKER_HEARTBEAT_MISSES
+		worker.IsAlive = time.Since(worker.LastHeartbeat) <= expiration
+
+		workers = append(workers, worker)
+	}
+
+	sort.Slice(workers, func(i, j int) bool {
+		if !workers[i].StartedAt.Equal(workers[j].StartedAt) {
+			return workers[i].StartedAt.Before(workers[j].StartedAt)
+		}
+		return workers[i].ID < workers[j].ID
+	})
+
+	return workers, nil
+}
@@ -0,0 +1,161 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+	"github.com/qioalice/ekago/v3/ekalog"
+	"github.com/qioalice/ekago/v3/ekatyp"
+
+	"github.com/json-iterator/go"
+)
+
+type (
+	// heartbeat registers the running Bokchoy instance in the Broker
+	// (see BrokerWorkerRegistry) and keeps it alive, saving WorkerInfo
+	// each WithHeartbeatInterval().
+	//
+	// It's created by Bokchoy.Run() if Broker supports workers registry,
+	// its loop is run in a separate goroutine until stop() is called.
+	//
+	// WARNING!
+	// It must not lock Bokchoy.sema, because Bokchoy.Stop() waits for it
+	// holding sema. That's why queues are captured at the creation.
+	heartbeat struct {
+		registry BrokerWorkerRegistry
+		logger   *ekalog.Logger
+		info     WorkerInfo // w/o queues and last heartbeat, they're updated by beat()
+		queues   []*Queue
+
+		stopChan chan struct{}
+		stopOnce sync.Once
+		doneChan chan struct{}
+	}
+)
+
+//goland:noinspection GoSnakeCaseUsage
+const (
+	// _WORKER_HEARTBEAT_MISSES is how many heartbeats in a row
+	// the worker may miss before it's considered dead.
+	_WORKER_HEARTBEAT_MISSES = 3
+
+	// _WORKER_TTL_HEARTBEATS is how many heartbeat intervals
+	// the worker's info is kept by the Broker since the last heartbeat.
+	// Dead workers must be kept longer than alive ones, so they can be reported.
+	_WORKER_TTL_HEARTBEATS = 10
+)
+
+// newHeartbeat returns a new heartbeat of the Bokchoy,
+// or nil if heartbeats are disabled or Broker doesn't support them.
+// Caller must take responsibility about locking Bokchoy.sema.
+func newHeartbeat(b *Bokchoy) *heartbeat {
+
+	registry, ok := b.broker.(BrokerWorkerRegistry)
+	if !ok || b.defaultOptions.HeartbeatInterval <= 0 {
+		return nil
+	}
+
+	hostname, _ := os.Hostname()
+
+	return &heartbeat{
+		registry: registry,
+		logger:   b.logger,
+		info: WorkerInfo{
+			ID:                ekatyp.ULID_New_OrNil().String(),
+			Hostname:          hostname,
+			PID:               os.Getpid(),
+			Version:           VERSION,
+			StartedAt:         time.Now(),
+			HeartbeatInterval: b.defaultOptions.HeartbeatInterval,
+		},
+		queues:   b.sortedQueues(),
+		stopChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+}
+
+// run is heartbeat's loop. It's done when stop() is called.
+// The first beat must be done before it's called (see Bokchoy.Run()).
+func (h *heartbeat) run() {
+	defer close(h.doneChan)
+
+	ticker := time.NewTicker(h.info.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stopChan:
+			return
+		case <-ticker.C:
+			h.beat()
+		}
+	}
+}
+
+// stop stops heartbeat's loop, waits until it's done and unregisters the worker.
+// It's safe to call it more than once.
+func (h *heartbeat) stop() {
+	const s = "Bokchoy: Failed to unregister worker. "
+
+	h.stopOnce.Do(func() {
+		close(h.stopChan)
+		<-h.doneChan
+
+		if err := h.registry.DeleteWorker(h.info.ID); err.IsNotNil() {
+			h.logger.Copy().
+				WithString("bokchoy_worker_id", h.info.ID).
+				Warne(s, err)
+		}
+	})
+}
+
+// beat saves the current WorkerInfo to the Broker.
+func (h *heartbeat) beat() {
+	const s = "Bokchoy: Failed to save worker's heartbeat. "
+
+	info := h.info
+	info.LastHeartbeat = time.Now()
+	info.Queues = make([]WorkerQueueInfo, len(h.queues))
+
+	for i, q := range h.queues {
+		info.Queues[i] = WorkerQueueInfo{
+			Name:        q.Name(),
+			Concurrency: q.Concurrency(),
+		}
+	}
+
+	data, legacyErr := jsoniter.Marshal(info)
+	if legacyErr != nil {
+		h.logger.Copy().
+			WithString("bokchoy_worker_id", info.ID).
+			Warne(s, ekaerr.InternalError.Wrap(legacyErr, "Failed to encode worker.").Throw())
+		return
+	}
+
+	ttl := info.HeartbeatInterval * _WORKER_TTL_HEARTBEATS
+	if err := h.registry.SaveWorker(info.ID, data, ttl); err.IsNotNil() {
+		h.logger.Copy().
+			WithString("bokchoy_worker_id", info.ID).
+			Warne(s, err)
+	}
+}
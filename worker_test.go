@@ -0,0 +1,235 @@
+//
+// ORIGINAL PACKAGE
+// ( https://github.com/thoas/bokchoy )
+//
+//     Copyright © 2019. All rights reserved.
+//     Author: Florent Messa
+//     Contacts: florent.messa@gmail.com, https://github.com/thoas
+//     License: https://opensource.org/licenses/MIT
+//
+// HAS BEEN FORKED, HIGHLY MODIFIED AND NOW IS AVAILABLE AS
+// ( https://github.com/qioalice/bokchoy )
+//
+//     Copyright © 2020. All rights reserved.
+//     Author: Ilya Stroy.
+//     Contacts: qioalice@gmail.com, https://github.com/qioalice
+//     License: https://opensource.org/licenses/MIT
+//
+
+package bokchoy_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/qioalice/ekago/v3/ekaerr"
+
+	"github.com/qioalice/bokchoy"
+	"github.com/qioalice/bokchoy/internal/brokertest"
+
+	"github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkers(t *testing.T) {
+
+	broker := brokertest.NewMemoryBroker()
+
+	b, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+		b.Queue("tasks.test").Use(func(_ *bokchoy.Task) *ekaerr.Error {
+			return nil
+		})
+	}, bokchoy.WithBroker(broker), bokchoy.WithConcurrency(2), bokchoy.WithHeartbeatInterval(10*time.Millisecond))
+	defer stop()
+
+	// Wait for the first heartbeat.
+	var workers []bokchoy.WorkerInfo
+	for deadline := time.Now().Add(5 * time.Second); len(workers) == 0; {
+		require.True(t, time.Now().Before(deadline), "Worker has not been registered in time.")
+		time.Sleep(time.Millisecond)
+
+		var err *ekaerr.Error
+		workers, err = b.Workers()
+		require.True(t, err.IsNil())
+	}
+
+	require.Len(t, workers, 1)
+	require.True(t, workers[0].IsAlive)
+	require.Equal(t, os.Getpid(), workers[0].PID)
+	require.Equal(t, []bokchoy.WorkerQueueInfo{{Name: "tasks.test", Concurrency: 2}}, workers[0].Queues)
+
+	// Worker that has been crashed long time ago, w/o unregistering.
+	data, legacyErr := jsoniter.Marshal(bokchoy.WorkerInfo{
+		ID:                "dead",
+		StartedAt:         time.Now().Add(-time.Hour),
+		LastHeartbeat:     time.Now().Add(-time.Minute),
+		HeartbeatInterval: time.Second,
+	})
+	require.NoError(t, legacyErr)
+	require.True(t, broker.SaveWorker("dead", data, 0).IsNil())
+
+	workers, err := b.Workers()
+	require.True(t, err.IsNil())
+	require.Len(t, workers, 2)
+	require.Equal(t, "dead", workers[0].ID)
+	require.False(t, workers[0].IsAlive)
+	require.True(t, workers[1].IsAlive)
+
+	// Stopped worker is unregistered.
+	stop()
+
+	workers, err = b.Workers()
+	require.True(t, err.IsNil())
+	require.Len(t, workers, 1)
+	require.Equal(t, "dead", workers[0].ID)
+}
+
+func TestOrphanRecovery(t *testing.T) {
+
+	// publishOrphan publishes a task, that looks like it's been claimed
+	// by the dead worker: it's claimed, but not pending.
+	publishOrphan := func(t *testing.T, q *bokchoy.Queue, broker *brokertest.MemoryBroker) *bokchoy.Task {
+
+		task, err := q.Publish(testTaskPayload{Data: "hello world"}, bokchoy.WithMaxRetries(1))
+		require.True(t, err.IsNil())
+		require.True(t, broker.RemovePending(q.Name(), task.ID()).IsNil())
+
+		require.True(t, broker.Set("tasks.test.claims", "dead", []byte("dead"), 0).IsNil())
+		require.True(t, broker.Set("tasks.test.claims.dead", task.ID(), []byte(task.ID()), 0).IsNil())
+
+		return task
+	}
+
+	t.Run("Retry", func(t *testing.T) {
+
+		var (
+			q       *bokchoy.Queue
+			orphan  *bokchoy.Task
+			broker  = brokertest.NewMemoryBroker()
+			handled = make(chan *bokchoy.Task, 1)
+		)
+
+		_, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+			q = b.Queue("tasks.test", bokchoy.WithOrphanPolicy(bokchoy.ORPHAN_POLICY_RETRY)).
+				Use(func(task *bokchoy.Task) *ekaerr.Error {
+					handled <- task
+					return nil
+				})
+			orphan = publishOrphan(t, q, broker)
+		}, bokchoy.WithBroker(broker), bokchoy.WithHeartbeatInterval(10*time.Millisecond))
+		defer stop()
+
+		select {
+		case task := <-handled:
+			require.Equal(t, orphan.ID(), task.ID())
+			require.Equal(t, int8(0), task.MaxRetries)
+			require.NotEqual(t, "dead", task.WorkerID())
+			require.NotEmpty(t, task.WorkerID())
+		case <-time.After(5 * time.Second):
+			t.Fatal("Orphaned task has not been recovered in time.")
+		}
+	})
+
+	t.Run("Requeued", func(t *testing.T) {
+
+		var (
+			q      *bokchoy.Queue
+			broker = brokertest.NewMemoryBroker()
+		)
+
+		_, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+			q = b.Queue("tasks.test", bokchoy.WithOrphanPolicy(bokchoy.ORPHAN_POLICY_RETRY)).
+				Use(func(_ *bokchoy.Task) *ekaerr.Error {
+					return ekaerr.IllegalState.New("Bad luck.").Throw()
+				})
+		},
+			bokchoy.WithBroker(broker),
+			bokchoy.WithHeartbeatInterval(10*time.Millisecond),
+			bokchoy.WithRetryIntervals([]time.Duration{time.Hour}))
+		defer stop()
+
+		task, err := q.Publish(testTaskPayload{Data: "hello world"}, bokchoy.WithMaxRetries(1))
+		require.True(t, err.IsNil())
+
+		for deadline := time.Now().Add(5 * time.Second); ; {
+			require.True(t, time.Now().Before(deadline), "Task has not been retried in time.")
+			time.Sleep(time.Millisecond)
+
+			task, err = q.Get(task.ID())
+			require.True(t, err.IsNil())
+
+			if task.Status() == bokchoy.TASK_STATUS_RETRYING {
+				break
+			}
+		}
+
+		// The dead worker has returned the task back to the pool, but not unclaimed it.
+		require.True(t, broker.Set("tasks.test.claims", "dead", []byte("dead"), 0).IsNil())
+		require.True(t, broker.Set("tasks.test.claims.dead", task.ID(), []byte(task.ID()+" 1"), 0).IsNil())
+
+		for deadline := time.Now().Add(5 * time.Second); ; {
+			require.True(t, time.Now().Before(deadline), "Dead worker's claims have not been removed in time.")
+			time.Sleep(time.Millisecond)
+
+			claims, err := broker.List("tasks.test.claims.dead")
+			require.True(t, err.IsNil())
+
+			if len(claims) == 0 {
+				break
+			}
+		}
+
+		// Neither retried nor failed once again.
+		task, err = q.Get(task.ID())
+		require.True(t, err.IsNil())
+		require.Equal(t, bokchoy.TASK_STATUS_RETRYING, task.Status())
+		require.Equal(t, int8(0), task.MaxRetries)
+	})
+
+	t.Run("Fail", func(t *testing.T) {
+
+		var (
+			q      *bokchoy.Queue
+			orphan *bokchoy.Task
+			broker = brokertest.NewMemoryBroker()
+		)
+
+		_, stop := newTestBokchoy(t, func(b *bokchoy.Bokchoy) {
+			q = b.Queue("tasks.test", bokchoy.WithOrphanPolicy(bokchoy.ORPHAN_POLICY_FAIL)).
+				Use(func(_ *bokchoy.Task) *ekaerr.Error {
+					return nil
+				})
+			orphan = publishOrphan(t, q, broker)
+		}, bokchoy.WithBroker(broker), bokchoy.WithHeartbeatInterval(10*time.Millisecond))
+		defer stop()
+
+		for deadline := time.Now().Add(5 * time.Second); ; {
+			require.True(t, time.Now().Before(deadline), "Orphaned task has not been failed in time.")
+			time.Sleep(time.Millisecond)
+
+			task, err := q.Get(orphan.ID())
+			require.True(t, err.IsNil())
+
+			if task.Status() == bokchoy.TASK_STATUS_FAILED {
+				require.True(t, task.Error.Is(ekaerr.Interrupted))
+				break
+			}
+		}
+
+		for deadline := time.Now().Add(5 * time.Second); ; {
+			require.True(t, time.Now().Before(deadline), "Dead worker's claims have not been removed in time.")
+			time.Sleep(time.Millisecond)
+
+			claimants, err := broker.List("tasks.test.claims")
+			require.True(t, err.IsNil())
+
+			if len(claimants) == 1 {
+				claims, err := broker.List("tasks.test.claims.dead")
+				require.True(t, err.IsNil())
+				require.Empty(t, claims)
+				break
+			}
+		}
+	})
+}